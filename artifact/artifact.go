@@ -0,0 +1,198 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package artifact
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// cmdTimeout bounds every cli call, the node may be the reason the test failed.
+const cmdTimeout = 30 * time.Second
+
+// Target is a node whose state is captured.
+type Target struct {
+	Name    string
+	Addr    string
+	LogFile string
+}
+
+// Collector captures node log tails and rpc state into one archive per failed test.
+type Collector struct {
+	Dir     string
+	Client  string
+	Targets []Target
+	LogTail int
+}
+
+// file is one entry of the archive.
+type file struct {
+	name string
+	data []byte
+}
+
+// commands are the cli calls captured for every target.
+var commands = []struct {
+	file string
+	args []string
+}{
+	{"getinfo.json", []string{"getinfo"}},
+	{"gettxpoolcontent.json", []string{"gettxpoolcontent"}},
+	{"getpendingtxs.json", []string{"getpendingtxs"}},
+	{"peersinfo.json", []string{"p2p", "peersinfo"}},
+}
+
+// Collect captures the state of all targets for the failed test and returns
+// the path of the archive. Failures of single captures are written into the
+// archive as well, so an archive is produced even if a node is down.
+func (c *Collector) Collect(pkg, test string) (string, error) {
+	var files []file
+	var errs []string
+	for _, target := range c.Targets {
+		for _, command := range commands {
+			output, err := c.run(append(command.args, "--address", target.Addr)...)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s %s: %s", target.Name, strings.Join(command.args, " "), err))
+			}
+			files = append(files, file{filepath.Join(target.Name, command.file), output})
+		}
+
+		if dump, err := c.dumpHeap(target, test); err != nil {
+			errs = append(errs, fmt.Sprintf("%s dumpheap: %s", target.Name, err))
+		} else {
+			files = append(files, file{filepath.Join(target.Name, "heap.dump"), dump})
+		}
+
+		if target.LogFile != "" {
+			if tail, err := tailFile(target.LogFile, c.LogTail); err != nil {
+				errs = append(errs, fmt.Sprintf("%s log: %s", target.Name, err))
+			} else {
+				files = append(files, file{filepath.Join(target.Name, "node.log"), tail})
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		files = append(files, file{"errors.txt", []byte(strings.Join(errs, "\n") + "\n")})
+	}
+
+	path := filepath.Join(c.Dir, filepath.Base(pkg), sanitize(test)+".tar.gz")
+	if err := writeArchive(path, files); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+func (c *Collector) run(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cmdTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, c.Client, args...).CombinedOutput()
+	if err != nil {
+		return output, fmt.Errorf("%s %s", err, bytes.TrimSpace(output))
+	}
+
+	return output, nil
+}
+
+// dumpHeap asks the node for a heap dump and reads it back. The dump is
+// written on the node's host, so this only works for local nodes.
+func (c *Collector) dumpHeap(target Target, test string) ([]byte, error) {
+	output, err := c.run("dumpheap", "--address", target.Addr, "--file", sanitize(test)+".dump")
+	if err != nil {
+		return nil, err
+	}
+
+	path := strings.TrimSpace(string(output))
+	defer os.Remove(path)
+	return ioutil.ReadFile(path)
+}
+
+// tailFile returns the last lines of a file.
+func tailFile(path string, lines int) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if lines <= 0 {
+		return data, nil
+	}
+
+	end := len(data)
+	if end > 0 && data[end-1] == '\n' {
+		end--
+	}
+
+	for i := end - 1; i >= 0; i-- {
+		if data[i] == '\n' {
+			if lines--; lines == 0 {
+				return data[i+1:], nil
+			}
+		}
+	}
+
+	return data, nil
+}
+
+func writeArchive(path string, files []file) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, f := range files {
+		header := &tar.Header{
+			Name:    filepath.ToSlash(f.name),
+			Mode:    0644,
+			Size:    int64(len(f.data)),
+			ModTime: now,
+		}
+
+		if err = tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if _, err = tw.Write(f.data); err != nil {
+			return err
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// sanitize turns a test name like Test_A/sub_case into a file name.
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', ' ':
+			return '_'
+		}
+		return r
+	}, name)
+}
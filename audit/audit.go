@@ -0,0 +1,274 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// cmdTimeout bounds every cli call
+const cmdTimeout = 20 * time.Second
+
+// rewardFrom is the sender of the reward tx, the first tx of every block
+const rewardFrom = "0x0000000000000000000000000000000000000000"
+
+// Header is the part of the block header the audit checks.
+type Header struct {
+	PreviousBlockHash string
+	Creator           string
+	Difficulty        *big.Int
+	Height            uint64
+	CreateTimestamp   uint64
+}
+
+// Tx is a tx of a block got with --fulltx.
+type Tx struct {
+	Hash   string   `json:"hash"`
+	From   string   `json:"from"`
+	To     string   `json:"to"`
+	Amount *big.Int `json:"amount"`
+}
+
+// Block is a block as printed by the client.
+type Block struct {
+	Hash         string `json:"hash"`
+	Header       Header `json:"header"`
+	Transactions []Tx   `json:"transactions"`
+}
+
+// Finding is a broken invariant.
+type Finding struct {
+	Height  uint64 `json:"height"`
+	Hash    string `json:"hash"`
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+// Checkpoint is the last audited block. Only blocks above it are audited by
+// the next run, History keeps the statistics of the previous runs.
+type Checkpoint struct {
+	Height  uint64    `json:"height"`
+	Hash    string    `json:"hash"`
+	Updated time.Time `json:"updated"`
+	History []*Stats  `json:"history"`
+}
+
+// Result is the outcome of a run.
+type Result struct {
+	Findings   []Finding   `json:"findings"`
+	Stats      *Stats      `json:"stats"`
+	Checkpoint *Checkpoint `json:"checkpoint"`
+}
+
+// Auditor walks the chain of a node and checks every block against its parent.
+type Auditor struct {
+	Client string
+	Addr   string
+	// Confirmations keeps the audit away from the head, which may still be reorganized
+	Confirmations uint64
+	// Limit is the maximum number of blocks audited by a run, 0 for no limit
+	Limit uint64
+	// MaxDrift is how far a block timestamp may be ahead of the local clock
+	MaxDrift time.Duration
+	// Difficulty returns the expected difficulty of a block, nil skips the check
+	Difficulty func(parent *Block, timestamp uint64) *big.Int
+	// Reward returns the expected miner reward at a height, nil skips the check
+	Reward func(height uint64) *big.Int
+	// HistorySize is the number of run statistics kept in the checkpoint
+	HistorySize int
+}
+
+// Run audits the blocks above the checkpoint up to the confirmed head. A nil
+// checkpoint starts from genesis, as does a checkpoint whose block is no
+// longer on the chain or above the head after a reset.
+func (a *Auditor) Run(checkpoint *Checkpoint) (*Result, error) {
+	result := &Result{}
+	if checkpoint == nil {
+		checkpoint = &Checkpoint{}
+	}
+
+	head, err := a.block(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	to := uint64(0)
+	if head.Header.Height > a.Confirmations {
+		to = head.Header.Height - a.Confirmations
+	}
+
+	var parent *Block
+	if checkpoint.Height > head.Header.Height {
+		// the chain was reset below the checkpoint
+		result.Findings = append(result.Findings, Finding{checkpoint.Height, checkpoint.Hash, "checkpoint",
+			fmt.Sprintf("the checkpoint is above the head %d, the chain is audited again from genesis", head.Header.Height)})
+		if parent, err = a.block(0); err != nil {
+			return nil, err
+		}
+	} else if parent, err = a.block(int64(checkpoint.Height)); err != nil {
+		return nil, err
+	} else if checkpoint.Hash != "" && parent.Hash != checkpoint.Hash {
+		result.add(parent, "checkpoint", "block %d is %s, the checkpoint was %s, the chain is audited again from genesis", checkpoint.Height, parent.Hash, checkpoint.Hash)
+		if parent, err = a.block(0); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.Limit > 0 && to > parent.Header.Height+a.Limit {
+		to = parent.Header.Height + a.Limit
+	}
+
+	var intervals []uint64
+	txs := 0
+	from := parent.Header.Height + 1
+	for height := from; height <= to; height++ {
+		block, err := a.block(int64(height))
+		if err != nil {
+			return nil, err
+		}
+
+		if err = a.check(result, parent, block); err != nil {
+			return nil, err
+		}
+
+		if block.Header.CreateTimestamp >= parent.Header.CreateTimestamp {
+			intervals = append(intervals, block.Header.CreateTimestamp-parent.Header.CreateTimestamp)
+		}
+		txs += len(block.Transactions)
+		parent = block
+	}
+
+	result.Stats = NewStats(from, parent.Header.Height, txs, intervals)
+	history := checkpoint.History
+	if result.Stats.Blocks > 0 {
+		history = append(history, result.Stats)
+	}
+
+	if a.HistorySize > 0 && len(history) > a.HistorySize {
+		history = history[len(history)-a.HistorySize:]
+	}
+
+	result.Checkpoint = &Checkpoint{Height: parent.Header.Height, Hash: parent.Hash, Updated: time.Now(), History: history}
+	return result, nil
+}
+
+// check verifies the invariants between a block and its parent.
+func (a *Auditor) check(result *Result, parent, block *Block) error {
+	header := block.Header
+	if header.PreviousBlockHash != parent.Hash {
+		result.add(block, "parent hash", "previous block hash %s, parent %d is %s", header.PreviousBlockHash, parent.Header.Height, parent.Hash)
+	}
+
+	if header.Height != parent.Header.Height+1 {
+		result.add(block, "height", "height %d after parent height %d", header.Height, parent.Header.Height)
+	}
+
+	if header.CreateTimestamp < parent.Header.CreateTimestamp {
+		result.add(block, "timestamp", "timestamp %d is before the parent timestamp %d", header.CreateTimestamp, parent.Header.CreateTimestamp)
+	}
+
+	if ahead := int64(header.CreateTimestamp) - time.Now().Unix(); ahead > int64(a.MaxDrift/time.Second) {
+		result.add(block, "timestamp", "timestamp %d is %ds ahead of the local clock", header.CreateTimestamp, ahead)
+	}
+
+	count, err := a.txCount(header.Height)
+	if err != nil {
+		return err
+	}
+
+	if count != len(block.Transactions) {
+		result.add(block, "tx count", "getblocktxcount returns %d, the block holds %d txs", count, len(block.Transactions))
+	}
+
+	if a.Difficulty != nil {
+		if expected := a.Difficulty(parent, header.CreateTimestamp); header.Difficulty == nil || expected.Cmp(header.Difficulty) != 0 {
+			result.add(block, "difficulty", "difficulty %v, expected %v after parent difficulty %v and %ds", header.Difficulty, expected,
+				parent.Header.Difficulty, int64(header.CreateTimestamp)-int64(parent.Header.CreateTimestamp))
+		}
+	}
+
+	if a.Reward != nil {
+		a.checkReward(result, block)
+	}
+
+	return nil
+}
+
+// checkReward verifies the reward tx pays the expected reward to the creator,
+// and that no other tx is sent from the reward sender.
+func (a *Auditor) checkReward(result *Result, block *Block) {
+	if len(block.Transactions) == 0 {
+		result.add(block, "reward", "the block has no reward tx")
+		return
+	}
+
+	reward := block.Transactions[0]
+	if reward.From != rewardFrom {
+		result.add(block, "reward", "the first tx %s is sent from %s, not a reward tx", reward.Hash, reward.From)
+		return
+	}
+
+	if reward.To != block.Header.Creator {
+		result.add(block, "reward", "reward tx %s pays %s, the creator is %s", reward.Hash, reward.To, block.Header.Creator)
+	}
+
+	if expected := a.Reward(block.Header.Height); reward.Amount == nil || expected.Cmp(reward.Amount) != 0 {
+		result.add(block, "reward", "reward tx %s pays %v, expected %v", reward.Hash, reward.Amount, expected)
+	}
+
+	for _, tx := range block.Transactions[1:] {
+		if tx.From == rewardFrom {
+			result.add(block, "reward", "tx %s is a second reward tx", tx.Hash)
+		}
+	}
+}
+
+func (r *Result) add(block *Block, check, format string, args ...interface{}) {
+	r.Findings = append(r.Findings, Finding{block.Header.Height, block.Hash, check, fmt.Sprintf(format, args...)})
+}
+
+// block returns the block at the height, -1 for the head.
+func (a *Auditor) block(height int64) (*Block, error) {
+	output, err := a.run("getblock", "--height", strconv.FormatInt(height, 10), "--fulltx")
+	if err != nil {
+		return nil, err
+	}
+
+	var block Block
+	if err = json.Unmarshal(output, &block); err != nil {
+		return nil, fmt.Errorf("invalid block %d: %s", height, err)
+	}
+
+	return &block, nil
+}
+
+func (a *Auditor) txCount(height uint64) (int, error) {
+	output, err := a.run("getblocktxcount", "--height", strconv.FormatUint(height, 10))
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(string(bytes.TrimSpace(output)))
+}
+
+func (a *Auditor) run(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cmdTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, a.Client, append(args, "--address", a.Addr)...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s err: %s", args[0], args[1:], bytes.TrimSpace(output))
+	}
+
+	return output, nil
+}
@@ -0,0 +1,58 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package audit
+
+import (
+	"math/big"
+)
+
+// the pow reward schedule of go-seele, in seele per block for every era of
+// about a year, then tailReward.
+var (
+	rewardTable       = [...]float64{6, 4, 3, 2, 1.5}
+	tailReward        = float64(1)
+	blockNumberPerEra = uint64(3150000)
+	fanPerSeele       = big.NewFloat(100000000)
+)
+
+// SeeleReward is the miner reward of the block at the height, in fan.
+func SeeleReward(height uint64) *big.Int {
+	reward := tailReward
+	if era := height / blockNumberPerEra; era < uint64(len(rewardTable)) {
+		reward = rewardTable[era]
+	}
+
+	fan, _ := new(big.Float).Mul(big.NewFloat(reward), fanPerSeele).Int(nil)
+	return fan
+}
+
+// SeeleDifficulty is the pow difficulty of a block created at the timestamp
+// after the parent. It targets a block every 10 seconds, the parent difficulty
+// changes by parent / 2048 * max(1 - interval / 10, -99). The first block
+// keeps the genesis difficulty.
+func SeeleDifficulty(parent *Block, timestamp uint64) *big.Int {
+	difficulty := parent.Header.Difficulty
+	if difficulty == nil {
+		difficulty = new(big.Int)
+	}
+
+	if parent.Header.Height == 0 {
+		return new(big.Int).Set(difficulty)
+	}
+
+	interval := int64(0)
+	if timestamp > parent.Header.CreateTimestamp {
+		interval = int64(timestamp-parent.Header.CreateTimestamp) / 10
+	}
+
+	factor := 1 - interval
+	if factor < -99 {
+		factor = -99
+	}
+
+	step := new(big.Int).Div(difficulty, big.NewInt(2048))
+	return step.Mul(step, big.NewInt(factor)).Add(step, difficulty)
+}
@@ -0,0 +1,96 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package audit
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// bucketSeconds is the width of a histogram bucket, the last bucket holds
+// every longer interval.
+const (
+	bucketSeconds = 10
+	buckets       = 10
+)
+
+// Stats describes the block times of the blocks audited by a run, in seconds.
+type Stats struct {
+	At        time.Time `json:"at"`
+	From      uint64    `json:"from"`
+	To        uint64    `json:"to"`
+	Blocks    int       `json:"blocks"`
+	Txs       int       `json:"txs"`
+	Mean      float64   `json:"mean"`
+	P50       uint64    `json:"p50"`
+	P95       uint64    `json:"p95"`
+	P99       uint64    `json:"p99"`
+	Max       uint64    `json:"max"`
+	Histogram []int     `json:"histogram"`
+}
+
+// NewStats computes the statistics of the intervals between blocks.
+func NewStats(from, to uint64, txs int, intervals []uint64) *Stats {
+	stats := &Stats{At: time.Now(), From: from, To: to, Txs: txs, Histogram: make([]int, buckets)}
+	if to >= from {
+		stats.Blocks = int(to - from + 1)
+	}
+
+	if len(intervals) == 0 {
+		return stats
+	}
+
+	sorted := append([]uint64{}, intervals...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	sum := uint64(0)
+	for _, interval := range sorted {
+		sum += interval
+		bucket := int(interval / bucketSeconds)
+		if bucket >= buckets {
+			bucket = buckets - 1
+		}
+		stats.Histogram[bucket]++
+	}
+
+	stats.Mean = float64(sum) / float64(len(sorted))
+	stats.P50, stats.P95, stats.P99 = percentile(sorted, 50), percentile(sorted, 95), percentile(sorted, 99)
+	stats.Max = sorted[len(sorted)-1]
+	return stats
+}
+
+// percentile is the nearest rank percentile of sorted values.
+func percentile(sorted []uint64, p int) uint64 {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+// Write prints the statistics as a line.
+func (s *Stats) Write(w io.Writer) {
+	fmt.Fprintf(w, "%s blocks %d..%d (%d blocks, %d txs): mean %.1fs p50 %ds p95 %ds p99 %ds max %ds\n",
+		s.At.Format("2006-01-02 15:04"), s.From, s.To, s.Blocks, s.Txs, s.Mean, s.P50, s.P95, s.P99, s.Max)
+}
+
+// WriteHistogram prints the number of blocks by interval.
+func (s *Stats) WriteHistogram(w io.Writer) {
+	for i, count := range s.Histogram {
+		if count == 0 {
+			continue
+		}
+
+		if i == len(s.Histogram)-1 {
+			fmt.Fprintf(w, "  >=%3ds %d\n", i*bucketSeconds, count)
+		} else {
+			fmt.Fprintf(w, "  %3d-%ds %d\n", i*bucketSeconds, (i+1)*bucketSeconds, count)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package audit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_NewStats(t *testing.T) {
+	var hundred []uint64
+	for i := uint64(100); i > 0; i-- {
+		hundred = append(hundred, i)
+	}
+
+	cases := []struct {
+		name               string
+		from, to           uint64
+		intervals          []uint64
+		blocks             int
+		mean               float64
+		p50, p95, p99, max uint64
+		histogram          []int
+	}{
+		{"no interval", 5, 5, nil, 1, 0, 0, 0, 0, 0, []int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"no block", 6, 5, nil, 0, 0, 0, 0, 0, 0, []int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"single", 1, 2, []uint64{7}, 2, 7, 7, 7, 7, 7, []int{1, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		// nearest rank, the rank of a percentile is count*p/100 rounded up
+		{"ten", 1, 11, []uint64{10, 1, 9, 2, 8, 3, 7, 4, 6, 5}, 11, 5.5, 5, 10, 10, 10, []int{9, 1, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"hundred", 1, 101, hundred, 101, 50.5, 50, 95, 99, 100, []int{9, 10, 10, 10, 10, 10, 10, 10, 10, 11}},
+		{"bucket edges", 1, 5, []uint64{9, 10, 89, 90, 500}, 5, 139.6, 89, 500, 500, 500, []int{1, 1, 0, 0, 0, 0, 0, 0, 1, 2}},
+	}
+
+	for _, c := range cases {
+		intervals := append([]uint64{}, c.intervals...)
+		s := NewStats(c.from, c.to, 3, intervals)
+		if s.From != c.from || s.To != c.to || s.Txs != 3 || s.Blocks != c.blocks {
+			t.Fatalf("Test_NewStats %s blocks %d..%d (%d blocks, %d txs), expected %d blocks", c.name, s.From, s.To, s.Blocks, s.Txs, c.blocks)
+		}
+
+		if s.Mean != c.mean || s.P50 != c.p50 || s.P95 != c.p95 || s.P99 != c.p99 || s.Max != c.max {
+			t.Fatalf("Test_NewStats %s mean %v p50 %d p95 %d p99 %d max %d, expected %v %d %d %d %d",
+				c.name, s.Mean, s.P50, s.P95, s.P99, s.Max, c.mean, c.p50, c.p95, c.p99, c.max)
+		}
+
+		if !reflect.DeepEqual(s.Histogram, c.histogram) {
+			t.Fatalf("Test_NewStats %s histogram %v, expected %v", c.name, s.Histogram, c.histogram)
+		}
+
+		if len(c.intervals) > 0 && !reflect.DeepEqual(intervals, c.intervals) {
+			t.Fatalf("Test_NewStats %s sorted the input to %v", c.name, intervals)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+// Sender is an account that sends the load, its nonce is counted locally so
+// txs do not wait for the previous one to be mined.
+type Sender struct {
+	KeyFile string
+	Account string
+
+	mutex sync.Mutex
+	nonce int
+	ready bool
+}
+
+// Senders returns a sender for every keyfile of the shard in the folder,
+// keyfiles are named like shard1-0x0a57....
+func Senders(keyDir string, shard uint) ([]*Sender, error) {
+	paths, err := filepath.Glob(filepath.Join(keyDir, fmt.Sprintf("shard%d-0x*", shard)))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no keyfile of shard %d in %s", shard, keyDir)
+	}
+
+	var senders []*Sender
+	for _, path := range paths {
+		name := filepath.Base(path)
+		senders = append(senders, &Sender{KeyFile: path, Account: strings.ToLower(name[strings.Index(name, "-")+1:])})
+	}
+
+	return senders, nil
+}
+
+// Workload is the tx every sender sends repeatedly.
+type Workload struct {
+	Name    string
+	To      string
+	Amount  int
+	Payload string
+	Gas     int
+	// CrossShard workloads pay an account of another shard, their inclusion
+	// is measured on the shard of the senders
+	CrossShard bool
+	// Setup prepares the workload before the run, like deploying a contract
+	Setup func(b *Bench) error
+}
+
+// Transfer pays amount to an account of the same shard.
+func Transfer(to string) *Workload {
+	return &Workload{Name: "transfer", To: to, Amount: 1}
+}
+
+// CrossShard pays amount to an account of another shard.
+func CrossShard(to string) *Workload {
+	return &Workload{Name: "crossshard", To: to, Amount: 1, CrossShard: true}
+}
+
+// ContractCall deploys the contract of the bin file once and calls the method
+// of the abi with the args.
+func ContractCall(bin, abi, method string, args ...string) *Workload {
+	w := &Workload{Name: "contract"}
+	w.Setup = func(b *Bench) error {
+		code, err := ioutil.ReadFile(bin)
+		if err != nil {
+			return err
+		}
+
+		sender := b.Senders[0]
+		nonce, err := common.GetNonce(nil, b.Client, sender.Account, b.Addr)
+		if err != nil {
+			return fmt.Errorf("get nonce of %s err: %s", sender.Account, err)
+		}
+
+		txHash, _, err := common.SendTx(nil, b.Client, 0, nonce, 0, sender.KeyFile, "", strings.TrimSpace(string(code)), b.Addr)
+		if err != nil {
+			return fmt.Errorf("deploy %s err: %s", bin, err)
+		}
+
+		receipt, err := common.WaitReceipt(nil, b.Client, txHash, b.Addr)
+		if err != nil {
+			return err
+		}
+
+		if receipt.Failed || receipt.Contract == "" {
+			return fmt.Errorf("deploy %s failed: %s", bin, receipt.Result)
+		}
+
+		cmd := exec.Command(b.Client, "payload", "--abi", abi, "--method", method)
+		for _, arg := range args {
+			cmd.Args = append(cmd.Args, "--args", arg)
+		}
+
+		output, err := cmd.CombinedOutput()
+		payload := strings.TrimSpace(string(output))
+		if err != nil || !strings.Contains(payload, "0x") {
+			return fmt.Errorf("payload of %s err: %s", method, payload)
+		}
+
+		w.To, w.Payload = receipt.Contract, payload[strings.Index(payload, "0x"):]
+		return nil
+	}
+
+	return w
+}
+
+// Bench drives a workload from the senders, either at a target rate or in a
+// closed loop where every worker sends its next tx once the previous one is
+// submitted.
+type Bench struct {
+	Client   string
+	Addr     string
+	Senders  []*Sender
+	Workload *Workload
+	// Rate is the target txs per second, 0 runs the closed loop
+	Rate float64
+	// Workers is the number of concurrent submissions. The txs of a sender are
+	// submitted one at a time, so more workers than senders do not add load.
+	Workers  int
+	Duration time.Duration
+	// InclusionTimeout is how long txs may take to be mined after the load stops
+	InclusionTimeout time.Duration
+	// SampleEvery is the period of the pool and throughput samples
+	SampleEvery time.Duration
+}
+
+// Run runs the workload and returns every tx with its latencies.
+func (b *Bench) Run() (*Result, error) {
+	if len(b.Senders) == 0 {
+		return nil, errors.New("no sender")
+	}
+
+	if b.Workers <= 0 {
+		b.Workers = 1
+	}
+
+	if b.Workload.Setup != nil {
+		if err := b.Workload.Setup(b); err != nil {
+			return nil, fmt.Errorf("setup %s workload err: %s", b.Workload.Name, err)
+		}
+	}
+
+	head, err := common.GetBlock(nil, b.Client, -1, b.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Workload: b.Workload.Name, Mode: b.mode(), Start: time.Now()}
+	tracker := newTracker(b.Client, b.Addr, head.Header.Height, result)
+	trackCtx, stopTracking := context.WithCancel(context.Background())
+	defer stopTracking()
+	go tracker.track(trackCtx, b.SampleEvery)
+
+	loadCtx, stopLoad := context.WithTimeout(context.Background(), b.Duration)
+	defer stopLoad()
+	if b.Rate > 0 {
+		b.openLoop(loadCtx, result)
+	} else {
+		b.closedLoop(loadCtx, result)
+	}
+	result.LoadEnd = time.Now()
+
+	deadline := time.Now().Add(b.InclusionTimeout)
+	for !result.allIncluded() && time.Now().Before(deadline) {
+		time.Sleep(time.Second)
+	}
+
+	stopTracking()
+	tracker.wait()
+	result.End = time.Now()
+	return result, nil
+}
+
+func (b *Bench) mode() string {
+	if b.Rate > 0 {
+		return fmt.Sprintf("rate %g tx/s, at most %d in flight", b.Rate, b.Workers)
+	}
+
+	return fmt.Sprintf("closed loop, %d workers", b.Workers)
+}
+
+// closedLoop runs the workers back to back until the context is done.
+func (b *Bench) closedLoop(ctx context.Context, result *Result) {
+	var wg sync.WaitGroup
+	for i := 0; i < b.Workers; i++ {
+		wg.Add(1)
+		go func(sender *Sender) {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				b.submit(sender, result)
+			}
+		}(b.Senders[i%len(b.Senders)])
+	}
+
+	wg.Wait()
+}
+
+// openLoop starts a submission at the target rate. A tick finding all
+// workers busy is missed, the node or the cli cannot keep up with the rate.
+func (b *Bench) openLoop(ctx context.Context, result *Result) {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / b.Rate))
+	defer ticker.Stop()
+
+	slots := make(chan struct{}, b.Workers)
+	var wg sync.WaitGroup
+	for next := 0; ; next++ {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+
+		select {
+		case slots <- struct{}{}:
+		default:
+			result.miss()
+			continue
+		}
+
+		wg.Add(1)
+		go func(sender *Sender) {
+			defer wg.Done()
+			b.submit(sender, result)
+			<-slots
+		}(b.Senders[next%len(b.Senders)])
+	}
+}
+
+// submit sends the next tx of the sender. A rejected tx makes the sender read
+// its nonce from the node again.
+func (b *Bench) submit(sender *Sender, result *Result) {
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+
+	record := &TxRecord{Sender: sender.Account}
+	if !sender.ready {
+		nonce, err := common.GetNonce(nil, b.Client, sender.Account, b.Addr)
+		if err != nil {
+			record.Err = fmt.Sprintf("get nonce err: %s", err)
+			result.add(record)
+			time.Sleep(time.Second)
+			return
+		}
+		sender.nonce, sender.ready = nonce, true
+	}
+
+	w := b.Workload
+	record.Nonce = sender.nonce
+	record.Submitted = time.Now()
+	txHash, _, err := common.SendTx(nil, b.Client, w.Amount, sender.nonce, w.Gas, sender.KeyFile, w.To, w.Payload, b.Addr)
+	record.SubmitLatency = time.Since(record.Submitted)
+	if err != nil {
+		record.Err = strings.TrimSpace(err.Error())
+		sender.ready = false
+	} else {
+		record.Hash = txHash
+		sender.nonce++
+	}
+
+	result.add(record)
+}
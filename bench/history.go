@@ -0,0 +1,114 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package bench
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Run is the summary of a bench run kept in the result store.
+type Run struct {
+	Workload string    `json:"workload"`
+	Version  string    `json:"version"`
+	Date     time.Time `json:"date"`
+	Summary  *Summary  `json:"summary"`
+}
+
+// Thresholds tell when a run is a regression of its baseline, the median of
+// the previous Window runs of the workload. Changes are ratios, 0.2 flags a
+// 20% tps drop or latency rise.
+type Thresholds struct {
+	Window      int
+	TPSDrop     float64
+	LatencyRise float64
+	// MinRuns is the number of previous runs needed before gating
+	MinRuns int
+}
+
+// Regression is a metric of a run worse than its baseline beyond the threshold.
+type Regression struct {
+	Metric   string  `json:"metric"`
+	Baseline float64 `json:"baseline"`
+	Current  float64 `json:"current"`
+	// Change is the relative change from the baseline, negative for a drop
+	Change float64 `json:"change"`
+}
+
+func (r *Regression) String() string {
+	return fmt.Sprintf("%s %.2f -> %.2f (%+.0f%%)", r.Metric, r.Baseline, r.Current, r.Change*100)
+}
+
+// metric is a number of a summary compared to the baseline. Latencies are in
+// seconds.
+type metric struct {
+	name   string
+	value  func(s *Summary) float64
+	higher bool // whether a higher value is better
+}
+
+var metrics = []metric{
+	{"tps", func(s *Summary) float64 { return s.TPS }, true},
+	{"inclusion p95", func(s *Summary) float64 { return s.Inclusion.P95.Seconds() }, false},
+	{"submission p95", func(s *Summary) float64 { return s.Submit.P95.Seconds() }, false},
+}
+
+// Previous returns the runs before the date, oldest first, at most window.
+func Previous(runs []*Run, date time.Time, window int) []*Run {
+	var previous []*Run
+	for _, run := range runs {
+		if run.Date.Before(date) {
+			previous = append(previous, run)
+		}
+	}
+
+	sort.Slice(previous, func(i, j int) bool { return previous[i].Date.Before(previous[j].Date) })
+	if window > 0 && len(previous) > window {
+		previous = previous[len(previous)-window:]
+	}
+
+	return previous
+}
+
+// Compare returns the regressions of the run against the baseline runs, none
+// if there are less than MinRuns of them.
+func Compare(run *Run, baseline []*Run, th Thresholds) []*Regression {
+	if len(baseline) == 0 || len(baseline) < th.MinRuns {
+		return nil
+	}
+
+	var regressions []*Regression
+	for _, m := range metrics {
+		var values []float64
+		for _, b := range baseline {
+			values = append(values, m.value(b.Summary))
+		}
+
+		base, current := median(values), m.value(run.Summary)
+		if base == 0 {
+			continue
+		}
+
+		change := (current - base) / base
+		if (m.higher && -change > th.TPSDrop) || (!m.higher && change > th.LatencyRise) {
+			regressions = append(regressions, &Regression{Metric: m.name, Baseline: base, Current: current, Change: change})
+		}
+	}
+
+	return regressions
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
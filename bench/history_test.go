@@ -0,0 +1,108 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package bench
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_Median(t *testing.T) {
+	cases := []struct {
+		name   string
+		values []float64
+		median float64
+	}{
+		{"single", []float64{5}, 5},
+		{"odd", []float64{3, 1, 2}, 2},
+		{"even", []float64{4, 1, 3, 2}, 2.5},
+		{"duplicates", []float64{7, 7, 1, 7}, 7},
+	}
+
+	for _, c := range cases {
+		values := append([]float64{}, c.values...)
+		if median := median(values); median != c.median {
+			t.Fatalf("Test_Median %s median is %v, expected %v", c.name, median, c.median)
+		}
+
+		if !reflect.DeepEqual(values, c.values) {
+			t.Fatalf("Test_Median %s sorted the input to %v", c.name, values)
+		}
+	}
+}
+
+func Test_Previous(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2019, 1, d, 0, 0, 0, 0, time.UTC) }
+	runs := []*Run{{Date: day(3)}, {Date: day(1)}, {Date: day(4)}, {Date: day(2)}}
+
+	cases := []struct {
+		name   string
+		date   time.Time
+		window int
+		days   []int
+	}{
+		{"window", day(4), 2, []int{2, 3}},
+		{"no window", day(4), 0, []int{1, 2, 3}},
+		{"window larger than runs", day(4), 10, []int{1, 2, 3}},
+		{"date excluded", day(3), 0, []int{1, 2}},
+		{"before every run", day(1), 0, nil},
+	}
+
+	for _, c := range cases {
+		var days []int
+		for _, run := range Previous(runs, c.date, c.window) {
+			days = append(days, run.Date.Day())
+		}
+
+		if !reflect.DeepEqual(days, c.days) {
+			t.Fatalf("Test_Previous %s returns days %v, expected %v", c.name, days, c.days)
+		}
+	}
+}
+
+func Test_Compare(t *testing.T) {
+	run := func(tps float64, inclusion, submit time.Duration) *Run {
+		summary := &Summary{TPS: tps}
+		summary.Inclusion.P95, summary.Submit.P95 = inclusion, submit
+		return &Run{Summary: summary}
+	}
+
+	// the medians are 100 tps and 10s inclusion, the submission is 0 and never compared
+	baseline := []*Run{run(90, 8*time.Second, 0), run(100, 10*time.Second, 0), run(110, 12*time.Second, 0)}
+	th := Thresholds{TPSDrop: 0.2, LatencyRise: 0.5, MinRuns: 3}
+
+	cases := []struct {
+		name     string
+		run      *Run
+		baseline []*Run
+		th       Thresholds
+		metrics  []string
+	}{
+		{"unchanged", run(100, 10*time.Second, 0), baseline, th, nil},
+		{"improved", run(200, time.Second, 0), baseline, th, nil},
+		{"tps drop at threshold", run(80, 10*time.Second, 0), baseline, th, nil},
+		{"tps drop beyond threshold", run(79, 10*time.Second, 0), baseline, th, []string{"tps"}},
+		{"latency rise at threshold", run(100, 15*time.Second, 0), baseline, th, nil},
+		{"latency rise beyond threshold", run(100, 16*time.Second, 0), baseline, th, []string{"inclusion p95"}},
+		{"both", run(50, 20*time.Second, 0), baseline, th, []string{"tps", "inclusion p95"}},
+		{"zero baseline", run(100, 10*time.Second, time.Minute), baseline, th, nil},
+		{"fewer runs than MinRuns", run(10, time.Minute, 0), baseline[:2], th, nil},
+		{"no baseline", run(10, time.Minute, 0), nil, Thresholds{TPSDrop: 0.2, LatencyRise: 0.5}, nil},
+		{"MinRuns reached", run(10, 10*time.Second, 0), baseline[:2], Thresholds{TPSDrop: 0.2, LatencyRise: 0.5, MinRuns: 2}, []string{"tps"}},
+	}
+
+	for _, c := range cases {
+		var metrics []string
+		for _, r := range Compare(c.run, c.baseline, c.th) {
+			metrics = append(metrics, r.Metric)
+		}
+
+		if !reflect.DeepEqual(metrics, c.metrics) {
+			t.Fatalf("Test_Compare %s regressions are %v, expected %v", c.name, metrics, c.metrics)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package bench
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TxRecord is a tx of the run. Rejected txs have an error and no hash, txs
+// not mined before the end of the run have no inclusion time.
+type TxRecord struct {
+	Hash          string        `json:"hash,omitempty"`
+	Sender        string        `json:"sender"`
+	Nonce         int           `json:"nonce"`
+	Submitted     time.Time     `json:"submitted"`
+	SubmitLatency time.Duration `json:"submitLatency"`
+	Included      time.Time     `json:"included,omitempty"`
+	Height        uint64        `json:"height,omitempty"`
+	Err           string        `json:"err,omitempty"`
+}
+
+// InclusionLatency returns the time from submission to the block, 0 if the
+// tx is not included.
+func (r *TxRecord) InclusionLatency() time.Duration {
+	if r.Included.IsZero() {
+		return 0
+	}
+
+	return r.Included.Sub(r.Submitted)
+}
+
+// Sample is the state of the node at a time of the run.
+type Sample struct {
+	Elapsed time.Duration `json:"elapsed"`
+	// Pool is the number of txs in the pool, -1 if it could not be read
+	Pool      int64 `json:"pool"`
+	Submitted int   `json:"submitted"`
+	Included  int   `json:"included"`
+}
+
+type inclusion struct {
+	at     time.Time
+	height uint64
+}
+
+// Result is every tx and sample of a run.
+type Result struct {
+	Workload string      `json:"workload"`
+	Mode     string      `json:"mode"`
+	Start    time.Time   `json:"start"`
+	LoadEnd  time.Time   `json:"loadEnd"`
+	End      time.Time   `json:"end"`
+	Txs      []*TxRecord `json:"txs"`
+	Samples  []*Sample   `json:"samples"`
+	// Missed is the number of ticks of the target rate skipped because all
+	// workers were busy
+	Missed int `json:"missed"`
+
+	mutex    sync.Mutex
+	byHash   map[string]*TxRecord
+	seen     map[string]inclusion
+	included int
+}
+
+func (r *Result) add(record *TxRecord) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.Txs = append(r.Txs, record)
+	if record.Hash == "" {
+		return
+	}
+
+	if r.byHash == nil {
+		r.byHash = make(map[string]*TxRecord)
+	}
+	r.byHash[record.Hash] = record
+
+	// the tracker may read the block before the submission returns
+	if seen, ok := r.seen[record.Hash]; ok {
+		record.Included, record.Height = seen.at, seen.height
+		r.included++
+	}
+}
+
+func (r *Result) miss() {
+	r.mutex.Lock()
+	r.Missed++
+	r.mutex.Unlock()
+}
+
+func (r *Result) include(height uint64, at time.Time, hashes []string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.seen == nil {
+		r.seen = make(map[string]inclusion)
+	}
+
+	for _, hash := range hashes {
+		r.seen[hash] = inclusion{at, height}
+		if record := r.byHash[hash]; record != nil && record.Included.IsZero() {
+			record.Included, record.Height = at, height
+			r.included++
+		}
+	}
+}
+
+func (r *Result) sample(at time.Time, pool int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.Samples = append(r.Samples, &Sample{Elapsed: at.Sub(r.Start), Pool: pool, Submitted: len(r.byHash), Included: r.included})
+}
+
+func (r *Result) allIncluded() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.included == len(r.byHash)
+}
+
+// Latency are the percentiles of a latency in the run.
+type Latency struct {
+	Count int           `json:"count"`
+	Mean  time.Duration `json:"mean"`
+	P50   time.Duration `json:"p50"`
+	P95   time.Duration `json:"p95"`
+	P99   time.Duration `json:"p99"`
+	Max   time.Duration `json:"max"`
+}
+
+func newLatency(values []time.Duration) Latency {
+	l := Latency{Count: len(values)}
+	if len(values) == 0 {
+		return l
+	}
+
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	var sum time.Duration
+	for _, v := range values {
+		sum += v
+	}
+
+	percentile := func(p int) time.Duration {
+		return values[(len(values)-1)*p/100]
+	}
+
+	l.Mean, l.P50, l.P95, l.P99, l.Max = sum/time.Duration(len(values)), percentile(50), percentile(95), percentile(99), values[len(values)-1]
+	return l
+}
+
+// Summary is what a run achieved.
+type Summary struct {
+	Workload  string  `json:"workload"`
+	Mode      string  `json:"mode"`
+	Submitted int     `json:"submitted"`
+	Rejected  int     `json:"rejected"`
+	Included  int     `json:"included"`
+	Missed    int     `json:"missed"`
+	SubmitTPS float64 `json:"submitTps"`
+	// TPS is the included txs per second from the start to the last inclusion
+	TPS       float64 `json:"tps"`
+	MaxPool   int64   `json:"maxPool"`
+	Submit    Latency `json:"submit"`
+	Inclusion Latency `json:"inclusion"`
+}
+
+// Summary computes the throughput and latencies of the run.
+func (r *Result) Summary() *Summary {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	s := &Summary{Workload: r.Workload, Mode: r.Mode, Missed: r.Missed}
+	var submit, inclusion []time.Duration
+	var last time.Time
+	for _, tx := range r.Txs {
+		if tx.Err != "" {
+			s.Rejected++
+			continue
+		}
+
+		s.Submitted++
+		submit = append(submit, tx.SubmitLatency)
+		if !tx.Included.IsZero() {
+			s.Included++
+			inclusion = append(inclusion, tx.InclusionLatency())
+			if tx.Included.After(last) {
+				last = tx.Included
+			}
+		}
+	}
+
+	if load := r.LoadEnd.Sub(r.Start).Seconds(); load > 0 {
+		s.SubmitTPS = float64(s.Submitted) / load
+	}
+
+	if elapsed := last.Sub(r.Start).Seconds(); s.Included > 0 && elapsed > 0 {
+		s.TPS = float64(s.Included) / elapsed
+	}
+
+	for _, sample := range r.Samples {
+		if sample.Pool > s.MaxPool {
+			s.MaxPool = sample.Pool
+		}
+	}
+
+	s.Submit, s.Inclusion = newLatency(submit), newLatency(inclusion)
+	return s
+}
+
+// Slowest returns the n submitted txs with the longest inclusion latency,
+// txs never included come first.
+func (r *Result) Slowest(n int) []*TxRecord {
+	r.mutex.Lock()
+	var txs []*TxRecord
+	for _, tx := range r.Txs {
+		if tx.Err == "" {
+			txs = append(txs, tx)
+		}
+	}
+	r.mutex.Unlock()
+
+	sort.SliceStable(txs, func(i, j int) bool {
+		a, b := txs[i], txs[j]
+		if a.Included.IsZero() != b.Included.IsZero() {
+			return a.Included.IsZero()
+		}
+
+		if a.Included.IsZero() {
+			return a.Submitted.Before(b.Submitted)
+		}
+
+		return a.InclusionLatency() > b.InclusionLatency()
+	})
+
+	if len(txs) > n {
+		txs = txs[:n]
+	}
+
+	return txs
+}
+
+// Errors returns the distinct submission errors with their count.
+func (r *Result) Errors() map[string]int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	errs := make(map[string]int)
+	for _, tx := range r.Txs {
+		if tx.Err != "" {
+			errs[tx.Err]++
+		}
+	}
+
+	return errs
+}
+
+// Write prints the summary, the pool backlog over time and the top n slowest txs.
+func (r *Result) Write(w io.Writer, n int) {
+	s := r.Summary()
+	fmt.Fprintf(w, "workload %s, %s, load %s\n", s.Workload, s.Mode, r.LoadEnd.Sub(r.Start).Round(time.Second))
+	fmt.Fprintf(w, "submitted %d, rejected %d, included %d, pending %d, missed ticks %d\n", s.Submitted, s.Rejected, s.Included, s.Submitted-s.Included, s.Missed)
+	fmt.Fprintf(w, "submit tps %.2f, achieved tps %.2f, max pool %d\n", s.SubmitTPS, s.TPS, s.MaxPool)
+	writeLatency(w, "submission", s.Submit)
+	writeLatency(w, "inclusion", s.Inclusion)
+
+	errs := r.Errors()
+	if len(errs) > 0 {
+		fmt.Fprintln(w, "\nsubmission errors:")
+		messages := make([]string, 0, len(errs))
+		for message := range errs {
+			messages = append(messages, message)
+		}
+		sort.Strings(messages)
+		for _, message := range messages {
+			fmt.Fprintf(w, "%6d  %s\n", errs[message], message)
+		}
+	}
+
+	fmt.Fprintln(w, "\npool backlog:")
+	fmt.Fprintf(w, "%10s %8s %10s %10s\n", "elapsed", "pool", "submitted", "included")
+	for _, sample := range r.Samples {
+		fmt.Fprintf(w, "%10s %8d %10d %10d\n", sample.Elapsed.Round(time.Second), sample.Pool, sample.Submitted, sample.Included)
+	}
+
+	fmt.Fprintf(w, "\ntop %d slowest txs:\n", n)
+	for _, tx := range r.Slowest(n) {
+		latency := "not included"
+		if !tx.Included.IsZero() {
+			latency = fmt.Sprintf("%s at height %d", tx.InclusionLatency().Round(time.Millisecond), tx.Height)
+		}
+		fmt.Fprintf(w, "%s from %s nonce %d: %s\n", tx.Hash, tx.Sender, tx.Nonce, latency)
+	}
+}
+
+func writeLatency(w io.Writer, name string, l Latency) {
+	if l.Count == 0 {
+		fmt.Fprintf(w, "%-10s latency: no tx\n", name)
+		return
+	}
+
+	values := []string{}
+	for _, v := range []struct {
+		name  string
+		value time.Duration
+	}{{"mean", l.Mean}, {"p50", l.P50}, {"p95", l.P95}, {"p99", l.P99}, {"max", l.Max}} {
+		values = append(values, fmt.Sprintf("%s %s", v.name, v.value.Round(time.Millisecond)))
+	}
+
+	fmt.Fprintf(w, "%-10s latency: %s\n", name, strings.Join(values, ", "))
+}
@@ -0,0 +1,90 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package bench
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_NewLatency(t *testing.T) {
+	ms := func(values ...int) []time.Duration {
+		var result []time.Duration
+		for _, v := range values {
+			result = append(result, time.Duration(v)*time.Millisecond)
+		}
+		return result
+	}
+
+	var hundred []int
+	for i := 100; i > 0; i-- {
+		hundred = append(hundred, i)
+	}
+
+	cases := []struct {
+		name    string
+		values  []time.Duration
+		latency Latency
+	}{
+		{"empty", nil, Latency{}},
+		{"single", ms(7), Latency{Count: 1, Mean: 7 * time.Millisecond, P50: 7 * time.Millisecond, P95: 7 * time.Millisecond, P99: 7 * time.Millisecond, Max: 7 * time.Millisecond}},
+		// the index of a percentile is (count-1)*p/100 rounded down
+		{"ten unsorted", ms(10, 1, 9, 2, 8, 3, 7, 4, 6, 5), Latency{Count: 10, Mean: 5500 * time.Microsecond, P50: 5 * time.Millisecond, P95: 9 * time.Millisecond, P99: 9 * time.Millisecond, Max: 10 * time.Millisecond}},
+		{"hundred", ms(hundred...), Latency{Count: 100, Mean: 50500 * time.Microsecond, P50: 50 * time.Millisecond, P95: 95 * time.Millisecond, P99: 99 * time.Millisecond, Max: 100 * time.Millisecond}},
+	}
+
+	for _, c := range cases {
+		if latency := newLatency(c.values); latency != c.latency {
+			t.Fatalf("Test_NewLatency %s latency is %+v, expected %+v", c.name, latency, c.latency)
+		}
+	}
+}
+
+func Test_Result_Summary(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	result := &Result{
+		Workload: "transfer",
+		Mode:     "open",
+		Start:    start,
+		LoadEnd:  at(10),
+		Missed:   4,
+		Txs: []*TxRecord{
+			{Hash: "0x1", Submitted: at(0), SubmitLatency: time.Second, Included: at(2)},
+			{Hash: "0x2", Submitted: at(1), SubmitLatency: 3 * time.Second, Included: at(5)},
+			{Hash: "0x3", Submitted: at(2), SubmitLatency: 2 * time.Second},
+			{Submitted: at(3), Err: "nonce too low"},
+		},
+		Samples: []*Sample{{Pool: 3}, {Pool: 7}, {Pool: -1}},
+	}
+
+	s := result.Summary()
+	if s.Workload != "transfer" || s.Mode != "open" || s.Missed != 4 {
+		t.Fatalf("Test_Result_Summary summary of %s %s with %d missed ticks", s.Workload, s.Mode, s.Missed)
+	}
+
+	if s.Submitted != 3 || s.Rejected != 1 || s.Included != 2 {
+		t.Fatalf("Test_Result_Summary submitted %d rejected %d included %d, expected 3 1 2", s.Submitted, s.Rejected, s.Included)
+	}
+
+	// submitted over the 10s of load, included until the last inclusion at 5s
+	if s.SubmitTPS != 0.3 || s.TPS != 0.4 {
+		t.Fatalf("Test_Result_Summary submit tps %v tps %v, expected 0.3 0.4", s.SubmitTPS, s.TPS)
+	}
+
+	if s.MaxPool != 7 {
+		t.Fatalf("Test_Result_Summary max pool %d, expected 7", s.MaxPool)
+	}
+
+	if s.Submit.Count != 3 || s.Submit.Max != 3*time.Second || s.Inclusion.Count != 2 || s.Inclusion.Max != 4*time.Second {
+		t.Fatalf("Test_Result_Summary submission %+v inclusion %+v", s.Submit, s.Inclusion)
+	}
+
+	empty := (&Result{Start: start, LoadEnd: start}).Summary()
+	if empty.SubmitTPS != 0 || empty.TPS != 0 || empty.Inclusion.Count != 0 {
+		t.Fatalf("Test_Result_Summary empty run summary %+v", empty)
+	}
+}
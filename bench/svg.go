@@ -0,0 +1,94 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package bench
+
+import (
+	"fmt"
+	"html/template"
+
+	"github.com/seeleteam/e2e-blackbox/chart"
+)
+
+// chart dimensions in pixels
+const (
+	chartWidth  = 640
+	chartHeight = 200
+)
+
+// Metrics returns the names of the metrics compared to the baseline.
+func Metrics() []string {
+	var names []string
+	for _, m := range metrics {
+		names = append(names, m.name)
+	}
+
+	return names
+}
+
+// TrendSVG renders the metric of the runs, oldest first, as an svg line chart.
+// A dashed line marks every node version change, the last point is red when
+// it is a regression.
+func TrendSVG(metricName string, runs []*Run, regressed bool) template.HTML {
+	var m *metric
+	for i := range metrics {
+		if metrics[i].name == metricName {
+			m = &metrics[i]
+		}
+	}
+
+	c := chart.New(chartWidth, chartHeight, metricName)
+	if m == nil || len(runs) == 0 {
+		return c.Empty("no runs")
+	}
+
+	values, max := make([]float64, len(runs)), 0.0
+	for i, run := range runs {
+		values[i] = m.value(run.Summary)
+		if values[i] > max {
+			max = values[i]
+		}
+	}
+
+	x := func(i int) float64 {
+		if len(runs) == 1 {
+			return c.X(0.5)
+		}
+		return c.X(float64(i) / float64(len(runs)-1))
+	}
+
+	c.Axes(max, 2)
+	for i, run := range runs {
+		if i == 0 || run.Version != runs[i-1].Version {
+			c.Marker(x(i))
+			c.Label(x(i)+2, "start", shortVersion(run.Version))
+		}
+	}
+
+	points := make([][2]float64, len(values))
+	for i, v := range values {
+		points[i] = [2]float64{x(i), c.Y(v)}
+	}
+	c.Line("#36c", 2, points)
+
+	for i, v := range values {
+		color := "#36c"
+		if regressed && i == len(values)-1 {
+			color = "#d33"
+		}
+		c.Point(x(i), c.Y(v), color, fmt.Sprintf("%s %s: %.3f", runs[i].Date.Format("2006-01-02 15:04"), runs[i].Version, v))
+	}
+
+	return c.SVG()
+}
+
+// shortVersion keeps the axis labels readable for long version strings.
+func shortVersion(version string) string {
+	if len(version) > 16 {
+		return version[:16]
+	}
+
+	return version
+}
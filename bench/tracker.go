@@ -0,0 +1,97 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package bench
+
+import (
+	"context"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+// tracker reads the blocks mined during the run to find when the txs were
+// included, and samples the pool backlog. Inclusion times are as precise as
+// the sample period.
+type tracker struct {
+	client string
+	addr   string
+	height uint64
+	result *Result
+	done   chan struct{}
+}
+
+func newTracker(client, addr string, height uint64, result *Result) *tracker {
+	return &tracker{client: client, addr: addr, height: height, result: result, done: make(chan struct{})}
+}
+
+func (tr *tracker) track(ctx context.Context, period time.Duration) {
+	defer close(tr.done)
+	if period <= 0 {
+		period = time.Second
+	}
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		tr.poll()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (tr *tracker) wait() {
+	<-tr.done
+}
+
+// poll reads the blocks above the last one read and the pool size.
+func (tr *tracker) poll() {
+	now := time.Now()
+	head, err := common.GetBlock(nil, tr.client, -1, tr.addr)
+	if err != nil {
+		return
+	}
+
+	for h := tr.height + 1; h <= head.Header.Height; h++ {
+		block := head
+		if h != head.Header.Height {
+			if block, err = common.GetBlock(nil, tr.client, int64(h), tr.addr); err != nil {
+				return
+			}
+		}
+
+		tr.result.include(h, now, txHashes(block))
+		tr.height = h
+	}
+
+	pool, err := common.GetPoolCountTxs(nil, tr.client, tr.addr)
+	if err != nil {
+		pool = -1
+	}
+
+	tr.result.sample(now, pool)
+}
+
+// txHashes returns the hashes of the txs of a block, printed as hashes or as
+// tx objects depending on the node.
+func txHashes(block *common.BlockInfo) []string {
+	var hashes []string
+	for _, tx := range block.Transactions {
+		switch value := tx.(type) {
+		case string:
+			hashes = append(hashes, value)
+		case map[string]interface{}:
+			if hash, ok := value["hash"].(string); ok {
+				hashes = append(hashes, hash)
+			}
+		}
+	}
+
+	return hashes
+}
@@ -0,0 +1,115 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"strconv"
+)
+
+// Margin is the space around the plot in pixels, it holds the axis labels.
+const Margin = 40
+
+// Chart is an svg line chart under construction. Every text written into it
+// is escaped, so the finished svg is embedded in the html reports as is.
+type Chart struct {
+	b      bytes.Buffer
+	width  int
+	height int
+	top    float64
+}
+
+// New starts a chart of width x height pixels with a bold title.
+func New(width, height int, title string) *Chart {
+	c := &Chart{width: width, height: height, top: 1}
+	fmt.Fprintf(&c.b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="11">`, width, height)
+	fmt.Fprintf(&c.b, `<text x="%d" y="14" font-weight="bold">%s</text>`, Margin, html.EscapeString(title))
+	return c
+}
+
+// Empty finishes the chart with the message in place of the plot.
+func (c *Chart) Empty(message string) template.HTML {
+	fmt.Fprintf(&c.b, `<text x="%d" y="%d">%s</text>`, Margin, c.height/2, html.EscapeString(message))
+	return c.SVG()
+}
+
+// X returns the pixel of a fraction of the plot width, 0 is the y axis.
+func (c *Chart) X(fraction float64) float64 {
+	return Margin + float64(c.width-2*Margin)*fraction
+}
+
+// Y returns the pixel of the value on the scale set by Axes.
+func (c *Chart) Y(v float64) float64 {
+	return Margin + float64(c.height-2*Margin)*(1-v/c.top)
+}
+
+// Axes draws the axes and scales the plot to max with a tenth of headroom.
+// The ticks at 0, half of max and max are labelled with the decimals.
+func (c *Chart) Axes(max float64, decimals int) {
+	if max <= 0 {
+		max = 1
+	}
+	c.top = max * 1.1
+
+	bottom, right := c.height-Margin, c.width-Margin
+	fmt.Fprintf(&c.b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#888"/>`, Margin, Margin, Margin, bottom)
+	fmt.Fprintf(&c.b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#888"/>`, Margin, bottom, right, bottom)
+	for _, tick := range []float64{0, max / 2, max} {
+		fmt.Fprintf(&c.b, `<text x="%d" y="%.1f" text-anchor="end">%s</text>`, Margin-4, c.Y(tick)+4, strconv.FormatFloat(tick, 'f', decimals, 64))
+	}
+}
+
+// Shade fills the plot between the x pixels red, clipped to the plot, with the
+// title as tooltip.
+func (c *Chart) Shade(from, to float64, title string) {
+	if from < Margin {
+		from = Margin
+	}
+	if right := float64(c.width - Margin); to > right {
+		to = right
+	}
+
+	fmt.Fprintf(&c.b, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="#fdd"><title>%s</title></rect>`,
+		from, Margin, to-from+1, c.height-2*Margin, html.EscapeString(title))
+}
+
+// Marker draws a dashed vertical line through the plot at x.
+func (c *Chart) Marker(x float64) {
+	fmt.Fprintf(&c.b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#bbb" stroke-dasharray="4 3"/>`, x, Margin, x, c.height-Margin)
+}
+
+// Label writes the text below the x axis, anchor is start, middle or end.
+func (c *Chart) Label(x float64, anchor, text string) {
+	fmt.Fprintf(&c.b, `<text x="%.1f" y="%d" text-anchor="%s">%s</text>`, x, c.height-Margin+14, anchor, html.EscapeString(text))
+}
+
+// Legend writes the i-th entry of the legend next to the title.
+func (c *Chart) Legend(i int, color, text string) {
+	fmt.Fprintf(&c.b, `<text x="%d" y="14" fill="%s">%s</text>`, Margin+80+i*90, color, html.EscapeString(text))
+}
+
+// Line draws a polyline through the points, pairs of x and y pixels.
+func (c *Chart) Line(color string, width float64, points [][2]float64) {
+	fmt.Fprintf(&c.b, `<polyline fill="none" stroke="%s" stroke-width="%g" points="`, color, width)
+	for _, p := range points {
+		fmt.Fprintf(&c.b, "%.1f,%.1f ", p[0], p[1])
+	}
+	c.b.WriteString(`"/>`)
+}
+
+// Point draws a dot at x, y with the title as tooltip.
+func (c *Chart) Point(x, y float64, color, title string) {
+	fmt.Fprintf(&c.b, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>%s</title></circle>`, x, y, color, html.EscapeString(title))
+}
+
+// SVG finishes the chart.
+func (c *Chart) SVG() template.HTML {
+	c.b.WriteString(`</svg>`)
+	return template.HTML(c.b.String())
+}
@@ -0,0 +1,323 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package differ
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cmdTimeout bounds every cli call
+const cmdTimeout = 20 * time.Second
+
+// missing stands for a field one side does not return
+const missing = "<missing>"
+
+// Endpoint is a cli binary and the rpc address it queries.
+type Endpoint struct {
+	Command string
+	Addr    string
+}
+
+// query runs the cli and decodes its json output, numbers are kept as
+// written so big balances compare exactly.
+func (e Endpoint) query(args ...string) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cmdTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, e.Command, append(args, "--address", e.Addr)...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s err: %s", filepath.Base(e.Command), args[0], bytes.TrimSpace(output))
+	}
+
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	decoder.UseNumber()
+	if err = decoder.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%s %s invalid output: %s", filepath.Base(e.Command), args[0], bytes.TrimSpace(output))
+	}
+
+	return v, nil
+}
+
+// Mismatch is a field the light node returns differently from the full node.
+type Mismatch struct {
+	// Height is -1 when the subject is not bound to a block
+	Height  int64
+	Subject string
+	Field   string
+	Full    string
+	Light   string
+}
+
+// Report is the outcome of a check.
+type Report struct {
+	From, To   uint64
+	Accounts   int
+	Receipts   int
+	Mismatches []Mismatch
+	// FirstDivergent is the lowest height with a mismatch, -1 if there is none
+	FirstDivergent int64
+}
+
+// Checker compares what a light node and a full node return for the same
+// blocks, accounts and txs.
+type Checker struct {
+	Full  Endpoint
+	Light Endpoint
+	// From and To are the heights of the compared blocks, both included
+	From, To uint64
+	Accounts []string
+	TxHashes []string
+	// MaxReceipts is the number of receipts of txs in the compared blocks
+	// checked in addition to TxHashes
+	MaxReceipts int
+	// Ignore lists the fields not compared, like "header.Creator"
+	Ignore []string
+	// Retries is how often a differing balance or nonce is read again, the
+	// nodes may see a new block at different times
+	Retries    int
+	RetryDelay time.Duration
+}
+
+// Run compares blocks, balances, nonces and receipts. The error is only set
+// when the full node cannot be queried, light node errors are mismatches.
+func (c *Checker) Run() (*Report, error) {
+	report := &Report{From: c.From, To: c.To, Accounts: len(c.Accounts), FirstDivergent: -1}
+	txHashes := append([]string{}, c.TxHashes...)
+	blockTxs := 0
+
+	for height := c.From; height <= c.To; height++ {
+		h := strconv.FormatUint(height, 10)
+		full, err := c.Full.query("getblock", "--height", h, "--fulltx")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, hash := range txHashesOf(full) {
+			if blockTxs < c.MaxReceipts {
+				txHashes = append(txHashes, hash)
+				blockTxs++
+			}
+		}
+
+		light, err := c.Light.query("getblock", "--height", h, "--fulltx")
+		c.add(report, int64(height), "block "+h, full, light, err)
+	}
+
+	for _, account := range c.Accounts {
+		for _, method := range []string{"getbalance", "getnonce"} {
+			if err := c.settle(report, method+" of "+account, method, "--account", account); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, hash := range txHashes {
+		full, err := c.Full.query("getreceipt", "--hash", hash)
+		if err != nil {
+			return nil, err
+		}
+
+		height := int64(-1)
+		if tx, err := c.Full.query("gettxbyhash", "--hash", hash); err == nil {
+			if n, err := strconv.ParseInt(field(tx, "blockHeight"), 10, 64); err == nil {
+				height = n
+			}
+		}
+
+		light, err := c.Light.query("getreceipt", "--hash", hash)
+		c.add(report, height, "receipt "+hash, full, light, err)
+		report.Receipts++
+	}
+
+	sort.SliceStable(report.Mismatches, func(i, j int) bool {
+		return report.Mismatches[i].Height < report.Mismatches[j].Height
+	})
+
+	for _, m := range report.Mismatches {
+		if m.Height >= 0 && (report.FirstDivergent < 0 || m.Height < report.FirstDivergent) {
+			report.FirstDivergent = m.Height
+		}
+	}
+
+	return report, nil
+}
+
+// settle compares account state, reading it again while it differs.
+func (c *Checker) settle(report *Report, subject string, args ...string) error {
+	for i := 0; ; i++ {
+		full, err := c.Full.query(args...)
+		if err != nil {
+			return err
+		}
+
+		light, err := c.Light.query(args...)
+		if err == nil && len(c.diff(full, light)) == 0 {
+			return nil
+		}
+
+		if i >= c.Retries {
+			c.add(report, -1, subject, full, light, err)
+			return nil
+		}
+
+		time.Sleep(c.RetryDelay)
+	}
+}
+
+func (c *Checker) add(report *Report, height int64, subject string, full, light interface{}, lightErr error) {
+	if lightErr != nil {
+		report.Mismatches = append(report.Mismatches, Mismatch{height, subject, "error", "", lightErr.Error()})
+		return
+	}
+
+	for _, m := range c.diff(full, light) {
+		m.Height, m.Subject = height, subject
+		report.Mismatches = append(report.Mismatches, m)
+	}
+}
+
+// diff compares the values field by field.
+func (c *Checker) diff(full, light interface{}) []Mismatch {
+	fullFields, lightFields := make(map[string]string), make(map[string]string)
+	flatten("", full, fullFields)
+	flatten("", light, lightFields)
+
+	ignored := make(map[string]bool)
+	for _, f := range c.Ignore {
+		ignored[f] = true
+	}
+
+	var mismatches []Mismatch
+	for _, name := range unionKeys(fullFields, lightFields) {
+		if ignored[name] {
+			continue
+		}
+
+		f, ok := fullFields[name]
+		if !ok {
+			f = missing
+		}
+
+		l, ok := lightFields[name]
+		if !ok {
+			l = missing
+		}
+
+		if f != l {
+			mismatches = append(mismatches, Mismatch{Field: name, Full: f, Light: l})
+		}
+	}
+
+	return mismatches
+}
+
+// flatten maps every leaf to its path, like header.Height or
+// transactions[0].hash. Arrays also get their length as len(path).
+func flatten(path string, v interface{}, fields map[string]string) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, child := range value {
+			if path == "" {
+				flatten(k, child, fields)
+			} else {
+				flatten(path+"."+k, child, fields)
+			}
+		}
+	case []interface{}:
+		fields["len("+path+")"] = strconv.Itoa(len(value))
+		for i, child := range value {
+			flatten(fmt.Sprintf("%s[%d]", path, i), child, fields)
+		}
+	default:
+		fields[path] = fmt.Sprint(value)
+	}
+}
+
+func unionKeys(a, b map[string]string) []string {
+	var keys []string
+	for k := range a {
+		keys = append(keys, k)
+	}
+
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// field returns a top level field of a decoded object as text.
+func field(v interface{}, name string) string {
+	if m, ok := v.(map[string]interface{}); ok && m[name] != nil {
+		return fmt.Sprint(m[name])
+	}
+
+	return ""
+}
+
+// txHashesOf returns the hashes of the txs in a block got with --fulltx.
+func txHashesOf(block interface{}) []string {
+	m, _ := block.(map[string]interface{})
+	txs, _ := m["transactions"].([]interface{})
+
+	var hashes []string
+	for _, tx := range txs {
+		if hash := field(tx, "hash"); hash != "" {
+			hashes = append(hashes, hash)
+		}
+	}
+
+	return hashes
+}
+
+// Consistent returns whether the light node matched the full node.
+func (r *Report) Consistent() bool {
+	return len(r.Mismatches) == 0
+}
+
+// Write prints the report, mismatches ordered by height.
+func (r *Report) Write(w io.Writer) {
+	fmt.Fprintf(w, "light vs full: blocks %d..%d, %d accounts, %d receipts\n", r.From, r.To, r.Accounts, r.Receipts)
+	if r.Consistent() {
+		fmt.Fprintln(w, "consistent")
+		return
+	}
+
+	if r.FirstDivergent >= 0 {
+		fmt.Fprintf(w, "first divergent height: %d\n", r.FirstDivergent)
+	}
+
+	fmt.Fprintf(w, "%d mismatches:\n", len(r.Mismatches))
+	for _, m := range r.Mismatches {
+		height := "-"
+		if m.Height >= 0 {
+			height = strconv.FormatInt(m.Height, 10)
+		}
+
+		fmt.Fprintf(w, "  height %s %s %s: full=%s light=%s\n", height, m.Subject, m.Field, m.Full, m.Light)
+	}
+}
+
+// String returns the report as written by Write.
+func (r *Report) String() string {
+	var b strings.Builder
+	r.Write(&b)
+	return b.String()
+}
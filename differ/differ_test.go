@@ -0,0 +1,99 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package differ
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// decode decodes like Endpoint.query, numbers are kept as written.
+func decode(t *testing.T, name, data string) interface{} {
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		t.Fatalf("%s invalid json %s: %s", name, data, err)
+	}
+
+	return v
+}
+
+func Test_Flatten(t *testing.T) {
+	cases := []struct {
+		name   string
+		data   string
+		fields map[string]string
+	}{
+		{"scalar", `5`, map[string]string{"": "5"}},
+		{"flat map", `{"a":1,"b":"x","c":true,"d":null}`, map[string]string{"a": "1", "b": "x", "c": "true", "d": "<nil>"}},
+		{"nested maps", `{"header":{"Height":3,"Creator":{"shard":1}}}`,
+			map[string]string{"header.Height": "3", "header.Creator.shard": "1"}},
+		{"array", `{"txs":["a","b"]}`, map[string]string{"len(txs)": "2", "txs[0]": "a", "txs[1]": "b"}},
+		{"empty array", `{"txs":[]}`, map[string]string{"len(txs)": "0"}},
+		{"maps in array", `{"transactions":[{"hash":"0x1","amount":10}]}`,
+			map[string]string{"len(transactions)": "1", "transactions[0].hash": "0x1", "transactions[0].amount": "10"}},
+		{"nested arrays", `{"m":[[1],[]]}`, map[string]string{"len(m)": "2", "len(m[0])": "1", "m[0][0]": "1", "len(m[1])": "0"}},
+		{"big number kept as written", `{"balance":123456789012345678901234567890}`,
+			map[string]string{"balance": "123456789012345678901234567890"}},
+	}
+
+	for _, c := range cases {
+		fields := make(map[string]string)
+		flatten("", decode(t, "Test_Flatten "+c.name, c.data), fields)
+		if !reflect.DeepEqual(fields, c.fields) {
+			t.Fatalf("Test_Flatten %s fields are %v, expected %v", c.name, fields, c.fields)
+		}
+	}
+}
+
+func Test_Diff(t *testing.T) {
+	cases := []struct {
+		name       string
+		full       string
+		light      string
+		ignore     []string
+		mismatches []Mismatch
+	}{
+		{"equal", `{"a":{"b":[1,"x"]}}`, `{"a":{"b":[1,"x"]}}`, nil, nil},
+		{"key order ignored", `{"a":1,"b":2}`, `{"b":2,"a":1}`, nil, nil},
+		{"numbers equal", `{"n":100,"f":1.5}`, `{"n":100,"f":1.5}`, nil, nil},
+		{"numbers differ", `{"n":100}`, `{"n":101}`, nil, []Mismatch{{Field: "n", Full: "100", Light: "101"}}},
+		// the nodes print numbers the same way, another spelling is a difference
+		{"number spelling differs", `{"n":100}`, `{"n":1e2}`, nil, []Mismatch{{Field: "n", Full: "100", Light: "1e2"}}},
+		{"big numbers compared exactly", `{"n":123456789012345678901}`, `{"n":123456789012345678902}`, nil,
+			[]Mismatch{{Field: "n", Full: "123456789012345678901", Light: "123456789012345678902"}}},
+		{"strings differ", `{"s":"a"}`, `{"s":"b"}`, nil, []Mismatch{{Field: "s", Full: "a", Light: "b"}}},
+		{"nested field differs", `{"header":{"Height":3,"Hash":"0x1"}}`, `{"header":{"Height":3,"Hash":"0x2"}}`, nil,
+			[]Mismatch{{Field: "header.Hash", Full: "0x1", Light: "0x2"}}},
+		{"missing in light", `{"a":1,"b":2}`, `{"a":1}`, nil, []Mismatch{{Field: "b", Full: "2", Light: missing}}},
+		{"missing in full", `{"a":1}`, `{"a":1,"b":2}`, nil, []Mismatch{{Field: "b", Full: missing, Light: "2"}}},
+		{"array shorter", `{"txs":["a","b"]}`, `{"txs":["a"]}`, nil, []Mismatch{
+			{Field: "len(txs)", Full: "2", Light: "1"},
+			{Field: "txs[1]", Full: "b", Light: missing},
+		}},
+		{"array element differs", `{"txs":[{"hash":"a"}]}`, `{"txs":[{"hash":"b"}]}`, nil,
+			[]Mismatch{{Field: "txs[0].hash", Full: "a", Light: "b"}}},
+		{"ignored field", `{"header":{"Creator":"x","Height":1}}`, `{"header":{"Creator":"y","Height":1}}`, []string{"header.Creator"}, nil},
+		{"ignore is exact", `{"header":{"Creator":{"a":1}}}`, `{"header":{"Creator":{"a":2}}}`, []string{"header.Creator"},
+			[]Mismatch{{Field: "header.Creator.a", Full: "1", Light: "2"}}},
+		{"sorted by field", `{"b":1,"a":1}`, `{"b":2,"a":2}`, nil, []Mismatch{
+			{Field: "a", Full: "1", Light: "2"},
+			{Field: "b", Full: "1", Light: "2"},
+		}},
+	}
+
+	for _, c := range cases {
+		checker := &Checker{Ignore: c.ignore}
+		name := "Test_Diff " + c.name
+		mismatches := checker.diff(decode(t, name, c.full), decode(t, name, c.light))
+		if !reflect.DeepEqual(mismatches, c.mismatches) {
+			t.Fatalf("%s mismatches are %+v, expected %+v", name, mismatches, c.mismatches)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cmdTimeout bounds every cli call, a hanging node should fail its check
+// instead of the doctor.
+const cmdTimeout = 20 * time.Second
+
+// Target is a node the suite talks to.
+type Target struct {
+	Name  string
+	Addr  string
+	Shard uint
+	// Managed nodes are started by the harness, they are not expected to be
+	// reachable before the run. A managed node that is already running is
+	// checked like any other.
+	Managed bool
+}
+
+// nodeInfo is the part of the getinfo output the doctor checks.
+type nodeInfo struct {
+	MinerStatus string `json:"MinerStatus"`
+	Shard       int    `json:"Shard"`
+}
+
+// Doctor checks the environment of a test run. All paths are used as given,
+// relative ones are resolved against the working directory of the caller.
+type Doctor struct {
+	Client     string
+	Light      string
+	Node       string
+	Targets    []Target
+	CurShard   uint
+	KeyDir     string
+	Password   string
+	MinBalance int64
+	Dir        string
+	MinFreeMB  uint64
+}
+
+// Result is the outcome of a single check, Remedy tells how to fix a failure.
+type Result struct {
+	Name   string
+	Err    error
+	Remedy string
+}
+
+// keyfile is a committed keyfile named like shard1-0x0a57....
+type keyfile struct {
+	path    string
+	account string
+	shard   uint
+}
+
+// Run runs all checks. Checks that depend on a failed one are skipped, so
+// every failure points at its own cause.
+func (d *Doctor) Run() []Result {
+	var results []Result
+	add := func(name string, err error, remedy string) bool {
+		results = append(results, Result{name, err, remedy})
+		return err == nil
+	}
+
+	clientOK := true
+	for _, binary := range []string{d.Client, d.Light, d.Node} {
+		if binary == "" {
+			continue
+		}
+
+		version, err := d.version(binary)
+		name := fmt.Sprintf("binary %s", binary)
+		if err == nil {
+			name += " " + version
+		}
+
+		if !add(name, err, fmt.Sprintf("build go-seele and copy %s into %s", filepath.Base(binary), filepath.Dir(binary))) && binary == d.Client {
+			clientOK = false
+		}
+	}
+
+	keyfiles, err := d.keyfiles()
+	add(fmt.Sprintf("keyfiles in %s", d.KeyDir), err, "restore the keyfiles from git, they are named shard<N>-<account>")
+	if !clientOK {
+		return results
+	}
+
+	// servers are the reachable nodes of each shard, harness is the shards
+	// whose nodes the harness starts for the run
+	servers, harness := make(map[uint]string), make(map[uint]bool)
+	for _, target := range d.Targets {
+		name := fmt.Sprintf("node %s at %s", target.Name, target.Addr)
+		info, err := d.getInfo(target.Addr)
+		if err != nil && target.Managed {
+			harness[target.Shard] = true
+			continue
+		}
+
+		if !add(name+" is reachable", err, fmt.Sprintf("start the shard %d node with rpc on %s, or fix config/topology.json", target.Shard, target.Addr)) {
+			continue
+		}
+
+		if info.Shard != int(target.Shard) {
+			add(name+" shard", fmt.Errorf("serves shard %d, expected %d", info.Shard, target.Shard),
+				fmt.Sprintf("restart the node with a shard %d config, or fix the shard of %s", target.Shard, target.Name))
+			continue
+		}
+
+		if _, ok := servers[target.Shard]; !ok {
+			servers[target.Shard] = target.Addr
+		}
+
+		var minerErr error
+		if info.MinerStatus != "Running" {
+			minerErr = fmt.Errorf("miner status is %q", info.MinerStatus)
+		}
+		add(name+" is mining", minerErr, fmt.Sprintf("run `%s miner start --address %s`", d.Client, target.Addr))
+	}
+
+	if _, ok := servers[d.CurShard]; !ok && !harness[d.CurShard] {
+		add(fmt.Sprintf("CurShard %d is served", d.CurShard), fmt.Errorf("no reachable node serves shard %d", d.CurShard),
+			"start a node of that shard, or fix CurShard in testcase/common/define.go")
+	}
+
+	for _, key := range keyfiles {
+		name := fmt.Sprintf("keyfile %s", filepath.Base(key.path))
+		if !add(name+" decrypts", d.decrypt(key), fmt.Sprintf("the keyfile does not open with the configured password, restore %s from git", key.path)) {
+			continue
+		}
+
+		// balances on nodes the harness has not started yet are checked by
+		// the suites themselves
+		addr, ok := servers[key.shard]
+		if !ok {
+			continue
+		}
+
+		balance, err := d.getBalance(key.account, addr)
+		if err == nil && balance < d.MinBalance {
+			err = fmt.Errorf("balance %d is below %d", balance, d.MinBalance)
+		}
+		add(fmt.Sprintf("account %s balance", key.account), err,
+			fmt.Sprintf("fund the account on shard %d, or rebuild the genesis with `run fixture build`", key.shard))
+	}
+
+	free, err := freeSpace(d.Dir)
+	if err == nil && free < d.MinFreeMB<<20 {
+		err = fmt.Errorf("%d MB free, %d MB required", free>>20, d.MinFreeMB)
+	}
+	add(fmt.Sprintf("disk space at %s", d.Dir), err, "remove old artifacts and node data, or run on a larger disk")
+
+	return results
+}
+
+// Report prints the results and returns whether all checks passed.
+func Report(w io.Writer, results []Result) bool {
+	failed := 0
+	for _, result := range results {
+		if result.Err == nil {
+			fmt.Fprintf(w, "[ OK ] %s\n", result.Name)
+			continue
+		}
+
+		failed++
+		fmt.Fprintf(w, "[FAIL] %s: %s\n", result.Name, result.Err)
+		fmt.Fprintf(w, "       fix: %s\n", result.Remedy)
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(w, "%d of %d checks failed\n", failed, len(results))
+		return false
+	}
+
+	fmt.Fprintf(w, "all %d checks passed\n", len(results))
+	return true
+}
+
+func (d *Doctor) run(stdin string, binary string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cmdTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binary, args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return output, fmt.Errorf("%s %s", err, bytes.TrimSpace(output))
+	}
+
+	return output, nil
+}
+
+func (d *Doctor) version(binary string) (string, error) {
+	if _, err := os.Stat(binary); err != nil {
+		return "", err
+	}
+
+	output, err := d.run("", binary, "--version")
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes.TrimSpace(output)), nil
+}
+
+func (d *Doctor) getInfo(addr string) (*nodeInfo, error) {
+	output, err := d.run("", d.Client, "getinfo", "--address", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var info nodeInfo
+	if err = json.Unmarshal(output, &info); err != nil {
+		return nil, fmt.Errorf("invalid getinfo output: %s", err)
+	}
+
+	return &info, nil
+}
+
+func (d *Doctor) getBalance(account, addr string) (int64, error) {
+	output, err := d.run("", d.Client, "getbalance", "--account", account, "--address", addr)
+	if err != nil {
+		return 0, err
+	}
+
+	var info struct {
+		Balance int64
+	}
+	if err = json.Unmarshal(output, &info); err != nil {
+		return 0, fmt.Errorf("invalid getbalance output: %s", err)
+	}
+
+	return info.Balance, nil
+}
+
+// decrypt opens the keyfile with the password. The output holds the private
+// key, so it is only searched for the account and never reported.
+func (d *Doctor) decrypt(key keyfile) error {
+	output, err := d.run(d.Password+"\n", d.Client, "deckeyfile", "--file", key.path)
+	if err != nil {
+		return fmt.Errorf("deckeyfile failed")
+	}
+
+	if !strings.Contains(strings.ToLower(string(output)), key.account) {
+		return fmt.Errorf("keyfile does not hold account %s", key.account)
+	}
+
+	return nil
+}
+
+func (d *Doctor) keyfiles() ([]keyfile, error) {
+	paths, err := filepath.Glob(filepath.Join(d.KeyDir, "shard*-0x*"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no keyfile found")
+	}
+
+	var keys []keyfile
+	for _, path := range paths {
+		parts := strings.SplitN(strings.TrimPrefix(filepath.Base(path), "shard"), "-", 2)
+		shard, err := strconv.ParseUint(parts[0], 10, 32)
+		if err != nil || len(parts) != 2 {
+			return nil, fmt.Errorf("invalid keyfile name %s", path)
+		}
+
+		keys = append(keys, keyfile{path, strings.ToLower(parts[1]), uint(shard)})
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,158 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package fixture
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+// Spec describes a deterministic genesis. The same spec always yields the
+// same genesis block, so the hashes recorded from it stay valid.
+type Spec struct {
+	Shard      uint             `json:"shard"`
+	Difficulty int64            `json:"difficulty"`
+	Timestamp  int64            `json:"timestamp"`
+	Accounts   map[string]int64 `json:"accounts"`
+}
+
+// DefaultSpec funds every account the suites send from with BaseBalance, the
+// committed keyfile accounts and Account2 whose private key signs txs.
+func DefaultSpec(shard uint) *Spec {
+	spec := &Spec{
+		Shard:      shard,
+		Difficulty: 8000000,
+		Timestamp:  1542274661,
+		Accounts:   make(map[string]int64),
+	}
+
+	for _, account := range []string{
+		common.AccountShard1_1, common.AccountShard1_2, common.AccountShard1_3, common.AccountShard1_4, common.AccountShard1_5,
+		common.AccountShard2_1, common.AccountShard2_2, common.AccountShard2_3, common.AccountShard2_4, common.AccountShard2_5,
+		common.Account2,
+	} {
+		spec.Accounts[account] = common.BaseBalance
+	}
+
+	return spec
+}
+
+// LoadSpec loads a spec file.
+func LoadSpec(file string) (*Spec, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec Spec
+	if err = json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("invalid spec file %s: %s", file, err)
+	}
+
+	return &spec, nil
+}
+
+// Validate checks the spec. Accounts of every shard may be funded, the node
+// only applies those of its own shard, so the shard of each account is
+// asked from the cli to report accounts that will have no effect.
+func (s *Spec) Validate(client string) (otherShard []string, err error) {
+	if s.Shard == 0 {
+		return nil, fmt.Errorf("shard is not set")
+	}
+
+	if s.Difficulty <= 0 {
+		return nil, fmt.Errorf("difficulty should be positive, got %d", s.Difficulty)
+	}
+
+	for account, balance := range s.Accounts {
+		if balance < 0 {
+			return nil, fmt.Errorf("account %s has a negative balance", account)
+		}
+
+		shard, err := common.GetShardNum(nil, client, account)
+		if err != nil {
+			return nil, fmt.Errorf("account %s is invalid: %s", account, err)
+		}
+
+		if shard != s.Shard {
+			otherShard = append(otherShard, account)
+		}
+	}
+
+	return otherShard, nil
+}
+
+// Apply writes the genesis section of the node config and the genesis
+// accounts file that is passed to the node with --accounts.
+func (s *Spec) Apply(configFile, accountsFile string) error {
+	data, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+
+	var config map[string]interface{}
+	if err = json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("invalid node config %s: %s", configFile, err)
+	}
+
+	// the node config spells it difficult
+	config["genesis"] = map[string]interface{}{
+		"difficult": s.Difficulty,
+		"shard":     s.Shard,
+		"timestamp": s.Timestamp,
+	}
+
+	if err = writeJSON(configFile, config); err != nil {
+		return err
+	}
+
+	return writeJSON(accountsFile, s.Accounts)
+}
+
+// Record reads the genesis block and the blocks at the given heights from a
+// node started from the spec and returns them as the fixture tests read.
+func (s *Spec) Record(client, address string, heights []uint64) (*common.Fixture, error) {
+	fixture := &common.Fixture{
+		Shard:      s.Shard,
+		Difficulty: s.Difficulty,
+		Timestamp:  s.Timestamp,
+		Blocks:     make(map[uint64]string),
+		Accounts:   s.Accounts,
+	}
+
+	for _, height := range append([]uint64{0}, heights...) {
+		block, err := common.GetBlock(nil, client, int64(height), address)
+		if err != nil {
+			return nil, fmt.Errorf("getblock %d err: %s", height, err)
+		}
+
+		if block.Header.Height != height {
+			return nil, fmt.Errorf("getblock %d returns height %d", height, block.Header.Height)
+		}
+
+		fixture.Blocks[height] = block.Hash
+	}
+
+	fixture.GenesisHash = fixture.Blocks[0]
+	return fixture, nil
+}
+
+// Save writes the fixture file.
+func Save(file string, fixture *common.Fixture) error {
+	return writeJSON(file, fixture)
+}
+
+func writeJSON(file string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(file, append(data, '\n'), 0644)
+}
@@ -0,0 +1,392 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package forkwatch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// cmdTimeout bounds every cli call
+const cmdTimeout = 20 * time.Second
+
+// Target is a node the monitor polls.
+type Target struct {
+	Name  string
+	Addr  string
+	Shard uint
+}
+
+// Fork is a time span in which nodes of a shard returned different blocks at
+// the same height.
+type Fork struct {
+	Shard uint
+	// Height is the lowest divergent height seen during the fork
+	Height uint64
+	// Hashes are the blocks of the nodes at Height when the fork was seen
+	Hashes    map[string]string
+	Start     time.Time
+	Converged time.Time
+}
+
+// Duration returns the time to convergence, or the time since the start of
+// a fork that is not converged yet.
+func (f *Fork) Duration() time.Duration {
+	if f.Converged.IsZero() {
+		return time.Since(f.Start)
+	}
+
+	return f.Converged.Sub(f.Start)
+}
+
+// Reorg is a node replacing blocks it returned before.
+type Reorg struct {
+	Node  string
+	Shard uint
+	At    time.Time
+	// Height is the lowest replaced height, Depth the number of abandoned blocks
+	Height uint64
+	Depth  uint64
+	// Orphaned are txs of the abandoned blocks whose receipt is gone
+	Orphaned []string
+}
+
+// Report is what the monitor observed.
+type Report struct {
+	Start  time.Time
+	Polls  int
+	Errors int
+	Forks  []*Fork
+	Reorgs []*Reorg
+}
+
+// block is what a node returned at a height.
+type block struct {
+	hash string
+	txs  []string
+}
+
+// view is the recent chain of a node as seen by the monitor.
+type view struct {
+	head   uint64
+	blocks map[uint64]block
+}
+
+// Monitor polls the nodes of a topology, detects forks between nodes of the
+// same shard and reorgs on every node.
+type Monitor struct {
+	Client  string
+	Targets []Target
+	// Window is the number of heights below the head a poll may read, a
+	// deeper reorg is reported with its depth cut to the window
+	Window uint64
+	Period time.Duration
+	// Events is called for every fork, convergence and reorg, nil to stay silent
+	Events func(format string, args ...interface{})
+
+	mutex  sync.Mutex
+	views  map[string]*view
+	active map[uint]*Fork
+	report Report
+}
+
+// Watch polls until the context is done.
+func (m *Monitor) Watch(ctx context.Context) {
+	ticker := time.NewTicker(m.Period)
+	defer ticker.Stop()
+
+	for {
+		m.Poll()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// WaitConverged polls until no fork is active or the timeout elapses.
+func (m *Monitor) WaitConverged(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		m.Poll()
+		forks := m.Report().Unconverged()
+		if len(forks) == 0 {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("shard %d diverges at height %d since %s", forks[0].Shard, forks[0].Height, forks[0].Duration())
+		}
+
+		time.Sleep(m.Period)
+	}
+}
+
+// Poll reads the recent blocks of every node once and updates the forks and
+// reorgs. Nodes that fail to answer keep their previous view.
+func (m *Monitor) Poll() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.views == nil {
+		m.views, m.active = make(map[string]*view), make(map[uint]*Fork)
+		m.report.Start = time.Now()
+	}
+	m.report.Polls++
+
+	var wg sync.WaitGroup
+	reads := make([]map[uint64]block, len(m.Targets))
+	heads := make([]uint64, len(m.Targets))
+	errs := make([]error, len(m.Targets))
+	for i, target := range m.Targets {
+		wg.Add(1)
+		go func(i int, target Target) {
+			defer wg.Done()
+			heads[i], reads[i], errs[i] = m.read(target, m.views[target.Name])
+		}(i, target)
+	}
+	wg.Wait()
+
+	for i, target := range m.Targets {
+		if errs[i] != nil {
+			m.report.Errors++
+			m.event("node %s poll err: %s", target.Name, errs[i])
+			continue
+		}
+
+		m.update(target, heads[i], reads[i])
+	}
+
+	m.compare()
+}
+
+// read returns the head height and the blocks from the head down to the
+// first one the node returned the same before.
+func (m *Monitor) read(target Target, old *view) (uint64, map[uint64]block, error) {
+	head, err := m.block(target.Addr, -1)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	height := head.height
+	blocks := map[uint64]block{height: head.block}
+	for h := height; h > 0 && height-h < m.Window; h-- {
+		if old != nil {
+			if b, ok := old.blocks[h]; ok && b.hash == blocks[h].hash {
+				break
+			}
+		}
+
+		parent, err := m.block(target.Addr, int64(h-1))
+		if err != nil {
+			return 0, nil, err
+		}
+		blocks[h-1] = parent.block
+	}
+
+	return height, blocks, nil
+}
+
+// update merges what a node returned into its view and records a reorg if
+// blocks it returned before are abandoned.
+func (m *Monitor) update(target Target, head uint64, blocks map[uint64]block) {
+	v := m.views[target.Name]
+	if v == nil {
+		v = &view{blocks: make(map[uint64]block)}
+		m.views[target.Name] = v
+	}
+
+	var abandoned []uint64
+	for h, old := range v.blocks {
+		if b, ok := blocks[h]; (ok && b.hash != old.hash) || h > head {
+			abandoned = append(abandoned, h)
+		}
+	}
+
+	if len(abandoned) > 0 {
+		sort.Slice(abandoned, func(i, j int) bool { return abandoned[i] < abandoned[j] })
+		reorg := &Reorg{
+			Node:   target.Name,
+			Shard:  target.Shard,
+			At:     time.Now(),
+			Height: abandoned[0],
+			Depth:  uint64(len(abandoned)),
+		}
+
+		included := make(map[string]bool)
+		for _, b := range blocks {
+			for _, tx := range b.txs {
+				included[tx] = true
+			}
+		}
+
+		for _, h := range abandoned {
+			for _, tx := range v.blocks[h].txs {
+				if !included[tx] && !m.hasReceipt(target.Addr, tx) {
+					reorg.Orphaned = append(reorg.Orphaned, tx)
+				}
+			}
+		}
+
+		m.report.Reorgs = append(m.report.Reorgs, reorg)
+		m.event("node %s of shard %d reorganized %d blocks from height %d, %d txs orphaned", target.Name, target.Shard, reorg.Depth, reorg.Height, len(reorg.Orphaned))
+	}
+
+	for h := range v.blocks {
+		if h > head || head-h > 2*m.Window {
+			delete(v.blocks, h)
+		}
+	}
+
+	for h, b := range blocks {
+		v.blocks[h] = b
+	}
+	v.head = head
+}
+
+// compare looks for heights at which nodes of a shard return different blocks.
+func (m *Monitor) compare() {
+	shards := make(map[uint][]Target)
+	for _, target := range m.Targets {
+		shards[target.Shard] = append(shards[target.Shard], target)
+	}
+
+	for shard, targets := range shards {
+		byHeight := make(map[uint64]map[string]string)
+		for _, target := range targets {
+			if v := m.views[target.Name]; v != nil {
+				for h, b := range v.blocks {
+					if byHeight[h] == nil {
+						byHeight[h] = make(map[string]string)
+					}
+					byHeight[h][target.Name] = b.hash
+				}
+			}
+		}
+
+		height, hashes, divergent := uint64(0), map[string]string(nil), false
+		for h, seen := range byHeight {
+			if distinct(seen) > 1 && (!divergent || h < height) {
+				height, hashes, divergent = h, seen, true
+			}
+		}
+
+		fork := m.active[shard]
+		switch {
+		case divergent && fork == nil:
+			fork = &Fork{Shard: shard, Height: height, Hashes: hashes, Start: time.Now()}
+			m.active[shard] = fork
+			m.report.Forks = append(m.report.Forks, fork)
+			m.event("shard %d forked at height %d: %v", shard, height, hashes)
+		case divergent && height < fork.Height:
+			fork.Height, fork.Hashes = height, hashes
+		case !divergent && fork != nil:
+			fork.Converged = time.Now()
+			delete(m.active, shard)
+			m.event("shard %d converged after %s, fork at height %d", shard, fork.Duration(), fork.Height)
+		}
+	}
+}
+
+func distinct(hashes map[string]string) int {
+	set := make(map[string]bool)
+	for _, hash := range hashes {
+		set[hash] = true
+	}
+
+	return len(set)
+}
+
+// Report returns a copy of what was observed so far.
+func (m *Monitor) Report() *Report {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	report := m.report
+	report.Forks = make([]*Fork, len(m.report.Forks))
+	for i, f := range m.report.Forks {
+		copied := *f
+		report.Forks[i] = &copied
+	}
+
+	report.Reorgs = append([]*Reorg{}, m.report.Reorgs...)
+	return &report
+}
+
+func (m *Monitor) event(format string, args ...interface{}) {
+	if m.Events != nil {
+		m.Events(format, args...)
+	}
+}
+
+type headBlock struct {
+	height uint64
+	block
+}
+
+// block returns the block at the height, -1 for the head. The txs are read
+// as hashes, or from the tx objects of nodes that print full txs.
+func (m *Monitor) block(addr string, height int64) (*headBlock, error) {
+	output, err := m.run("getblock", "--height", strconv.FormatInt(height, 10), "--address", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var b struct {
+		Hash   string `json:"hash"`
+		Header struct {
+			Height uint64
+		} `json:"header"`
+		Transactions []interface{} `json:"transactions"`
+	}
+	if err = json.Unmarshal(output, &b); err != nil {
+		return nil, fmt.Errorf("invalid block %d: %s", height, err)
+	}
+
+	result := &headBlock{height: b.Header.Height, block: block{hash: b.Hash}}
+	for i, tx := range b.Transactions {
+		// the first tx is the miner reward, it goes away with its block
+		if i == 0 {
+			continue
+		}
+
+		switch value := tx.(type) {
+		case string:
+			result.txs = append(result.txs, value)
+		case map[string]interface{}:
+			if hash, ok := value["hash"].(string); ok {
+				result.txs = append(result.txs, hash)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (m *Monitor) hasReceipt(addr, txHash string) bool {
+	_, err := m.run("getreceipt", "--hash", txHash, "--address", addr)
+	return err == nil
+}
+
+func (m *Monitor) run(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cmdTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, m.Client, args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s err: %s", args[0], bytes.TrimSpace(output))
+	}
+
+	return output, nil
+}
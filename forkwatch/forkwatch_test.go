@@ -0,0 +1,71 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package forkwatch
+
+import (
+	"testing"
+)
+
+func Test_Monitor_Compare(t *testing.T) {
+	m := &Monitor{
+		Targets: []Target{{Name: "a", Shard: 1}, {Name: "b", Shard: 1}, {Name: "c", Shard: 1}, {Name: "d", Shard: 2}},
+		views:   make(map[string]*view),
+		active:  make(map[uint]*Fork),
+	}
+
+	// every step sets the views of the nodes and checks the forks after
+	// comparing, converged is whether the last fork seen is converged
+	steps := []struct {
+		name  string
+		views map[string]map[uint64]string
+		forks int
+		// height is the height of the active fork of shard 1, 0 for none
+		height    uint64
+		converged bool
+	}{
+		{"agree", map[string]map[uint64]string{"a": {10: "x"}, "b": {10: "x"}, "c": {10: "x"}, "d": {10: "y"}}, 0, 0, false},
+		{"height only on one node", map[string]map[uint64]string{"a": {10: "x", 11: "p"}, "b": {10: "x"}}, 0, 0, false},
+		{"fork", map[string]map[uint64]string{"a": {10: "x", 11: "p"}, "b": {10: "x", 11: "q"}}, 1, 11, false},
+		{"fork lowered", map[string]map[uint64]string{"a": {9: "w", 10: "x", 11: "p"}, "c": {9: "v", 10: "x", 11: "p"}}, 1, 9, false},
+		{"fork raised stays at the lowest height", map[string]map[uint64]string{"c": {9: "w", 10: "x", 11: "p"}}, 1, 9, false},
+		{"converged", map[string]map[uint64]string{"b": {9: "w", 10: "x", 11: "p"}}, 1, 0, true},
+		{"fork again", map[string]map[uint64]string{"c": {9: "w", 10: "x", 11: "r"}}, 2, 11, false},
+		{"other shard alone", map[string]map[uint64]string{"d": {10: "z", 11: "s"}}, 2, 11, false},
+	}
+
+	for _, step := range steps {
+		for name, blocks := range step.views {
+			v := &view{blocks: make(map[uint64]block)}
+			for h, hash := range blocks {
+				v.blocks[h] = block{hash: hash}
+			}
+			m.views[name] = v
+		}
+
+		m.compare()
+		report := m.Report()
+		if len(report.Forks) != step.forks {
+			t.Fatalf("Test_Monitor_Compare %s sees %d forks, expected %d", step.name, len(report.Forks), step.forks)
+		}
+
+		fork := m.active[1]
+		if step.height == 0 && fork != nil {
+			t.Fatalf("Test_Monitor_Compare %s has a fork at height %d, expected none", step.name, fork.Height)
+		}
+
+		if step.height != 0 && (fork == nil || fork.Height != step.height) {
+			t.Fatalf("Test_Monitor_Compare %s fork is %+v, expected one at height %d", step.name, fork, step.height)
+		}
+
+		if _, ok := m.active[2]; ok {
+			t.Fatalf("Test_Monitor_Compare %s forks shard 2 with a single node", step.name)
+		}
+
+		if last := len(report.Forks) - 1; last >= 0 && report.Forks[last].Converged.IsZero() == step.converged {
+			t.Fatalf("Test_Monitor_Compare %s last fork converged is %t, expected %t", step.name, !step.converged, step.converged)
+		}
+	}
+}
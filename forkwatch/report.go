@@ -0,0 +1,89 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package forkwatch
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Unconverged returns the forks that are still active.
+func (r *Report) Unconverged() []*Fork {
+	var forks []*Fork
+	for _, f := range r.Forks {
+		if f.Converged.IsZero() {
+			forks = append(forks, f)
+		}
+	}
+
+	return forks
+}
+
+// MaxReorgDepth returns the depth of the deepest reorg.
+func (r *Report) MaxReorgDepth() uint64 {
+	depth := uint64(0)
+	for _, reorg := range r.Reorgs {
+		if reorg.Depth > depth {
+			depth = reorg.Depth
+		}
+	}
+
+	return depth
+}
+
+// MaxConvergence returns the longest time a fork took to converge.
+func (r *Report) MaxConvergence() time.Duration {
+	longest := time.Duration(0)
+	for _, f := range r.Forks {
+		if d := f.Duration(); d > longest {
+			longest = d
+		}
+	}
+
+	return longest
+}
+
+// Orphaned returns the orphaned txs of all reorgs.
+func (r *Report) Orphaned() []string {
+	var txs []string
+	for _, reorg := range r.Reorgs {
+		txs = append(txs, reorg.Orphaned...)
+	}
+
+	return txs
+}
+
+// Write prints the forks and reorgs.
+func (r *Report) Write(w io.Writer) {
+	fmt.Fprintf(w, "observed %s in %d polls, %d poll errors\n", time.Since(r.Start).Round(time.Second), r.Polls, r.Errors)
+	fmt.Fprintf(w, "%d forks, longest convergence %s, %d unconverged\n", len(r.Forks), r.MaxConvergence().Round(time.Second), len(r.Unconverged()))
+	for _, f := range r.Forks {
+		state := "converged after " + f.Duration().Round(time.Second).String()
+		if f.Converged.IsZero() {
+			state = "diverging for " + f.Duration().Round(time.Second).String()
+		}
+
+		fmt.Fprintf(w, "  %s shard %d at height %d %s: %v\n", f.Start.Format("15:04:05"), f.Shard, f.Height, state, f.Hashes)
+	}
+
+	fmt.Fprintf(w, "%d reorgs, max depth %d, %d orphaned txs\n", len(r.Reorgs), r.MaxReorgDepth(), len(r.Orphaned()))
+	for _, reorg := range r.Reorgs {
+		fmt.Fprintf(w, "  %s node %s of shard %d from height %d depth %d", reorg.At.Format("15:04:05"), reorg.Node, reorg.Shard, reorg.Height, reorg.Depth)
+		if len(reorg.Orphaned) > 0 {
+			fmt.Fprintf(w, ", orphaned %s", strings.Join(reorg.Orphaned, " "))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// String returns the report as written by Write.
+func (r *Report) String() string {
+	var b strings.Builder
+	r.Write(&b)
+	return b.String()
+}
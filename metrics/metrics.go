@@ -0,0 +1,204 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+// cmdTimeout bounds every cli call, a slow node must not stall the sampling.
+const cmdTimeout = 10 * time.Second
+
+// Target is a node whose metrics are sampled.
+type Target struct {
+	Name string
+	Addr string
+}
+
+// Sample is the state of a node at a time. The node pushes its own metrics
+// to influxdb, so they are read from the rpc and the process instead. A value
+// that could not be read is -1, RSS is -1 as well for nodes on other hosts.
+type Sample struct {
+	Time   time.Time `json:"time"`
+	Node   string    `json:"node"`
+	Height int64     `json:"height"`
+	Pool   int64     `json:"pool"`
+	Peers  int64     `json:"peers"`
+	RSS    int64     `json:"rss"`
+}
+
+// Scraper samples the targets periodically between Start and Stop.
+type Scraper struct {
+	Client   string
+	Targets  []Target
+	Interval time.Duration
+
+	mutex   sync.Mutex
+	samples []Sample
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// Start begins sampling in the background, the first samples are taken at once.
+func (s *Scraper) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel, s.done = cancel, make(chan struct{})
+
+	interval := s.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.Scrape()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends sampling and returns all samples.
+func (s *Scraper) Stop() []Sample {
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+		s.cancel = nil
+	}
+
+	return s.Samples()
+}
+
+// Samples returns the samples taken so far in time order.
+func (s *Scraper) Samples() []Sample {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Sample{}, s.samples...)
+}
+
+// Scrape takes one sample of every target.
+func (s *Scraper) Scrape() {
+	var wg sync.WaitGroup
+	samples := make([]Sample, len(s.Targets))
+	for i, target := range s.Targets {
+		wg.Add(1)
+		go func(i int, target Target) {
+			defer wg.Done()
+			samples[i] = s.sample(target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	s.mutex.Lock()
+	s.samples = append(s.samples, samples...)
+	s.mutex.Unlock()
+}
+
+func (s *Scraper) sample(target Target) Sample {
+	sample := Sample{Time: time.Now(), Node: target.Name, Height: -1, Pool: -1, Peers: -1, RSS: -1}
+	if head, err := common.GetBlock(nil, s.Client, -1, target.Addr); err == nil {
+		sample.Height = int64(head.Header.Height)
+	}
+
+	if pool, err := common.GetPoolCountTxs(nil, s.Client, target.Addr); err == nil {
+		sample.Pool = pool
+	}
+
+	if output, err := s.run("p2p", "peers", "--address", target.Addr); err == nil {
+		if peers, err := strconv.ParseInt(string(bytes.TrimSpace(output)), 10, 64); err == nil {
+			sample.Peers = peers
+		}
+	}
+
+	if rss, err := rssOf(target.Addr); err == nil {
+		sample.RSS = rss
+	}
+
+	return sample
+}
+
+func (s *Scraper) run(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cmdTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, s.Client, args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err, bytes.TrimSpace(output))
+	}
+
+	return output, nil
+}
+
+// Of returns the samples of the node taken at or after from.
+func Of(samples []Sample, node string, from time.Time) []Sample {
+	var selected []Sample
+	for _, sample := range samples {
+		if sample.Node == node && !sample.Time.Before(from) {
+			selected = append(selected, sample)
+		}
+	}
+
+	return selected
+}
+
+// PoolDrainedWithin checks that the pool of the node shrinks back to level
+// within blocks blocks after from. Pool sizes of other suites add up in the
+// pool as well, so level is usually the size before the test filled it.
+func PoolDrainedWithin(samples []Sample, node string, from time.Time, level, blocks int64) error {
+	selected := Of(samples, node, from)
+	start := int64(-1)
+	for _, sample := range selected {
+		if sample.Height < 0 || sample.Pool < 0 {
+			continue
+		}
+
+		if start < 0 {
+			start = sample.Height
+		}
+
+		if sample.Height > start+blocks {
+			break
+		}
+
+		if sample.Pool <= level {
+			return nil
+		}
+	}
+
+	if start < 0 {
+		return fmt.Errorf("no sample of %s since %s", node, from.Format("15:04:05"))
+	}
+
+	return fmt.Errorf("pool of %s is not back to %d within %d blocks above %d", node, level, blocks, start)
+}
+
+// MinPeers returns the smallest peer count of the node since from, -1 if it
+// is unknown.
+func MinPeers(samples []Sample, node string, from time.Time) int64 {
+	min := int64(-1)
+	for _, sample := range Of(samples, node, from) {
+		if sample.Peers >= 0 && (min < 0 || sample.Peers < min) {
+			min = sample.Peers
+		}
+	}
+
+	return min
+}
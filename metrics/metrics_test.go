@@ -0,0 +1,69 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+var base = time.Date(2019, 1, 1, 4, 0, 0, 0, time.UTC)
+
+// at returns a sample of the node taken seconds after base.
+func at(seconds int, node string, height, pool, peers int64) Sample {
+	return Sample{Time: base.Add(time.Duration(seconds) * time.Second), Node: node, Height: height, Pool: pool, Peers: peers}
+}
+
+func Test_PoolDrainedWithin(t *testing.T) {
+	cases := []struct {
+		name    string
+		samples []Sample
+		from    time.Time
+		level   int64
+		blocks  int64
+		drained bool
+	}{
+		{"drained at once", []Sample{at(0, "a", 10, 0, 1)}, base, 0, 3, true},
+		{"drained within blocks", []Sample{at(0, "a", 10, 50, 1), at(1, "a", 12, 20, 1), at(2, "a", 13, 5, 1)}, base, 5, 3, true},
+		{"drained too late", []Sample{at(0, "a", 10, 50, 1), at(1, "a", 12, 20, 1), at(2, "a", 14, 0, 1)}, base, 5, 3, false},
+		{"never drained", []Sample{at(0, "a", 10, 50, 1), at(1, "a", 11, 40, 1)}, base, 5, 3, false},
+		{"unknown values skipped", []Sample{at(0, "a", -1, 0, 1), at(1, "a", 10, 50, 1), at(2, "a", 12, -1, 1), at(3, "a", 13, 0, 1)}, base, 0, 3, true},
+		{"unknown start height", []Sample{at(0, "a", -1, 0, 1), at(1, "a", 20, 50, 1), at(2, "a", 24, 0, 1)}, base, 0, 3, false},
+		{"other node ignored", []Sample{at(0, "b", 10, 0, 1), at(1, "a", 10, 50, 1)}, base, 0, 3, false},
+		{"samples before from ignored", []Sample{at(0, "a", 10, 0, 1), at(5, "a", 11, 50, 1)}, base.Add(time.Second), 0, 3, false},
+		{"no samples", nil, base, 0, 3, false},
+	}
+
+	for _, c := range cases {
+		err := PoolDrainedWithin(c.samples, "a", c.from, c.level, c.blocks)
+		if drained := err == nil; drained != c.drained {
+			t.Fatalf("Test_PoolDrainedWithin %s drained is %v, expected %v, err: %v", c.name, drained, c.drained, err)
+		}
+	}
+}
+
+func Test_MinPeers(t *testing.T) {
+	cases := []struct {
+		name    string
+		samples []Sample
+		from    time.Time
+		min     int64
+	}{
+		{"lowest", []Sample{at(0, "a", 1, 0, 4), at(1, "a", 1, 0, 2), at(2, "a", 1, 0, 3)}, base, 2},
+		{"zero peers", []Sample{at(0, "a", 1, 0, 4), at(1, "a", 1, 0, 0)}, base, 0},
+		{"unknown skipped", []Sample{at(0, "a", 1, 0, -1), at(1, "a", 1, 0, 3)}, base, 3},
+		{"all unknown", []Sample{at(0, "a", 1, 0, -1)}, base, -1},
+		{"other node ignored", []Sample{at(0, "b", 1, 0, 1), at(1, "a", 1, 0, 3)}, base, 3},
+		{"samples before from ignored", []Sample{at(0, "a", 1, 0, 1), at(2, "a", 1, 0, 3)}, base.Add(time.Second), 3},
+		{"no samples", nil, base, -1},
+	}
+
+	for _, c := range cases {
+		if min := MinPeers(c.samples, "a", c.from); min != c.min {
+			t.Fatalf("Test_MinPeers %s min is %d, expected %d", c.name, min, c.min)
+		}
+	}
+}
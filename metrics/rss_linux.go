@@ -0,0 +1,111 @@
+//go:build linux
+// +build linux
+
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// rssOf returns the resident memory in bytes of the local process listening
+// on the address, found through the socket inodes in /proc.
+func rssOf(addr string) (int64, error) {
+	_, portText, err := net.SplitHostPort(addr)
+	if err != nil {
+		return 0, err
+	}
+
+	port, err := strconv.ParseUint(portText, 10, 16)
+	if err != nil {
+		return 0, err
+	}
+
+	inodes := make(map[string]bool)
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		listening(table, port, inodes)
+	}
+
+	if len(inodes) == 0 {
+		return 0, fmt.Errorf("no local process listens on %s", addr)
+	}
+
+	fds, _ := filepath.Glob("/proc/[0-9]*/fd/*")
+	for _, fd := range fds {
+		link, err := os.Readlink(fd)
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+
+		if inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] {
+			return rssOfPid(filepath.Dir(filepath.Dir(fd)))
+		}
+	}
+
+	return 0, fmt.Errorf("no readable process listens on %s", addr)
+}
+
+// listening adds the inodes of the sockets listening on the port in the table.
+func listening(table string, port uint64, inodes map[string]bool) {
+	file, err := os.Open(table)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// sl local_address rem_address st ... inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != "0A" {
+			continue
+		}
+
+		local := fields[1]
+		idx := strings.LastIndexByte(local, ':')
+		if idx < 0 {
+			continue
+		}
+
+		if p, err := strconv.ParseUint(local[idx+1:], 16, 16); err == nil && p == port {
+			inodes[fields[9]] = true
+		}
+	}
+}
+
+func rssOfPid(dir string) (int64, error) {
+	status, err := ioutil.ReadFile(filepath.Join(dir, "status"))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(string(status), "\n") {
+		if !strings.HasPrefix(line, "VmRSS:") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			break
+		}
+
+		kb, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return kb * 1024, nil
+	}
+
+	return 0, fmt.Errorf("no VmRSS in %s/status", dir)
+}
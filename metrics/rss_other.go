@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package metrics
+
+import "errors"
+
+// rssOf is only supported on linux, where the process is found through /proc.
+func rssOf(addr string) (int64, error) {
+	return 0, errors.New("resident memory is only read on linux")
+}
@@ -0,0 +1,130 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package metrics
+
+import (
+	"html/template"
+	"sort"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/chart"
+)
+
+// chart dimensions in pixels
+const (
+	chartWidth  = 800
+	chartHeight = 200
+)
+
+// nodeColors are the line colors of the nodes, in the order of their names
+var nodeColors = []string{"#36c", "#3a3", "#c80", "#93c", "#0aa", "#c36"}
+
+// field is a sampled value charted in the report
+type field struct {
+	name  string
+	value func(Sample) int64
+}
+
+var fields = []field{
+	{"height", func(s Sample) int64 { return s.Height }},
+	{"pool", func(s Sample) int64 { return s.Pool }},
+	{"peers", func(s Sample) int64 { return s.Peers }},
+	{"rss MB", func(s Sample) int64 {
+		if s.RSS < 0 {
+			return -1
+		}
+		return s.RSS >> 20
+	}},
+}
+
+// Span is the time a test ran, failed spans are marked in the charts.
+type Span struct {
+	Name   string    `json:"name"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Failed bool      `json:"failed"`
+}
+
+// Fields returns the names of the charted values.
+func Fields() []string {
+	var names []string
+	for _, f := range fields {
+		names = append(names, f.name)
+	}
+
+	return names
+}
+
+// SeriesSVG renders the field of the samples over time as an svg line chart
+// with a line per node. Unknown values are left out, failed test spans are
+// shaded red with the test name as tooltip.
+func SeriesSVG(fieldName string, samples []Sample, spans []Span) template.HTML {
+	var f *field
+	for i := range fields {
+		if fields[i].name == fieldName {
+			f = &fields[i]
+		}
+	}
+
+	c := chart.New(chartWidth, chartHeight, fieldName)
+	if f == nil || len(samples) == 0 {
+		return c.Empty("no samples")
+	}
+
+	first, last, max := samples[0].Time, samples[0].Time, int64(0)
+	series := make(map[string][]Sample)
+	for _, s := range samples {
+		if s.Time.Before(first) {
+			first = s.Time
+		}
+		if s.Time.After(last) {
+			last = s.Time
+		}
+		if v := f.value(s); v > max {
+			max = v
+		}
+		series[s.Node] = append(series[s.Node], s)
+	}
+
+	duration := last.Sub(first)
+	if duration <= 0 {
+		duration = time.Second
+	}
+
+	x := func(t time.Time) float64 {
+		return c.X(float64(t.Sub(first)) / float64(duration))
+	}
+
+	for _, span := range spans {
+		if span.Failed && !span.End.Before(first) && !span.Start.After(last) {
+			c.Shade(x(span.Start), x(span.End), span.Name)
+		}
+	}
+
+	c.Axes(float64(max), 0)
+	c.Label(c.X(0), "start", first.Format("15:04:05"))
+	c.Label(c.X(1), "end", last.Format("15:04:05"))
+
+	nodes := make([]string, 0, len(series))
+	for node := range series {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	for i, node := range nodes {
+		color := nodeColors[i%len(nodeColors)]
+		var points [][2]float64
+		for _, s := range series[node] {
+			if v := f.value(s); v >= 0 {
+				points = append(points, [2]float64{x(s.Time), c.Y(float64(v))})
+			}
+		}
+		c.Line(color, 1.5, points)
+		c.Legend(i, color, node)
+	}
+
+	return c.SVG()
+}
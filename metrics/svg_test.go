@@ -0,0 +1,67 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_SeriesSVG(t *testing.T) {
+	samples := []Sample{at(0, "a", 1, 0, 2), at(10, "a", 2, 0, -1), at(20, "a", 3, 0, 4), at(0, "<b>", 1, 0, 3)}
+	span := func(name string, from, to int, failed bool) Span {
+		return Span{name, base.Add(time.Duration(from) * time.Second), base.Add(time.Duration(to) * time.Second), failed}
+	}
+
+	cases := []struct {
+		name     string
+		field    string
+		samples  []Sample
+		spans    []Span
+		contains []string
+		excludes []string
+		points   []int
+	}{
+		{"unknown field", "nope", samples, nil, []string{"no samples"}, []string{"<polyline"}, nil},
+		{"no samples", "peers", nil, nil, []string{"no samples"}, []string{"<polyline"}, nil},
+		{"line per node, unknown values left out", "peers", samples, nil,
+			[]string{"&lt;b&gt;", ">04:00:00<", ">04:00:20<"}, []string{"<b>", "no samples", "<rect"}, []int{1, 2}},
+		{"failed spans shaded", "peers", samples, []Span{span("Test_<x>", 5, 15, true), span("Test_Passed", 5, 15, false), span("Test_Before", -20, -10, true)},
+			[]string{"<title>Test_&lt;x&gt;</title>"}, []string{"Test_Passed", "Test_Before", "Test_<x>"}, []int{1, 2}},
+	}
+
+	for _, c := range cases {
+		svg := string(SeriesSVG(c.field, c.samples, c.spans))
+		if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(svg, "</svg>") {
+			t.Fatalf("Test_SeriesSVG %s is not an svg: %s", c.name, svg)
+		}
+
+		for _, s := range c.contains {
+			if !strings.Contains(svg, s) {
+				t.Fatalf("Test_SeriesSVG %s does not contain %s: %s", c.name, s, svg)
+			}
+		}
+
+		for _, s := range c.excludes {
+			if strings.Contains(svg, s) {
+				t.Fatalf("Test_SeriesSVG %s contains %s: %s", c.name, s, svg)
+			}
+		}
+
+		lines := strings.Split(svg, "<polyline")[1:]
+		if len(lines) != len(c.points) {
+			t.Fatalf("Test_SeriesSVG %s has %d lines, expected %d", c.name, len(lines), len(c.points))
+		}
+
+		for i, line := range lines {
+			points := strings.Fields(line[strings.Index(line, `points="`)+len(`points="`) : strings.Index(line, `"/>`)])
+			if len(points) != c.points[i] {
+				t.Fatalf("Test_SeriesSVG %s line %d has %d points, expected %d", c.name, i, len(points), c.points[i])
+			}
+		}
+	}
+}
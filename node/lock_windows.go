@@ -0,0 +1,36 @@
+//go:build windows
+// +build windows
+
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package node
+
+import (
+	"os"
+	"time"
+)
+
+// lockFile blocks until it creates the file exclusively. A lock file left by
+// a crashed process has to be removed by hand.
+func lockFile(path string) (*os.File, error) {
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
+		if err == nil {
+			return file, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		time.Sleep(time.Second)
+	}
+}
+
+func unlockFile(file *os.File) {
+	file.Close()
+	os.Remove(file.Name())
+}
@@ -0,0 +1,299 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// ...
+const (
+	startTimeout = 60 * time.Second
+	stopTimeout  = 15 * time.Second
+	// startGrace is how long the process must survive after its rpc answers,
+	// a node that failed to bind a port exits shortly after start up
+	startGrace = 2 * time.Second
+)
+
+// Node is a seele node in the topology. A node with a config file is managed,
+// the harness starts and stops its process. A node without one is external,
+// it is only reached through its addresses.
+type Node struct {
+	Name     string `json:"name"`
+	Shard    uint   `json:"shard"`
+	Config   string `json:"config"`
+	Accounts string `json:"accounts"`
+	RPCAddr  string `json:"rpc"`
+	P2PAddr  string `json:"p2p"`
+	HTTPAddr string `json:"http"`
+	WSAddr   string `json:"ws"`
+	LogFile  string `json:"log"`
+	// Light nodes sync headers only, the light cli talks to them
+	Light bool `json:"light"`
+
+	binary    string
+	runConfig string
+	proxyAddr string
+
+	mutex sync.Mutex
+	cmd   *exec.Cmd
+	exit  chan struct{}
+}
+
+// New returns a managed node outside of a topology, started from the config
+// with the binary.
+func New(binary, name, config, rpcAddr string) *Node {
+	return &Node{Name: name, Config: config, RPCAddr: rpcAddr, binary: binary}
+}
+
+// Managed returns whether the harness owns the node process.
+func (n *Node) Managed() bool {
+	return n.Config != ""
+}
+
+// Addr returns the rpc address the cli should use, which is the proxy in
+// front of the node once the topology is interposed.
+func (n *Node) Addr() string {
+	if n.proxyAddr != "" {
+		return n.proxyAddr
+	}
+
+	return n.RPCAddr
+}
+
+// Running returns whether the managed node process is alive.
+func (n *Node) Running() bool {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.running()
+}
+
+func (n *Node) running() bool {
+	if n.cmd == nil {
+		return false
+	}
+
+	select {
+	case <-n.exit:
+		return false
+	default:
+		return true
+	}
+}
+
+// Serving returns whether a process accepts connections on the rpc address,
+// which may be a process the node did not start.
+func (n *Node) Serving() bool {
+	conn, err := net.DialTimeout("tcp", n.RPCAddr, time.Second)
+	if err != nil {
+		return false
+	}
+
+	conn.Close()
+	return true
+}
+
+// Start launches the node process and waits until its rpc port accepts
+// connections and the process is still alive. It fails if another process
+// already serves the rpc address.
+func (n *Node) Start() error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if !n.Managed() {
+		return fmt.Errorf("node %s is external and cannot be started", n.Name)
+	}
+
+	if n.running() {
+		return nil
+	}
+
+	if n.Serving() {
+		return fmt.Errorf("node %s rpc %s is already served by another process", n.Name, n.RPCAddr)
+	}
+
+	config := n.Config
+	if n.runConfig != "" {
+		config = n.runConfig
+	}
+
+	cmd := exec.Command(n.binary, "start", "-c", config)
+	if n.Light {
+		cmd.Args = append(cmd.Args, "--light")
+	}
+
+	if n.Accounts != "" {
+		cmd.Args = append(cmd.Args, "--accounts", n.Accounts)
+	}
+
+	if n.LogFile != "" {
+		logFile, err := os.OpenFile(n.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("node %s open log file err: %s", n.Name, err)
+		}
+		defer logFile.Close()
+		cmd.Stdout, cmd.Stderr = logFile, logFile
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("node %s start err: %s", n.Name, err)
+	}
+
+	exit := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exit)
+	}()
+	n.cmd, n.exit = cmd, exit
+
+	deadline := time.Now().Add(startTimeout)
+	for time.Now().Before(deadline) {
+		if n.Serving() {
+			select {
+			case <-exit:
+				return fmt.Errorf("node %s exited after start up, see %s", n.Name, n.LogFile)
+			case <-time.After(startGrace):
+				return nil
+			}
+		}
+
+		select {
+		case <-exit:
+			return fmt.Errorf("node %s exited during start up, see %s", n.Name, n.LogFile)
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+
+	return fmt.Errorf("node %s rpc %s is not reachable after %s", n.Name, n.RPCAddr, startTimeout)
+}
+
+// Stop interrupts the node process and kills it if it does not exit in time.
+func (n *Node) Stop() error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if !n.Managed() {
+		return fmt.Errorf("node %s is external and cannot be stopped", n.Name)
+	}
+
+	if !n.running() {
+		return nil
+	}
+
+	// interrupt is not supported on windows, kill right away there
+	if err := n.cmd.Process.Signal(os.Interrupt); err != nil {
+		n.cmd.Process.Kill()
+	}
+
+	select {
+	case <-n.exit:
+	case <-time.After(stopTimeout):
+		n.cmd.Process.Kill()
+		<-n.exit
+	}
+
+	return nil
+}
+
+// Kill stops the node process without giving it a chance to shut down cleanly.
+func (n *Node) Kill() error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if !n.running() {
+		return nil
+	}
+
+	n.cmd.Process.Kill()
+	<-n.exit
+	return nil
+}
+
+// Restart stops and starts the node.
+func (n *Node) Restart() error {
+	if err := n.Stop(); err != nil {
+		return err
+	}
+
+	return n.Start()
+}
+
+// staticNodeAddrRe matches the address of a static node like
+// snode://<id>@127.0.0.1:8057[1]
+var staticNodeAddrRe = regexp.MustCompile(`@([^\[\]]+)`)
+
+// normalizeAddr returns the address with the local host spelled 127.0.0.1,
+// so the addresses of the topology and of the node configs compare equal.
+func normalizeAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	if host == "" || host == "localhost" || host == "0.0.0.0" {
+		host = "127.0.0.1"
+	}
+
+	return net.JoinHostPort(host, port)
+}
+
+// rewriteStaticNodes writes a copy of the node config in which the addresses
+// in p2p.staticNodes are replaced, the copy is used on next start. The keys
+// of replace are normalized addresses. A static node missing from replace
+// would be a link no proxy can partition, so it is an error.
+func (n *Node) rewriteStaticNodes(replace map[string]string) error {
+	bytes, err := ioutil.ReadFile(n.Config)
+	if err != nil {
+		return err
+	}
+
+	var config map[string]interface{}
+	if err = json.Unmarshal(bytes, &config); err != nil {
+		return fmt.Errorf("node %s invalid config %s: %s", n.Name, n.Config, err)
+	}
+
+	p2p, ok := config["p2p"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("node %s config %s has no p2p section", n.Name, n.Config)
+	}
+
+	if staticNodes, ok := p2p["staticNodes"].([]interface{}); ok {
+		for i, item := range staticNodes {
+			str, _ := item.(string)
+			match := staticNodeAddrRe.FindStringSubmatchIndex(str)
+			if match == nil {
+				return fmt.Errorf("node %s config %s has an invalid static node %q", n.Name, n.Config, str)
+			}
+
+			to, ok := replace[normalizeAddr(str[match[2]:match[3]])]
+			if !ok {
+				return fmt.Errorf("node %s static node %s is not a p2p address of the topology, its link cannot be proxied", n.Name, str)
+			}
+			staticNodes[i] = str[:match[2]] + to + str[match[3]:]
+		}
+	}
+
+	if bytes, err = json.MarshalIndent(config, "", "\t"); err != nil {
+		return err
+	}
+
+	runConfig := filepath.Join(os.TempDir(), fmt.Sprintf("e2e-%s-%d.json", n.Name, os.Getpid()))
+	if err = ioutil.WriteFile(runConfig, bytes, 0644); err != nil {
+		return err
+	}
+
+	n.runConfig = runConfig
+	return nil
+}
@@ -0,0 +1,219 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/seeleteam/e2e-blackbox/proxy"
+)
+
+const (
+	// CLI is the endpoint name of the command line tools in the proxy network.
+	CLI = "cli"
+	// ExclusiveEnv is set by the runner when no other package uses the nodes,
+	// tests that stop or partition shared nodes only run then
+	ExclusiveEnv = "E2E_EXCLUSIVE"
+	// lockName is the file in the temp folder that serializes the packages
+	// owning the topology
+	lockName = "e2e-topology.lock"
+)
+
+// Topology describes the nodes a test run talks to. It is loaded from a json
+// file, relative paths in it are resolved against the file's folder.
+type Topology struct {
+	Binary string  `json:"binary"`
+	Nodes  []*Node `json:"nodes"`
+
+	lock *os.File
+}
+
+// LoadTopology loads the topology file.
+func LoadTopology(file string) (*Topology, error) {
+	bytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var topology Topology
+	if err = json.Unmarshal(bytes, &topology); err != nil {
+		return nil, fmt.Errorf("invalid topology file %s: %s", file, err)
+	}
+
+	dir := filepath.Dir(file)
+	topology.Binary = resolve(dir, topology.Binary)
+	names := make(map[string]bool)
+	for _, n := range topology.Nodes {
+		if n.Name == "" || names[n.Name] {
+			return nil, fmt.Errorf("invalid topology file %s: node name %q is empty or duplicated", file, n.Name)
+		}
+		names[n.Name] = true
+
+		if n.Managed() && topology.Binary == "" {
+			return nil, fmt.Errorf("invalid topology file %s: node %s is managed but no binary is set", file, n.Name)
+		}
+
+		n.Config, n.Accounts, n.LogFile = resolve(dir, n.Config), resolve(dir, n.Accounts), resolve(dir, n.LogFile)
+		n.binary = topology.Binary
+	}
+
+	return &topology, nil
+}
+
+func resolve(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(dir, path)
+}
+
+// Node returns the node with the name, nil if there is none.
+func (t *Topology) Node(name string) *Node {
+	for _, n := range t.Nodes {
+		if n.Name == name {
+			return n
+		}
+	}
+
+	return nil
+}
+
+// Shard returns the nodes of a shard.
+func (t *Topology) Shard(shard uint) []*Node {
+	var nodes []*Node
+	for _, n := range t.Nodes {
+		if n.Shard == shard {
+			nodes = append(nodes, n)
+		}
+	}
+
+	return nodes
+}
+
+// StartAll starts all managed nodes. A managed node whose rpc address already
+// answers was started by the runner, the topology attaches to it and neither
+// restarts nor stops it.
+func (t *Topology) StartAll() error {
+	for _, n := range t.Nodes {
+		if !n.Managed() || n.Serving() {
+			continue
+		}
+
+		if err := n.Start(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StopAll stops the managed nodes this topology started, attached nodes keep running.
+func (t *Topology) StopAll() {
+	for _, n := range t.Nodes {
+		if n.Managed() {
+			n.Stop()
+		}
+	}
+}
+
+// Interpose puts a proxy on the p2p link between every pair of nodes and on
+// the rpc link from the cli to every node. Managed nodes dial their static
+// nodes through the proxies from their next start on, so this should be
+// called before StartAll. Every static node must be a node of the topology.
+// Discovery starts from the static nodes over udp, the proxies relay tcp
+// only, so no node learns the direct address of another and every link can
+// be partitioned.
+func (t *Topology) Interpose(network *proxy.Network) error {
+	for _, from := range t.Nodes {
+		if from.Managed() {
+			replace := make(map[string]string)
+			for _, to := range t.Nodes {
+				if to == from || to.P2PAddr == "" {
+					continue
+				}
+
+				link, err := network.AddLink(from.Name, to.Name, to.P2PAddr)
+				if err != nil {
+					return err
+				}
+				replace[normalizeAddr(to.P2PAddr)] = link.Addr()
+			}
+
+			if err := from.rewriteStaticNodes(replace); err != nil {
+				return err
+			}
+		}
+
+		link, err := network.AddLink(CLI, from.Name, from.RPCAddr)
+		if err != nil {
+			return err
+		}
+		from.proxyAddr = link.Addr()
+	}
+
+	return nil
+}
+
+// Exclusive returns whether the test owns the nodes, only then it may stop or
+// partition them.
+func Exclusive() bool {
+	return os.Getenv(ExclusiveEnv) != ""
+}
+
+// Setup loads the topology file, interposes a proxy network and starts the
+// managed nodes that are not running yet. A missing file is not an error, the
+// topology is nil then and tests fall back to the fixed addresses in
+// testcase/common. go test runs packages in parallel processes, Setup blocks
+// until no other package holds the topology and keeps it until Teardown.
+func Setup(file string) (*Topology, *proxy.Network, error) {
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+
+	topology, err := LoadTopology(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if topology.lock, err = lockFile(filepath.Join(os.TempDir(), lockName)); err != nil {
+		return nil, nil, fmt.Errorf("lock topology err: %s", err)
+	}
+
+	network := proxy.NewNetwork()
+	if err = topology.Interpose(network); err != nil {
+		Teardown(topology, network)
+		return nil, nil, err
+	}
+
+	if err = topology.StartAll(); err != nil {
+		Teardown(topology, network)
+		return nil, nil, err
+	}
+
+	return topology, network, nil
+}
+
+// Teardown stops the managed nodes started by Setup, closes the proxy network
+// and releases the topology to other packages.
+func Teardown(topology *Topology, network *proxy.Network) {
+	if topology != nil {
+		topology.StopAll()
+	}
+
+	if network != nil {
+		network.Close()
+	}
+
+	if topology != nil && topology.lock != nil {
+		unlockFile(topology.lock)
+		topology.lock = nil
+	}
+}
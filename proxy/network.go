@@ -0,0 +1,135 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package proxy
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Network holds the proxies on the links between named endpoints, such as
+// two nodes talking p2p or the cli talking rpc to a node, so that faults can
+// be applied per link or to a whole partition at once.
+type Network struct {
+	mutex sync.Mutex
+	links map[string]*Proxy
+}
+
+// NewNetwork creates an empty network.
+func NewNetwork() *Network {
+	return &Network{
+		links: make(map[string]*Proxy),
+	}
+}
+
+func linkName(from, to string) string {
+	return from + "->" + to
+}
+
+// AddLink starts a proxy for traffic from one endpoint to another. The from
+// endpoint must then be configured to dial the returned proxy's Addr instead
+// of target.
+func (n *Network) AddLink(from, to, target string) (*Proxy, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	name := linkName(from, to)
+	if _, ok := n.links[name]; ok {
+		return nil, fmt.Errorf("link %s already exists", name)
+	}
+
+	p := New(name, target)
+	if err := p.Start("127.0.0.1:0"); err != nil {
+		return nil, err
+	}
+
+	n.links[name] = p
+	return p, nil
+}
+
+// Link returns the proxy from one endpoint to another, nil if there is none.
+func (n *Network) Link(from, to string) *Proxy {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.links[linkName(from, to)]
+}
+
+// Links returns all proxies whose traffic comes from or goes to the endpoint.
+func (n *Network) Links(endpoint string) []*Proxy {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	var result []*Proxy
+	for _, p := range n.links {
+		from, to := splitLinkName(p.Name)
+		if from == endpoint || to == endpoint {
+			result = append(result, p)
+		}
+	}
+
+	return result
+}
+
+// Partition splits the endpoints into groups that cannot reach each other.
+// Links between different groups are blackholed and their connections are
+// closed, links inside a group and links of unlisted endpoints are untouched.
+func (n *Network) Partition(groups ...[]string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	groupOf := make(map[string]int)
+	for i, group := range groups {
+		for _, endpoint := range group {
+			groupOf[endpoint] = i
+		}
+	}
+
+	for _, p := range n.links {
+		from, to := splitLinkName(p.Name)
+		fromGroup, ok1 := groupOf[from]
+		toGroup, ok2 := groupOf[to]
+		if ok1 && ok2 && fromGroup != toGroup {
+			p.SetBlackhole(true)
+			p.Disconnect()
+		}
+	}
+}
+
+// Isolate cuts every link of the endpoint.
+func (n *Network) Isolate(endpoint string) {
+	for _, p := range n.Links(endpoint) {
+		p.SetBlackhole(true)
+		p.Disconnect()
+	}
+}
+
+// Heal clears the faults on all links.
+func (n *Network) Heal() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for _, p := range n.links {
+		p.Reset()
+	}
+}
+
+// Close stops all proxies.
+func (n *Network) Close() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for name, p := range n.links {
+		p.Stop()
+		delete(n.links, name)
+	}
+}
+
+func splitLinkName(name string) (from, to string) {
+	if idx := strings.Index(name, "->"); idx >= 0 {
+		return name[:idx], name[idx+2:]
+	}
+
+	return name, ""
+}
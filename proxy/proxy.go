@@ -0,0 +1,354 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	dialTimeout = 5 * time.Second
+	bufferSize  = 32 * 1024
+	queueSize   = 1024
+)
+
+// Proxy is a userspace TCP proxy between a local listen address and a target
+// address. Faults set on the proxy apply to both directions of every
+// connection that goes through it.
+type Proxy struct {
+	// sent and received count the bytes forwarded to the target and back,
+	// first in the struct to stay 64 bit aligned for the atomic operations
+	sent     int64
+	received int64
+
+	Name   string
+	target string
+
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	// swallowed are the connections that lost data to the blackhole
+	swallowed map[net.Conn]struct{}
+	wg        sync.WaitGroup
+
+	mutex     sync.RWMutex
+	latency   time.Duration
+	jitter    time.Duration
+	dropRate  float64
+	bandwidth int64
+	blackhole bool
+	closed    bool
+}
+
+// chunk is a piece of stream data that becomes due after the configured latency.
+type chunk struct {
+	data []byte
+	due  time.Time
+}
+
+// New creates a proxy that forwards connections to target once started.
+func New(name, target string) *Proxy {
+	return &Proxy{
+		Name:      name,
+		target:    target,
+		conns:     make(map[net.Conn]struct{}),
+		swallowed: make(map[net.Conn]struct{}),
+	}
+}
+
+// Start listens on listenAddr and begins forwarding. Use "127.0.0.1:0" to
+// pick a free port, then read it back with Addr.
+func (p *Proxy) Start(listenAddr string) error {
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return fmt.Errorf("proxy %s listen on %s err: %s", p.Name, listenAddr, err)
+	}
+
+	p.listener = listener
+	p.wg.Add(1)
+	go p.accept()
+	return nil
+}
+
+// Addr returns the address clients should connect to instead of the target.
+func (p *Proxy) Addr() string {
+	if p.listener == nil {
+		return ""
+	}
+
+	return p.listener.Addr().String()
+}
+
+// Target returns the address the proxy forwards to.
+func (p *Proxy) Target() string {
+	return p.target
+}
+
+// Stop closes the listener and all forwarded connections.
+func (p *Proxy) Stop() {
+	p.mutex.Lock()
+	p.closed = true
+	p.mutex.Unlock()
+
+	if p.listener != nil {
+		p.listener.Close()
+	}
+
+	p.Disconnect()
+	p.wg.Wait()
+}
+
+// SetLatency delays every chunk of data by latency plus a random duration in [0, jitter).
+func (p *Proxy) SetLatency(latency, jitter time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.latency, p.jitter = latency, jitter
+}
+
+// SetDropRate sets the probability in [0, 1] that a new connection is refused
+// or that an established connection is reset when it carries data.
+func (p *Proxy) SetDropRate(rate float64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.dropRate = rate
+}
+
+// SetBandwidth limits each direction of a connection to bytesPerSec, 0 means unlimited.
+func (p *Proxy) SetBandwidth(bytesPerSec int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.bandwidth = bytesPerSec
+}
+
+// SetBlackhole makes the proxy silently swallow all traffic. Connections stay
+// open, so peers only notice through their own timeouts. Lifting it closes the
+// connections that lost data, their streams would go on after a gap.
+func (p *Proxy) SetBlackhole(on bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.blackhole = on
+	if !on {
+		p.closeSwallowed()
+	}
+}
+
+// Reset clears all faults. Established connections are kept, except those
+// that lost data to the blackhole.
+func (p *Proxy) Reset() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.latency, p.jitter, p.dropRate, p.bandwidth, p.blackhole = 0, 0, 0, 0, false
+	p.closeSwallowed()
+}
+
+// Disconnect closes all established connections, new connections are still accepted.
+func (p *Proxy) Disconnect() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for conn := range p.conns {
+		conn.Close()
+		delete(p.conns, conn)
+	}
+}
+
+// Transferred returns the bytes forwarded to the target and back to the clients.
+func (p *Proxy) Transferred() (sent, received int64) {
+	return atomic.LoadInt64(&p.sent), atomic.LoadInt64(&p.received)
+}
+
+// Connections returns the number of established connections.
+func (p *Proxy) Connections() int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return len(p.conns) / 2
+}
+
+func (p *Proxy) accept() {
+	defer p.wg.Done()
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		if p.shouldDrop() {
+			conn.Close()
+			continue
+		}
+
+		p.wg.Add(1)
+		go p.serve(conn)
+	}
+}
+
+func (p *Proxy) serve(src net.Conn) {
+	defer p.wg.Done()
+
+	dst, err := net.DialTimeout("tcp", p.target, dialTimeout)
+	if err != nil {
+		src.Close()
+		return
+	}
+
+	if !p.track(src, dst) {
+		src.Close()
+		dst.Close()
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go p.pipe(&wg, src, dst, &p.sent)
+	go p.pipe(&wg, dst, src, &p.received)
+	wg.Wait()
+
+	p.untrack(src, dst)
+}
+
+func (p *Proxy) track(conns ...net.Conn) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		return false
+	}
+
+	for _, conn := range conns {
+		p.conns[conn] = struct{}{}
+	}
+
+	return true
+}
+
+func (p *Proxy) untrack(conns ...net.Conn) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, conn := range conns {
+		conn.Close()
+		delete(p.conns, conn)
+		delete(p.swallowed, conn)
+	}
+}
+
+// swallow marks the connections if the blackhole is on and returns whether
+// their data is dropped.
+func (p *Proxy) swallow(conns ...net.Conn) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !p.blackhole {
+		return false
+	}
+
+	for _, conn := range conns {
+		p.swallowed[conn] = struct{}{}
+	}
+
+	return true
+}
+
+// closeSwallowed closes the connections that lost data, the mutex must be held.
+func (p *Proxy) closeSwallowed() {
+	for conn := range p.swallowed {
+		conn.Close()
+		delete(p.swallowed, conn)
+	}
+}
+
+// pipe copies from src to dst and adds the written bytes to counter. The
+// reader stamps every chunk with its due time and the writer holds it back
+// until then, so latency does not reduce throughput and the stream order is
+// preserved.
+func (p *Proxy) pipe(wg *sync.WaitGroup, src, dst net.Conn, counter *int64) {
+	defer wg.Done()
+
+	queue := make(chan chunk, queueSize)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		failed := false
+		for c := range queue {
+			if failed {
+				continue
+			}
+
+			if wait := time.Until(c.due); wait > 0 {
+				time.Sleep(wait)
+			}
+
+			if bandwidth := p.getBandwidth(); bandwidth > 0 {
+				time.Sleep(time.Duration(int64(len(c.data)) * int64(time.Second) / bandwidth))
+			}
+
+			n, err := dst.Write(c.data)
+			atomic.AddInt64(counter, int64(n))
+			if err != nil {
+				failed = true
+				src.Close()
+			}
+		}
+	}()
+
+	buf := make([]byte, bufferSize)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if p.shouldDrop() {
+				src.Close()
+				dst.Close()
+				break
+			}
+
+			if !p.swallow(src, dst) {
+				data := make([]byte, n)
+				copy(data, buf[:n])
+				queue <- chunk{data, time.Now().Add(p.delay())}
+			}
+		}
+
+		if err != nil {
+			if err != io.EOF {
+				dst.Close()
+			}
+			break
+		}
+	}
+
+	close(queue)
+	<-done
+
+	// half close so that the other direction can still drain
+	if tcp, ok := dst.(*net.TCPConn); ok {
+		tcp.CloseWrite()
+	} else {
+		dst.Close()
+	}
+}
+
+func (p *Proxy) shouldDrop() bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.dropRate > 0 && rand.Float64() < p.dropRate
+}
+
+func (p *Proxy) getBandwidth() int64 {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.bandwidth
+}
+
+func (p *Proxy) delay() time.Duration {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if p.jitter <= 0 {
+		return p.latency
+	}
+
+	return p.latency + time.Duration(rand.Int63n(int64(p.jitter)))
+}
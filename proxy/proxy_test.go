@@ -0,0 +1,133 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// startEcho starts a server that echoes every connection.
+func startEcho(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("echo listen err: %s", err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	return listener
+}
+
+func startProxy(t *testing.T, target string) *Proxy {
+	p := New("test", target)
+	if err := p.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	return p
+}
+
+// echo writes data and reads it back within timeout.
+func echo(conn net.Conn, data []byte, timeout time.Duration) ([]byte, error) {
+	if _, err := conn.Write(data); err != nil {
+		return nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	back := make([]byte, len(data))
+	n, err := io.ReadFull(conn, back)
+	return back[:n], err
+}
+
+func Test_Proxy_Blackhole_Heal(t *testing.T) {
+	listener := startEcho(t)
+	defer listener.Close()
+	p := startProxy(t, listener.Addr().String())
+	defer p.Stop()
+
+	conn, err := net.Dial("tcp", p.Addr())
+	if err != nil {
+		t.Fatalf("Test_Proxy_Blackhole_Heal dial err: %s", err)
+	}
+	defer conn.Close()
+
+	if back, err := echo(conn, []byte("a"), 5*time.Second); err != nil || string(back) != "a" {
+		t.Fatalf("Test_Proxy_Blackhole_Heal echo %q err: %v", back, err)
+	}
+
+	p.SetBlackhole(true)
+	back, err := echo(conn, []byte("b"), 300*time.Millisecond)
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Fatalf("Test_Proxy_Blackhole_Heal blackholed echo returns %q %v, expected a timeout", back, err)
+	}
+
+	// the b is lost, the connection must not go on as if nothing was missing
+	p.SetBlackhole(false)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if n, err := conn.Read(make([]byte, 1)); n > 0 || err == nil {
+		t.Fatalf("Test_Proxy_Blackhole_Heal connection that lost data reads %d bytes after heal", n)
+	} else if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		t.Fatalf("Test_Proxy_Blackhole_Heal connection that lost data is still open after heal")
+	}
+
+	fresh, err := net.Dial("tcp", p.Addr())
+	if err != nil {
+		t.Fatalf("Test_Proxy_Blackhole_Heal dial after heal err: %s", err)
+	}
+	defer fresh.Close()
+
+	if back, err := echo(fresh, []byte("c"), 5*time.Second); err != nil || string(back) != "c" {
+		t.Fatalf("Test_Proxy_Blackhole_Heal echo after heal %q err: %v", back, err)
+	}
+}
+
+func Test_Proxy_Bandwidth(t *testing.T) {
+	listener := startEcho(t)
+	defer listener.Close()
+	p := startProxy(t, listener.Addr().String())
+	defer p.Stop()
+
+	conn, err := net.Dial("tcp", p.Addr())
+	if err != nil {
+		t.Fatalf("Test_Proxy_Bandwidth dial err: %s", err)
+	}
+	defer conn.Close()
+
+	bandwidth := int64(4096)
+	p.SetBandwidth(bandwidth)
+	data := bytes.Repeat([]byte("x"), 2048)
+	start := time.Now()
+	back, err := echo(conn, data, 10*time.Second)
+	elapsed := time.Since(start)
+	if err != nil || !bytes.Equal(back, data) {
+		t.Fatalf("Test_Proxy_Bandwidth echo %d bytes err: %v", len(back), err)
+	}
+
+	// both directions are limited
+	if minimum := 2 * time.Duration(len(data)) * time.Second / time.Duration(bandwidth); elapsed < minimum {
+		t.Fatalf("Test_Proxy_Bandwidth echo of %d bytes in %s, expected at least %s", len(data), elapsed, minimum)
+	}
+
+	if sent, received := p.Transferred(); sent != int64(len(data)) || received != int64(len(data)) {
+		t.Fatalf("Test_Proxy_Bandwidth transferred %d and %d bytes, expected %d each way", sent, received, len(data))
+	}
+}
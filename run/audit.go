@@ -0,0 +1,111 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/audit"
+	"github.com/seeleteam/e2e-blackbox/store"
+)
+
+// auditCommand audits the blocks mined since the last audit of the node's
+// chain and prints the findings and the block time statistics.
+func auditCommand(args []string) error {
+	set := flag.NewFlagSet("audit", flag.ContinueOnError)
+	address := set.String("address", ServerAddr, "rpc address of the audited node")
+	chain := set.String("chain", "shard1", "name the checkpoint of the chain is stored under")
+	reset := set.Bool("reset", false, "audit from genesis instead of the stored checkpoint")
+	confirmations := set.Uint64("confirmations", 6, "blocks below the head that are not audited yet")
+	limit := set.Uint64("limit", 0, "maximum number of blocks to audit, 0 for all")
+	difficulty := set.Bool("difficulty", true, "check the pow difficulty of every block")
+	reward := set.Bool("reward", true, "check the miner reward of every block")
+	asJSON := set.Bool("json", false, "print the result as json")
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+
+	auditor := newAuditor(*address)
+	auditor.Confirmations, auditor.Limit = *confirmations, *limit
+	if !*difficulty {
+		auditor.Difficulty = nil
+	}
+
+	if !*reward {
+		auditor.Reward = nil
+	}
+
+	var checkpoint *audit.Checkpoint
+	if data := store.GetCheckpoint(*chain); data != nil && !*reset {
+		checkpoint = &audit.Checkpoint{}
+		if err := json.Unmarshal(data, checkpoint); err != nil {
+			return fmt.Errorf("invalid checkpoint of %s, run with -reset: %s", *chain, err)
+		}
+	}
+
+	result, err := auditor.Run(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(result.Checkpoint)
+	if err != nil {
+		return err
+	}
+	store.SaveCheckpoint(*chain, data)
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "\t")
+		if err = encoder.Encode(result); err != nil {
+			return err
+		}
+	} else {
+		writeAudit(os.Stdout, *chain, result)
+	}
+
+	if len(result.Findings) > 0 {
+		return fmt.Errorf("%d findings", len(result.Findings))
+	}
+
+	return nil
+}
+
+func newAuditor(address string) *audit.Auditor {
+	return &audit.Auditor{
+		Client:      CmdClient,
+		Addr:        address,
+		MaxDrift:    15 * time.Second,
+		Difficulty:  audit.SeeleDifficulty,
+		Reward:      audit.SeeleReward,
+		HistorySize: 30,
+	}
+}
+
+// writeAudit prints the findings and the statistics of this run and the previous ones.
+func writeAudit(w io.Writer, chain string, result *audit.Result) {
+	fmt.Fprintf(w, "audit of %s, checkpoint at block %d %s\n", chain, result.Checkpoint.Height, result.Checkpoint.Hash)
+	for _, f := range result.Findings {
+		fmt.Fprintf(w, "[%s] block %d %s: %s\n", f.Check, f.Height, f.Hash, f.Message)
+	}
+
+	if result.Stats.Blocks == 0 {
+		fmt.Fprintln(w, "no new confirmed blocks")
+	} else {
+		fmt.Fprintln(w, "\nblock times of this run:")
+		result.Stats.WriteHistogram(w)
+	}
+
+	fmt.Fprintln(w, "\nblock times by run:")
+	for _, stats := range result.Checkpoint.History {
+		stats.Write(w)
+	}
+}
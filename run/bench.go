@@ -0,0 +1,252 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/bench"
+	"github.com/seeleteam/e2e-blackbox/node"
+	"github.com/seeleteam/e2e-blackbox/store"
+)
+
+// benchOutput is the json report of a bench run.
+type benchOutput struct {
+	Summary *bench.Summary    `json:"summary"`
+	Slowest []*bench.TxRecord `json:"slowest"`
+	Samples []*bench.Sample   `json:"samples"`
+}
+
+// benchCommand drives a workload against shard 1 and reports the latencies,
+// the achieved tps, the pool backlog and the slowest txs.
+func benchCommand(args []string) error {
+	set := flag.NewFlagSet("bench", flag.ContinueOnError)
+	workload := set.String("workload", "transfer", "load to drive: transfer, contract or crossshard")
+	address := set.String("address", ServerAddr, "rpc address of the node the txs are sent to")
+	rate := set.Float64("rate", 0, "target txs per second, 0 for a closed loop")
+	workers := set.Int("workers", 4, "concurrent submissions")
+	duration := set.Duration("duration", time.Minute, "how long the load is driven")
+	timeout := set.Duration("timeout", 2*time.Minute, "how long txs may take to be mined after the load")
+	sample := set.Duration("sample", 2*time.Second, "period of the pool samples")
+	top := set.Int("top", BenchTopN, "number of slowest txs in the report")
+	format := set.String("format", BenchReportFormat, "report format: text or json")
+	out := set.String("out", "", "report file, default artifacts/bench/<time>.<format>")
+	version := set.String("version", "", "node version the run is stored under, default the --version of the node binary")
+	save := set.Bool("save", true, "store the summary in the result store")
+	th := bench.Thresholds{}
+	set.IntVar(&th.Window, "baseline", BenchBaselineRuns, "number of previous runs the baseline is the median of")
+	set.IntVar(&th.MinRuns, "min-runs", BenchMinRuns, "previous runs needed before regressions are flagged")
+	set.Float64Var(&th.TPSDrop, "tps-drop", BenchTPSDrop, "tps drop from the baseline flagged as a regression, as a ratio")
+	set.Float64Var(&th.LatencyRise, "latency-rise", BenchLatencyRise, "p95 latency rise from the baseline flagged as a regression, as a ratio")
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %s, use text or json", *format)
+	}
+
+	senders, err := bench.Senders(KeyDir, 1)
+	if err != nil {
+		return err
+	}
+
+	b := &bench.Bench{
+		Client:           CmdClient,
+		Addr:             *address,
+		Senders:          senders,
+		Rate:             *rate,
+		Workers:          *workers,
+		Duration:         *duration,
+		InclusionTimeout: *timeout,
+		SampleEvery:      *sample,
+	}
+
+	if b.Workload, err = newWorkload(*workload); err != nil {
+		return err
+	}
+
+	fmt.Printf("bench %s against %s from %d accounts for %s\n", *workload, *address, len(senders), *duration)
+	result, err := b.Run()
+	if err != nil {
+		return err
+	}
+
+	if err = writeBenchResult(*out, *format, *workload, *top, result); err != nil {
+		return err
+	}
+
+	run := &bench.Run{Workload: *workload, Version: *version, Date: result.Start, Summary: result.Summary()}
+	if run.Version == "" {
+		run.Version = nodeVersion()
+	}
+
+	runs, err := loadBenchRuns(*workload)
+	if err != nil {
+		return err
+	}
+
+	baseline := bench.Previous(runs, run.Date, th.Window)
+	regressions := bench.Compare(run, baseline, th)
+	if *save {
+		if err = saveBenchRun(run); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("\nnode %s, %d previous runs in the baseline\n", run.Version, len(baseline))
+	if len(regressions) == 0 {
+		return nil
+	}
+
+	for _, r := range regressions {
+		fmt.Println("regression:", r)
+	}
+
+	return fmt.Errorf("%d regressions of %s from the baseline", len(regressions), *workload)
+}
+
+// writeBenchResult writes the report of the run to the file and stdout.
+func writeBenchResult(path, format, workload string, top int, result *bench.Result) error {
+	if path == "" {
+		ext := "txt"
+		if format == "json" {
+			ext = "json"
+		}
+		path = filepath.Join(ArtifactDir, "bench", fmt.Sprintf("%s-%s.%s", result.Start.Format("20060102-150405"), workload, ext))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := io.MultiWriter(file, os.Stdout)
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		err = encoder.Encode(&benchOutput{Summary: result.Summary(), Slowest: result.Slowest(top), Samples: result.Samples})
+	} else {
+		result.Write(w, top)
+	}
+
+	fmt.Printf("\nreport written to %s\n", path)
+	return err
+}
+
+// nodeVersion returns the --version of the node binary of the topology, or of
+// the client built with the external nodes.
+func nodeVersion() string {
+	binary := CmdClient
+	if topology, err := node.LoadTopology(TopologyFile); err == nil && topology.Binary != "" {
+		binary = topology.Binary
+	}
+
+	output, err := exec.Command(binary, "--version").CombinedOutput()
+	if err != nil {
+		return "unknown"
+	}
+
+	return string(bytes.TrimSpace(output))
+}
+
+// loadBenchRuns returns the stored runs of the workload on every node version,
+// oldest first.
+func loadBenchRuns(workload string) ([]*bench.Run, error) {
+	var versions []string
+	if data := store.GetBenchVersions(workload); data != nil {
+		if err := json.Unmarshal(data, &versions); err != nil {
+			return nil, fmt.Errorf("invalid bench versions of %s: %s", workload, err)
+		}
+	}
+
+	var runs []*bench.Run
+	for _, version := range versions {
+		data := store.GetBench(workload, version)
+		if data == nil {
+			continue
+		}
+
+		var stored []*bench.Run
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return nil, fmt.Errorf("invalid bench runs of %s on %s: %s", workload, version, err)
+		}
+		runs = append(runs, stored...)
+	}
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Date.Before(runs[j].Date) })
+	return runs, nil
+}
+
+// saveBenchRun appends the run to the runs of its workload and node version,
+// keeping the last BenchHistorySize.
+func saveBenchRun(run *bench.Run) error {
+	var versions []string
+	if data := store.GetBenchVersions(run.Workload); data != nil {
+		if err := json.Unmarshal(data, &versions); err != nil {
+			return fmt.Errorf("invalid bench versions of %s: %s", run.Workload, err)
+		}
+	}
+
+	var runs []*bench.Run
+	if data := store.GetBench(run.Workload, run.Version); data != nil {
+		if err := json.Unmarshal(data, &runs); err != nil {
+			return fmt.Errorf("invalid bench runs of %s on %s: %s", run.Workload, run.Version, err)
+		}
+	} else {
+		versions = append(versions, run.Version)
+	}
+
+	runs = append(runs, run)
+	if len(runs) > BenchHistorySize {
+		runs = runs[len(runs)-BenchHistorySize:]
+	}
+
+	data, err := json.Marshal(runs)
+	if err != nil {
+		return err
+	}
+	store.SaveBench(run.Workload, run.Version, data)
+
+	if data, err = json.Marshal(versions); err != nil {
+		return err
+	}
+	store.SaveBenchVersions(run.Workload, data)
+	return nil
+}
+
+// newWorkload returns the workload of the name, sent from shard 1 accounts.
+func newWorkload(name string) (*bench.Workload, error) {
+	switch name {
+	case "transfer":
+		return bench.Transfer(benchReceiver), nil
+	case "contract":
+		dir := filepath.Join("testcase", "contract", "simplestorage")
+		return bench.ContractCall(filepath.Join(dir, "SimpleStorage.bin"), filepath.Join(dir, "SimpleStorage.abi"), "set", "7"), nil
+	case "crossshard":
+		receivers, err := bench.Senders(KeyDir, 2)
+		if err != nil {
+			return nil, err
+		}
+		return bench.CrossShard(receivers[0].Account), nil
+	}
+
+	return nil, fmt.Errorf("unknown workload %s, use transfer, contract or crossshard", name)
+}
@@ -0,0 +1,77 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/seeleteam/e2e-blackbox/doctor"
+	"github.com/seeleteam/e2e-blackbox/node"
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+// doctorCommand checks the environment and prints how to fix what is wrong.
+func doctorCommand(args []string) error {
+	set := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	minBalance := set.Int64("balance", MinBalance, "minimum balance of every keyfile account")
+	minFree := set.Uint64("disk", MinFreeMB, "minimum free disk space in MB")
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+
+	d := newDoctor()
+	d.MinBalance, d.MinFreeMB = *minBalance, *minFree
+	if !doctor.Report(os.Stdout, d.Run()) {
+		return errors.New("the environment is not ready for a run")
+	}
+
+	return nil
+}
+
+// preflight runs the doctor before the daily run, the report is returned so
+// it can be mailed when the run is refused.
+func preflight() (string, bool) {
+	var report bytes.Buffer
+	ok := doctor.Report(io.MultiWriter(&report, os.Stdout), newDoctor().Run())
+	return report.String(), ok
+}
+
+// newDoctor checks the nodes of the topology, or the default nodes if there is none.
+func newDoctor() *doctor.Doctor {
+	d := &doctor.Doctor{
+		Client:     CmdClient,
+		Light:      CmdLight,
+		CurShard:   uint(common.CurShard),
+		KeyDir:     KeyDir,
+		Password:   KeyPassword,
+		MinBalance: MinBalance,
+		Dir:        ".",
+		MinFreeMB:  MinFreeMB,
+	}
+
+	if topology, err := node.LoadTopology(TopologyFile); err == nil {
+		d.Node = topology.Binary
+		for _, n := range topology.Nodes {
+			// light nodes do not mine, the differential check covers them
+			if n.Light {
+				continue
+			}
+			d.Targets = append(d.Targets, doctor.Target{Name: n.Name, Addr: n.RPCAddr, Shard: n.Shard, Managed: n.Managed()})
+		}
+	} else {
+		if !os.IsNotExist(err) {
+			fmt.Println("load topology err:", err)
+		}
+		d.Targets = []doctor.Target{{Name: "shard1", Addr: ServerAddr, Shard: 1}, {Name: "shard2", Addr: ServertwoAddr, Shard: 2}}
+	}
+
+	return d
+}
@@ -0,0 +1,96 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/seeleteam/e2e-blackbox/fixture"
+)
+
+const fixtureUsage = `usage:
+  run fixture build  [-spec spec.json | -shard 1] -config node.json -accounts accounts.json
+  run fixture record [-spec spec.json | -shard 1] [-address 127.0.0.1:8027] [-heights 1] [-out config/fixture.json]`
+
+// fixtureCommand builds a deterministic genesis into a node config, or records
+// the known blocks of a node started from it into the fixture file.
+func fixtureCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(fixtureUsage)
+	}
+
+	set := flag.NewFlagSet("fixture "+args[0], flag.ContinueOnError)
+	specFile := set.String("spec", "", "genesis spec file, the committed keyfile accounts are funded if not set")
+	shard := set.Uint("shard", 1, "shard of the default spec")
+	config := set.String("config", "", "node config to write the genesis section into")
+	accounts := set.String("accounts", "", "genesis accounts file to write")
+	address := set.String("address", ServerAddr, "rpc address of a node started from the spec")
+	heights := set.String("heights", "1", "comma separated block heights to record besides genesis")
+	out := set.String("out", FixtureFile, "fixture file to write")
+	if err := set.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	spec := fixture.DefaultSpec(*shard)
+	if *specFile != "" {
+		var err error
+		if spec, err = fixture.LoadSpec(*specFile); err != nil {
+			return err
+		}
+	}
+
+	switch args[0] {
+	case "build":
+		if *config == "" || *accounts == "" {
+			return errors.New(fixtureUsage)
+		}
+
+		otherShard, err := spec.Validate(CmdClient)
+		if err != nil {
+			return err
+		}
+
+		if len(otherShard) > 0 {
+			fmt.Printf("accounts not in shard %d get no genesis balance: %s\n", spec.Shard, strings.Join(otherShard, ", "))
+		}
+
+		if err = spec.Apply(*config, *accounts); err != nil {
+			return err
+		}
+
+		fmt.Printf("genesis written to %s and %s, start the node and run `run fixture record`\n", *config, *accounts)
+		return nil
+
+	case "record":
+		var recordHeights []uint64
+		for _, str := range strings.Split(*heights, ",") {
+			height, err := strconv.ParseUint(strings.TrimSpace(str), 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid height %q", str)
+			}
+			recordHeights = append(recordHeights, height)
+		}
+
+		f, err := spec.Record(CmdClient, *address, recordHeights)
+		if err != nil {
+			return err
+		}
+
+		if err = fixture.Save(*out, f); err != nil {
+			return err
+		}
+
+		fmt.Printf("fixture with genesis %s written to %s\n", f.GenesisHash, *out)
+		return nil
+
+	default:
+		return errors.New(fixtureUsage)
+	}
+}
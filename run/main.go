@@ -6,15 +6,23 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/mail"
 	"net/smtp"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/scorredoira/email"
+	"github.com/seeleteam/e2e-blackbox/artifact"
+	"github.com/seeleteam/e2e-blackbox/node"
 	"github.com/seeleteam/e2e-blackbox/store"
 )
 
@@ -41,11 +49,79 @@ const (
 	StartMin  = 00
 	StartSec  = 00
 
-	BenchTopN         = "15"
-	BenchReportFormat = "pdf"
+	// defaults of run bench, the report format is text or json
+	BenchTopN         = 15
+	BenchReportFormat = "text"
+	// a bench run regresses when its tps drops or its p95 latencies rise by
+	// more than the ratio from the median of the previous runs
+	BenchBaselineRuns = 7
+	BenchMinRuns      = 3
+	BenchTPSDrop      = 0.2
+	BenchLatencyRise  = 0.2
+	BenchTrendRuns    = 30
+	BenchHistorySize  = 200
+	// MetricsInterval is the seconds between two samples of the nodes during the run
+	MetricsInterval = 5
+	// the tests that stop or partition the nodes, they run after all other
+	// tests with the nodes to themselves
+	ExclusivePackages = "./testcase/chaos,./testcase/crossshard,./testcase/miner,./testcase/network"
+	ExclusiveTests    = "^(Test_Chaos_Scenarios|Test_CrossShard_Debt_Target_Down|Test_Miner_RewardsAndStop|Test_Miner_HashrateScales|Test_Miner_CoinbaseOtherShard|Test_Fork_Partition_Converge)$"
+	// benchReceiver is paid by the transfer workload
+	benchReceiver = "0xa00d22dc3624d4696eff8d1641b442f79c3379b1"
+
+	// paths relative to the repository root, where the runner is started
+	CmdClient     = "bin/client"
+	CmdLight      = "bin/light"
+	KeyDir        = "config/keyfile"
+	TopologyFile  = "config/topology.json"
+	ArtifactDir   = "artifacts"
+	LogTailLines  = 500
+	ServerAddr    = "127.0.0.1:8027"
+	ServertwoAddr = "127.0.0.1:8028"
+	FixtureFile   = "config/fixture.json"
+
+	// preflight thresholds, KeyPassword is the password of the committed keyfiles
+	KeyPassword = "123"
+	MinBalance  = 100000000
+	MinFreeMB   = 2048
+
+	// CollectWorkers bounds the failed tests whose nodes are captured at once
+	CollectWorkers = 4
 )
 
+// testEvent is a line of go test -json output.
+type testEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Output  string
+}
+
+// commands are the subcommands of the runner, without one the daily run starts.
+var commands = map[string]func(args []string) error{
+	"audit":   auditCommand,
+	"bench":   benchCommand,
+	"doctor":  doctorCommand,
+	"fixture": fixtureCommand,
+	"watch":   watchCommand,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		command, ok := commands[os.Args[1]]
+		if !ok {
+			fmt.Printf("unknown command %s, available commands: %s\n", os.Args[1], strings.Join(commandNames(), ", "))
+			os.Exit(2)
+		}
+
+		if err := command(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	now := time.Now()
 	weekday := now.Weekday()
 	if weekday != time.Saturday && weekday != time.Sunday {
@@ -74,7 +150,16 @@ func sendEmail(message string, attachFile []string) {
 }
 
 func do(today string) {
-	coverResult, specified := Run()
+	if report, ok := preflight(); !ok {
+		sendEmail("😵 the environment is not ready, the suite did not run\n\n"+report, nil)
+		return
+	}
+
+	collector, spans := newCollector(today), newSpanRecorder()
+	scraper := newScraper(collector)
+	scraper.Start()
+	coverResult, specified, artifacts := Run(collector, spans)
+	samples := &metricsRun{Samples: scraper.Stop(), Tests: spans.spans}
 	coverbyte, err := json.Marshal(specified)
 	if err != nil {
 		fmt.Println("Marshal specified FAIL")
@@ -94,17 +179,95 @@ func do(today string) {
 	// message += PrintSpecifiedPkg(yesterday, specified)
 	message += "\n\n============= Go cover seele cmd commands completed. ===============\n" + coverResult
 
+	benchMessage, benchFile, err := benchReport(filepath.Join(ArtifactDir, today))
+	if err != nil {
+		fmt.Println("bench report err:", err)
+	} else if benchFile != "" {
+		message += "\n\n============= Benchmarks ===============\n" + benchMessage
+		attachFile = append(attachFile, benchFile)
+	}
+
+	if samplesbyte, err := json.Marshal(samples); err == nil {
+		store.SaveMetrics(today, samplesbyte)
+	}
+
+	metricsMessage, metricsFile, err := metricsReport(filepath.Join(ArtifactDir, today), samples)
+	if err != nil {
+		fmt.Println("metrics report err:", err)
+	} else if metricsFile != "" {
+		message += "\n\n============= Node metrics ===============\n" + metricsMessage
+		attachFile = append(attachFile, metricsFile)
+	}
+
+	// the archives hold heap dumps and may exceed the mail size limit, they
+	// stay on the runner host and only their paths are mailed
+	if len(artifacts) > 0 {
+		message += "\n\n============= Failure artifacts ===============\n"
+		for _, test := range sortedKeys(artifacts) {
+			message += test + " --> " + artifacts[test] + "\n"
+		}
+	}
+
 	sendEmail(message, attachFile)
 }
 
-// Run go
-func Run() (all string, specified map[string]string) {
+// newCollector captures the nodes of the topology, or the default nodes if there is none.
+func newCollector(today string) *artifact.Collector {
+	collector := &artifact.Collector{
+		Dir:     filepath.Join(ArtifactDir, today),
+		Client:  CmdClient,
+		LogTail: LogTailLines,
+	}
+
+	if topology, err := node.LoadTopology(TopologyFile); err == nil {
+		for _, n := range topology.Nodes {
+			collector.Targets = append(collector.Targets, artifact.Target{Name: n.Name, Addr: n.RPCAddr, LogFile: n.LogFile})
+		}
+	} else {
+		if !os.IsNotExist(err) {
+			fmt.Println("load topology err:", err)
+		}
+		collector.Targets = []artifact.Target{{Name: "shard1", Addr: ServerAddr}, {Name: "shard2", Addr: ServertwoAddr}}
+	}
+
+	return collector
+}
+
+// Run go, spans records the time of every test if not nil. The managed nodes
+// of the topology are started once for all packages, the tests attach to them.
+// The tests that stop or partition nodes run afterwards one package at a time,
+// they own the nodes then.
+func Run(collector *artifact.Collector, spans *spanRecorder) (all string, specified map[string]string, artifacts map[string]string) {
 	specified = make(map[string]string)
+	artifacts = make(map[string]string)
+
+	topology, err := node.LoadTopology(TopologyFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Sprintf("topology FAIL: %s", err), nil, artifacts
+	}
+
+	if topology != nil {
+		if err = topology.StartAll(); err != nil {
+			topology.StopAll()
+			return fmt.Sprintf("topology FAIL: %s", err), nil, artifacts
+		}
+	}
 
 	// coverbyte, err := exec.Command("go", "test", "./...", "-v", "-timeout", "3h", "-coverprofile="+CoverFileName).CombinedOutput()
-	coverbyte, err := exec.Command("go", "test", "./...", "-v", "-timeout", "3h").CombinedOutput()
+	coverbyte, err := goTest(collector, spans, artifacts, nil, "./...", "-v", "-timeout", "3h", "-json")
+	if topology != nil {
+		topology.StopAll()
+	}
+
+	exclusiveArgs := append([]string{"-p", "1", "-run", ExclusiveTests, "-v", "-timeout", "3h", "-json"}, strings.Split(ExclusivePackages, ",")...)
+	exclusivebyte, exclusiveErr := goTest(collector, spans, artifacts, []string{node.ExclusiveEnv + "=1"}, exclusiveArgs...)
+	coverbyte = append(coverbyte, exclusivebyte...)
+	if err == nil {
+		err = exclusiveErr
+	}
+
 	if err != nil {
-		return fmt.Sprintf("cover FAIL: %s %s", err, string(coverbyte)), nil
+		return fmt.Sprintf("cover FAIL: %s %s", err, string(coverbyte)), nil, artifacts
 	}
 
 	// remove useless output
@@ -129,7 +292,97 @@ func Run() (all string, specified map[string]string) {
 	// 	return fmt.Sprintf("tool cover FAIL: %s", err), nil
 	// }
 
-	return all, specified
+	return all, specified, artifacts
+}
+
+// goTest runs go test -json with the args and the extra environment and
+// returns its output. The node state is captured into artifacts as soon as a
+// test fails, in the background so that the output of the tests that keep
+// running is still read. It returns once all captures are done.
+func goTest(collector *artifact.Collector, spans *spanRecorder, artifacts map[string]string, env []string, args ...string) ([]byte, error) {
+	cmd := exec.Command("go", append([]string{"test"}, args...)...)
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	var output bytes.Buffer
+	var collecting sync.WaitGroup
+	var artifactsMutex sync.Mutex
+	workers := make(chan struct{}, CollectWorkers)
+	collected := make(map[string]bool)
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var event testEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			output.Write(scanner.Bytes())
+			output.WriteByte('\n')
+			continue
+		}
+
+		output.WriteString(event.Output)
+		if spans != nil {
+			spans.event(event)
+		}
+
+		if event.Action != "fail" || event.Test == "" {
+			continue
+		}
+
+		test := event.Package + "." + strings.Split(event.Test, "/")[0]
+		if collected[test] {
+			continue
+		}
+		collected[test] = true
+
+		collecting.Add(1)
+		go func(pkg, name, test string) {
+			defer collecting.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+
+			path, err := collector.Collect(pkg, name)
+			if err != nil {
+				fmt.Printf("failed to collect artifacts of %s: %s\n", test, err)
+				return
+			}
+
+			artifactsMutex.Lock()
+			artifacts[test] = path
+			artifactsMutex.Unlock()
+		}(event.Package, event.Test, test)
+	}
+
+	err = cmd.Wait()
+	collecting.Wait()
+	result := append(output.Bytes(), stderr.Bytes()...)
+	return result, err
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // PrintSpecifiedPkg print pkg
@@ -0,0 +1,187 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/artifact"
+	"github.com/seeleteam/e2e-blackbox/metrics"
+)
+
+var metricsTemplate = template.Must(template.New("metrics").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table>
+<tr><th>node</th><th>samples</th><th>height</th><th>max pool</th><th>min peers</th><th>max rss MB</th></tr>
+{{range .Nodes}}<tr><td>{{.Name}}</td><td>{{.Samples}}</td><td>{{.First}} → {{.Last}}</td><td>{{.MaxPool}}</td><td>{{.MinPeers}}</td><td>{{.MaxRSS}}</td></tr>
+{{end}}
+</table>
+<p>failed tests are shaded red, hover them for the name</p>
+{{range .Charts}}<div>{{.}}</div>{{end}}
+</body>
+</html>
+`))
+
+// metricsRun is the stored and archived samples of a daily run.
+type metricsRun struct {
+	Samples []metrics.Sample `json:"samples"`
+	Tests   []metrics.Span   `json:"tests"`
+}
+
+// nodeSummary is a row of the metrics report.
+type nodeSummary struct {
+	Name     string
+	Samples  int
+	First    int64
+	Last     int64
+	MaxPool  int64
+	MinPeers int64
+	MaxRSS   int64
+}
+
+// spanRecorder records when every test of the run started and ended.
+type spanRecorder struct {
+	running map[string]time.Time
+	spans   []metrics.Span
+}
+
+func newSpanRecorder() *spanRecorder {
+	return &spanRecorder{running: make(map[string]time.Time)}
+}
+
+// event takes a go test -json event, subtests are part of their test.
+func (r *spanRecorder) event(event testEvent) {
+	if event.Test == "" || strings.Contains(event.Test, "/") {
+		return
+	}
+
+	at := event.Time
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	name := event.Package + "." + event.Test
+	switch event.Action {
+	case "run":
+		r.running[name] = at
+	case "pass", "fail", "skip":
+		start, ok := r.running[name]
+		if !ok {
+			return
+		}
+		delete(r.running, name)
+		r.spans = append(r.spans, metrics.Span{Name: name, Start: start, End: at, Failed: event.Action == "fail"})
+	}
+}
+
+// newScraper samples the nodes captured by the collector.
+func newScraper(collector *artifact.Collector) *metrics.Scraper {
+	scraper := &metrics.Scraper{Client: CmdClient, Interval: MetricsInterval * time.Second}
+	for _, target := range collector.Targets {
+		scraper.Targets = append(scraper.Targets, metrics.Target{Name: target.Name, Addr: target.Addr})
+	}
+
+	return scraper
+}
+
+// metricsReport writes the samples of the run with the test spans as json and
+// as an html report with a chart per value. It returns the text for the mail
+// and the report file, empty if nothing was sampled.
+func metricsReport(dir string, run *metricsRun) (string, string, error) {
+	if len(run.Samples) == 0 {
+		return "", "", nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", err
+	}
+
+	data, err := json.Marshal(run)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(dir, "metrics.json"), data, 0644); err != nil {
+		return "", "", err
+	}
+
+	byNode := make(map[string]*nodeSummary)
+	for _, s := range run.Samples {
+		summary, ok := byNode[s.Node]
+		if !ok {
+			summary = &nodeSummary{Name: s.Node, First: -1, Last: -1, MaxPool: -1, MinPeers: -1, MaxRSS: -1}
+			byNode[s.Node] = summary
+		}
+
+		summary.Samples++
+		if s.Height >= 0 {
+			if summary.First < 0 {
+				summary.First = s.Height
+			}
+			summary.Last = s.Height
+		}
+		if s.Pool > summary.MaxPool {
+			summary.MaxPool = s.Pool
+		}
+		if s.RSS >= 0 && s.RSS>>20 > summary.MaxRSS {
+			summary.MaxRSS = s.RSS >> 20
+		}
+	}
+
+	var message strings.Builder
+	var nodes []*nodeSummary
+	for _, summary := range byNode {
+		summary.MinPeers = metrics.MinPeers(run.Samples, summary.Name, time.Time{})
+		nodes = append(nodes, summary)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+
+	for _, n := range nodes {
+		fmt.Fprintf(&message, "%s: height %d → %d, max pool %d, min peers %d, max rss %d MB\n", n.Name, n.First, n.Last, n.MaxPool, n.MinPeers, n.MaxRSS)
+	}
+
+	var charts []template.HTML
+	for _, field := range metrics.Fields() {
+		charts = append(charts, metrics.SeriesSVG(field, run.Samples, run.Tests))
+	}
+
+	path := filepath.Join(dir, "metrics.html")
+	file, err := os.Create(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	report := struct {
+		Title  string
+		Nodes  []*nodeSummary
+		Charts []template.HTML
+	}{"Node metrics during the run", nodes, charts}
+	if err = metricsTemplate.Execute(file, report); err != nil {
+		return "", "", err
+	}
+
+	return message.String(), path, nil
+}
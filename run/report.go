@@ -0,0 +1,156 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/bench"
+	"github.com/seeleteam/e2e-blackbox/store"
+)
+
+// benchWorkloads are the workloads of the daily report
+var benchWorkloads = []string{"transfer", "contract", "crossshard"}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+.regression { color: #d33; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Workloads}}
+<h2>{{.Name}}</h2>
+<p>last run {{.Last.Date.Format "2006-01-02 15:04"}} on node {{.Last.Version}}, baseline of {{.Baseline}} previous runs</p>
+<table>
+<tr><th>run</th><th>tps</th><th>submit tps</th><th>inclusion p95</th><th>submission p95</th><th>included</th><th>rejected</th></tr>
+{{with .Last.Summary}}<tr><td>last</td><td>{{printf "%.2f" .TPS}}</td><td>{{printf "%.2f" .SubmitTPS}}</td><td>{{.Inclusion.P95}}</td><td>{{.Submit.P95}}</td><td>{{.Included}}</td><td>{{.Rejected}}</td></tr>{{end}}
+</table>
+{{range .Regressions}}<p class="regression">regression: {{.}}</p>{{else}}<p>no regression</p>{{end}}
+{{range .Charts}}<div>{{.}}</div>{{end}}
+{{else}}
+<p>no benchmark runs stored</p>
+{{end}}
+</body>
+</html>
+`))
+
+type workloadReport struct {
+	Name        string
+	Last        *bench.Run
+	Baseline    int
+	Regressions []*bench.Regression
+	Charts      []template.HTML
+}
+
+// benchReport compares the last run of every workload to its baseline and
+// writes the html report with the trend charts. A workload whose last run was
+// in a previous daily report is left out. It returns the text for the mail
+// and the report file, empty if there is no new run.
+func benchReport(dir string) (string, string, error) {
+	th := bench.Thresholds{Window: BenchBaselineRuns, MinRuns: BenchMinRuns, TPSDrop: BenchTPSDrop, LatencyRise: BenchLatencyRise}
+
+	var reports []*workloadReport
+	var message strings.Builder
+	for _, workload := range benchWorkloads {
+		runs, err := loadBenchRuns(workload)
+		if err != nil {
+			return "", "", err
+		}
+
+		if len(runs) == 0 {
+			continue
+		}
+
+		last := runs[len(runs)-1]
+		if reported, err := benchReported(workload); err != nil {
+			return "", "", err
+		} else if !last.Date.After(reported) {
+			continue
+		}
+		baseline := bench.Previous(runs, last.Date, th.Window)
+		report := &workloadReport{Name: workload, Last: last, Baseline: len(baseline), Regressions: bench.Compare(last, baseline, th)}
+		regressed := make(map[string]bool)
+		for _, r := range report.Regressions {
+			regressed[r.Metric] = true
+		}
+
+		trend := runs
+		if len(trend) > BenchTrendRuns {
+			trend = trend[len(trend)-BenchTrendRuns:]
+		}
+
+		for _, metric := range bench.Metrics() {
+			report.Charts = append(report.Charts, bench.TrendSVG(metric, trend, regressed[metric]))
+		}
+		reports = append(reports, report)
+
+		fmt.Fprintf(&message, "%s on %s: %.2f tps, inclusion p95 %s", workload, last.Version, last.Summary.TPS, last.Summary.Inclusion.P95)
+		for _, r := range report.Regressions {
+			fmt.Fprintf(&message, "\n    😦 regression %s", r)
+		}
+		message.WriteString("\n")
+	}
+
+	if len(reports) == 0 {
+		return "", "", nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", err
+	}
+
+	path := filepath.Join(dir, "report.html")
+	file, err := os.Create(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	data := struct {
+		Title     string
+		Workloads []*workloadReport
+	}{Subject, reports}
+	if err = reportTemplate.Execute(file, data); err != nil {
+		return "", "", err
+	}
+
+	for _, report := range reports {
+		date, err := json.Marshal(report.Last.Date)
+		if err != nil {
+			return "", "", err
+		}
+		store.SaveBenchReported(report.Name, date)
+	}
+
+	return message.String(), path, nil
+}
+
+// benchReported returns the date of the last run of the workload in a daily
+// report, zero if none was reported yet.
+func benchReported(workload string) (time.Time, error) {
+	var date time.Time
+	if data := store.GetBenchReported(workload); data != nil {
+		if err := json.Unmarshal(data, &date); err != nil {
+			return date, fmt.Errorf("invalid reported bench date of %s: %s", workload, err)
+		}
+	}
+
+	return date, nil
+}
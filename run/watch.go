@@ -0,0 +1,72 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/forkwatch"
+	"github.com/seeleteam/e2e-blackbox/node"
+)
+
+// watchCommand observes the nodes for forks and reorgs until the duration
+// elapses or it is interrupted, then prints what it saw.
+func watchCommand(args []string) error {
+	set := flag.NewFlagSet("watch", flag.ContinueOnError)
+	duration := set.Duration("duration", 0, "how long to observe, 0 until interrupted")
+	period := set.Duration("period", 2*time.Second, "pause between two polls")
+	window := set.Uint64("window", 30, "heights below the head read on every poll")
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+
+	monitor := &forkwatch.Monitor{
+		Client: CmdClient,
+		Window: *window,
+		Period: *period,
+		Events: func(format string, args ...interface{}) {
+			fmt.Printf(time.Now().Format("15:04:05 ")+format+"\n", args...)
+		},
+	}
+
+	if topology, err := node.LoadTopology(TopologyFile); err == nil {
+		for _, n := range topology.Nodes {
+			if !n.Light {
+				monitor.Targets = append(monitor.Targets, forkwatch.Target{Name: n.Name, Addr: n.RPCAddr, Shard: n.Shard})
+			}
+		}
+	} else {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("load topology err: %s", err)
+		}
+		monitor.Targets = []forkwatch.Target{{Name: "shard1", Addr: ServerAddr, Shard: 1}, {Name: "shard2", Addr: ServertwoAddr, Shard: 2}}
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if *duration > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), *duration)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	monitor.Watch(ctx)
+	fmt.Print(monitor.Report())
+	return nil
+}
@@ -0,0 +1,368 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/node"
+	"github.com/seeleteam/e2e-blackbox/proxy"
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+// action executes one step with its arguments.
+type action func(ctx context.Context, r *Runner, args map[string]string) error
+
+// actions are the step actions a scenario file may use.
+var actions = map[string]action{
+	"sleep":     sleepAction,
+	"sendtx":    sendTxAction,
+	"start":     nodeAction((*node.Node).Start),
+	"stop":      nodeAction((*node.Node).Stop),
+	"kill":      nodeAction((*node.Node).Kill),
+	"restart":   nodeAction((*node.Node).Restart),
+	"partition": partitionAction,
+	"isolate":   isolateAction,
+	"fault":     faultAction,
+	"heal":      healAction,
+	"assert":    assertAction,
+}
+
+var errNoNetwork = errors.New("no proxy network, the topology is not interposed")
+
+// isNodeArg returns whether the argument of the action names nodes.
+func isNodeArg(action, key string) bool {
+	switch action {
+	case "partition":
+		return key == "groups"
+	case "fault":
+		return key == "from" || key == "to"
+	default:
+		return false
+	}
+}
+
+// splitGroups splits "a,b|c" into all of its names.
+func splitGroups(value string) []string {
+	var names []string
+	for _, group := range parseGroups(value) {
+		names = append(names, group...)
+	}
+
+	return names
+}
+
+// parseGroups parses "a,b|c" into the groups [a b] and [c].
+func parseGroups(value string) [][]string {
+	var groups [][]string
+	for _, group := range strings.Split(value, "|") {
+		var names []string
+		for _, name := range strings.Split(group, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		groups = append(groups, names)
+	}
+
+	return groups
+}
+
+func durationArg(args map[string]string, key string) (time.Duration, error) {
+	value, ok := args[key]
+	if !ok {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %s", key, value, err)
+	}
+
+	return d, nil
+}
+
+func intArg(args map[string]string, key string, defaultValue int64) (int64, error) {
+	value, ok := args[key]
+	if !ok {
+		return defaultValue, nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %s", key, value, err)
+	}
+
+	return n, nil
+}
+
+// holdThenHeal keeps a fault for the duration argument, if any, and then heals it.
+func holdThenHeal(ctx context.Context, args map[string]string, heal func()) error {
+	d, err := durationArg(args, "duration")
+	if err != nil || d == 0 {
+		return err
+	}
+
+	defer heal()
+	return sleep(ctx, d)
+}
+
+func sleepAction(ctx context.Context, r *Runner, args map[string]string) error {
+	d, err := durationArg(args, "duration")
+	if err != nil {
+		return err
+	}
+
+	return sleep(ctx, d)
+}
+
+func sendTxAction(ctx context.Context, r *Runner, args map[string]string) error {
+	from, ok := accounts[args["from"]]
+	if !ok {
+		return fmt.Errorf("unknown account %q", args["from"])
+	}
+
+	to, ok := accounts[args["to"]]
+	if !ok {
+		return fmt.Errorf("unknown account %q", args["to"])
+	}
+
+	amount, err := intArg(args, "amount", 1)
+	if err != nil {
+		return err
+	}
+
+	return r.ledger.send(ctx, r, args["from"], from, args["to"], to, amount)
+}
+
+func nodeAction(do func(*node.Node) error) action {
+	return func(ctx context.Context, r *Runner, args map[string]string) error {
+		if r.Topology == nil {
+			return errors.New("no topology is loaded")
+		}
+
+		n := r.Topology.Node(args["node"])
+		if n == nil {
+			return fmt.Errorf("unknown node %q", args["node"])
+		}
+
+		return do(n)
+	}
+}
+
+func partitionAction(ctx context.Context, r *Runner, args map[string]string) error {
+	if r.Network == nil {
+		return errNoNetwork
+	}
+
+	groups := parseGroups(args["groups"])
+	if len(groups) < 2 {
+		return fmt.Errorf("partition needs at least two groups, got %q", args["groups"])
+	}
+
+	r.Network.Partition(groups...)
+	return holdThenHeal(ctx, args, r.Network.Heal)
+}
+
+func isolateAction(ctx context.Context, r *Runner, args map[string]string) error {
+	if r.Network == nil {
+		return errNoNetwork
+	}
+
+	r.Network.Isolate(args["node"])
+	return holdThenHeal(ctx, args, func() {
+		for _, p := range r.Network.Links(args["node"]) {
+			p.Reset()
+		}
+	})
+}
+
+func healAction(ctx context.Context, r *Runner, args map[string]string) error {
+	if r.Network == nil {
+		return errNoNetwork
+	}
+
+	r.Network.Heal()
+	return nil
+}
+
+// faultAction applies latency, jitter, drop, bandwidth or blackhole to the
+// link from one endpoint to another, or to all links of a node.
+func faultAction(ctx context.Context, r *Runner, args map[string]string) error {
+	if r.Network == nil {
+		return errNoNetwork
+	}
+
+	var links []*proxy.Proxy
+	if name, ok := args["node"]; ok {
+		links = r.Network.Links(name)
+	} else if link := r.Network.Link(args["from"], args["to"]); link != nil {
+		links = append(links, link)
+	}
+
+	if len(links) == 0 {
+		return fmt.Errorf("no link matches %v", args)
+	}
+
+	latency, err := durationArg(args, "latency")
+	if err != nil {
+		return err
+	}
+
+	jitter, err := durationArg(args, "jitter")
+	if err != nil {
+		return err
+	}
+
+	bandwidth, err := intArg(args, "bandwidth", 0)
+	if err != nil {
+		return err
+	}
+
+	drop := 0.0
+	if value, ok := args["drop"]; ok {
+		if drop, err = strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("invalid drop %q: %s", value, err)
+		}
+	}
+
+	for _, link := range links {
+		link.SetLatency(latency, jitter)
+		link.SetBandwidth(bandwidth)
+		link.SetDropRate(drop)
+		link.SetBlackhole(args["blackhole"] == "true")
+	}
+
+	return holdThenHeal(ctx, args, func() {
+		for _, link := range links {
+			link.Reset()
+		}
+	})
+}
+
+// assertAction checks a condition, see the check functions for the arguments.
+func assertAction(ctx context.Context, r *Runner, args map[string]string) error {
+	switch args["check"] {
+	case "ledger":
+		return r.ledger.checkBalances(r)
+	case "included":
+		return r.ledger.checkIncluded(r)
+	case "converge":
+		return checkConverge(r, args)
+	case "balance":
+		return checkBalance(r, args)
+	case "height":
+		return checkHeight(r, args)
+	default:
+		return fmt.Errorf("unknown check %q", args["check"])
+	}
+}
+
+// checkConverge asserts that all running nodes of the shard have the same
+// block at the lowest head height among them.
+func checkConverge(r *Runner, args map[string]string) error {
+	if r.Topology == nil {
+		return errors.New("no topology is loaded")
+	}
+
+	shard, err := intArg(args, "shard", 1)
+	if err != nil {
+		return err
+	}
+
+	var addrs []string
+	for _, n := range r.Topology.Shard(uint(shard)) {
+		if !n.Managed() || n.Running() {
+			addrs = append(addrs, n.Addr())
+		}
+	}
+
+	if len(addrs) < 2 {
+		return fmt.Errorf("shard %d has less than two running nodes", shard)
+	}
+
+	height := int64(-1)
+	for _, addr := range addrs {
+		head, err := common.GetBlock(r.t, common.CmdClient, -1, addr)
+		if err != nil {
+			return fmt.Errorf("node %s getblock err: %s", addr, err)
+		}
+
+		if height < 0 || int64(head.Header.Height) < height {
+			height = int64(head.Header.Height)
+		}
+	}
+
+	hash := ""
+	for _, addr := range addrs {
+		block, err := common.GetBlock(r.t, common.CmdClient, height, addr)
+		if err != nil {
+			return fmt.Errorf("node %s getblock %d err: %s", addr, height, err)
+		}
+
+		if hash == "" {
+			hash = block.Hash
+		} else if block.Hash != hash {
+			return fmt.Errorf("shard %d diverges at height %d: %s != %s", shard, height, block.Hash, hash)
+		}
+	}
+
+	return nil
+}
+
+// checkBalance compares the balance of an account with a value, op is one of eq, ge and le.
+func checkBalance(r *Runner, args map[string]string) error {
+	acc, ok := accounts[args["account"]]
+	if !ok {
+		return fmt.Errorf("unknown account %q", args["account"])
+	}
+
+	value, err := intArg(args, "value", 0)
+	if err != nil {
+		return err
+	}
+
+	balance, err := common.GetBalance(r.t, common.CmdClient, acc.address, r.server(acc.shard))
+	if err != nil {
+		return err
+	}
+
+	op := args["op"]
+	if (op == "eq" || op == "") && balance != value || op == "ge" && balance < value || op == "le" && balance > value {
+		return fmt.Errorf("balance of %s is %d, want %s %d", args["account"], balance, op, value)
+	}
+
+	return nil
+}
+
+// checkHeight asserts that the shard's head is at least at the min height.
+func checkHeight(r *Runner, args map[string]string) error {
+	shard, err := intArg(args, "shard", 1)
+	if err != nil {
+		return err
+	}
+
+	min, err := intArg(args, "min", 0)
+	if err != nil {
+		return err
+	}
+
+	head, err := common.GetBlock(r.t, common.CmdClient, -1, r.server(uint(shard)))
+	if err != nil {
+		return err
+	}
+
+	if int64(head.Header.Height) < min {
+		return fmt.Errorf("shard %d is at height %d, want at least %d", shard, head.Header.Height, min)
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+const (
+	sendRetries  = 3
+	sendInterval = 2 * time.Second
+)
+
+type account struct {
+	keyFile string
+	address string
+	shard   uint
+}
+
+// accounts are the names scenario files use for the committed keyfiles.
+var accounts = map[string]account{
+	"shard1_1": {common.KeyFileShard1_1, common.AccountShard1_1, 1},
+	"shard1_2": {common.KeyFileShard1_2, common.AccountShard1_2, 1},
+	"shard1_3": {common.KeyFileShard1_3, common.AccountShard1_3, 1},
+	"shard1_4": {common.KeyFileShard1_4, common.AccountShard1_4, 1},
+	"shard1_5": {common.KeyFileShard1_5, common.AccountShard1_5, 1},
+	"shard2_1": {common.KeyFileShard2_1, common.AccountShard2_1, 2},
+	"shard2_2": {common.KeyFileShard2_2, common.AccountShard2_2, 2},
+	"shard2_3": {common.KeyFileShard2_3, common.AccountShard2_3, 2},
+	"shard2_4": {common.KeyFileShard2_4, common.AccountShard2_4, 2},
+	"shard2_5": {common.KeyFileShard2_5, common.AccountShard2_5, 2},
+}
+
+// server returns the rpc address of a running node of the shard.
+func (r *Runner) server(shard uint) string {
+	if r.Topology != nil {
+		for _, n := range r.Topology.Shard(shard) {
+			if !n.Managed() || n.Running() {
+				return n.Addr()
+			}
+		}
+	}
+
+	if shard == 2 {
+		return common.ServertwoAddr
+	}
+
+	return common.ServerAddr
+}
+
+type sentTx struct {
+	hash   string
+	from   string
+	to     string
+	amount int64
+}
+
+// ledger tracks the transfers of a scenario so that the expected balances
+// can be checked against the chain at the end.
+type ledger struct {
+	mutex  sync.Mutex
+	nonces map[string]int
+	start  map[string]int64
+	sent   []*sentTx
+}
+
+func newLedger() *ledger {
+	return &ledger{
+		nonces: make(map[string]int),
+		start:  make(map[string]int64),
+	}
+}
+
+// touch records the balance of an account before the scenario's first transfer.
+func (l *ledger) touch(r *Runner, name string, acc account) error {
+	if _, ok := l.start[name]; ok {
+		return nil
+	}
+
+	balance, err := common.GetBalance(r.t, common.CmdClient, acc.address, r.server(acc.shard))
+	if err != nil {
+		return fmt.Errorf("get balance of %s err: %s", name, err)
+	}
+
+	l.start[name] = balance
+	return nil
+}
+
+// send transfers amount and records it. Nonces are assigned locally so that
+// transfers keep going while the node that was asked for the nonce restarts.
+func (l *ledger) send(ctx context.Context, r *Runner, fromName string, from account, toName string, to account, amount int64) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := l.touch(r, fromName, from); err != nil {
+		return err
+	}
+
+	if err := l.touch(r, toName, to); err != nil {
+		return err
+	}
+
+	nonce, ok := l.nonces[fromName]
+	if !ok {
+		var err error
+		if nonce, err = common.GetNonce(r.t, common.CmdClient, from.address, r.server(from.shard)); err != nil {
+			return fmt.Errorf("get nonce of %s err: %s", fromName, err)
+		}
+	}
+
+	var err error
+	for i := 0; i < sendRetries; i++ {
+		var hash string
+		hash, _, err = common.SendTx(r.t, common.CmdClient, int(amount), nonce, 0, from.keyFile, to.address, "", r.server(from.shard))
+		if err == nil {
+			l.nonces[fromName] = nonce + 1
+			l.sent = append(l.sent, &sentTx{hash, fromName, toName, amount})
+			return nil
+		}
+
+		if sleepErr := sleep(ctx, sendInterval); sleepErr != nil {
+			return sleepErr
+		}
+	}
+
+	return fmt.Errorf("send from %s to %s err: %s", fromName, toName, err)
+}
+
+// fees returns the fee of every sent tx, an error if one has no receipt yet.
+func (l *ledger) fees(r *Runner) (map[*sentTx]int64, error) {
+	fees := make(map[*sentTx]int64)
+	for _, tx := range l.sent {
+		receipt, err := common.GetReceipt(r.t, common.CmdClient, tx.hash, r.server(accounts[tx.from].shard))
+		if err != nil {
+			return nil, fmt.Errorf("tx %s from %s has no receipt: %s", tx.hash, tx.from, err)
+		}
+
+		if receipt.Failed {
+			return nil, fmt.Errorf("tx %s from %s failed: %s", tx.hash, tx.from, receipt.Result)
+		}
+
+		fees[tx] = receipt.TotalFee
+	}
+
+	return fees, nil
+}
+
+// checkIncluded asserts that every sent tx has a successful receipt.
+func (l *ledger) checkIncluded(r *Runner) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	_, err := l.fees(r)
+	return err
+}
+
+// checkBalances asserts that every account touched by the scenario holds its
+// start balance plus what it received minus what it sent and paid in fees.
+func (l *ledger) checkBalances(r *Runner) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	fees, err := l.fees(r)
+	if err != nil {
+		return err
+	}
+
+	expected := make(map[string]int64)
+	for name, balance := range l.start {
+		expected[name] = balance
+	}
+
+	for _, tx := range l.sent {
+		expected[tx.from] -= tx.amount + fees[tx]
+		expected[tx.to] += tx.amount
+	}
+
+	var mismatches []string
+	for name, want := range expected {
+		acc := accounts[name]
+		got, err := common.GetBalance(r.t, common.CmdClient, acc.address, r.server(acc.shard))
+		if err != nil {
+			return fmt.Errorf("get balance of %s err: %s", name, err)
+		}
+
+		if got != want {
+			mismatches = append(mismatches, fmt.Sprintf("%s has %d, want %d", name, got, want))
+		}
+	}
+
+	if len(mismatches) > 0 {
+		sort.Strings(mismatches)
+		return fmt.Errorf("balances have not converged after %d txs: %s", len(l.sent), strings.Join(mismatches, ", "))
+	}
+
+	return nil
+}
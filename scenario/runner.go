@@ -0,0 +1,238 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/node"
+	"github.com/seeleteam/e2e-blackbox/proxy"
+)
+
+// assertInterval is the pause between two tries of an assert with within.
+const assertInterval = 2 * time.Second
+
+// Runner executes scenarios. The topology and network are optional, steps
+// that control nodes or links need them.
+type Runner struct {
+	Topology *node.Topology
+	Network  *proxy.Network
+
+	t      *testing.T
+	ledger *ledger
+}
+
+// NewRunner creates a runner that reports to t.
+func NewRunner(t *testing.T, topology *node.Topology, network *proxy.Network) *Runner {
+	return &Runner{
+		Topology: topology,
+		Network:  network,
+		t:        t,
+		ledger:   newLedger(),
+	}
+}
+
+// Check returns an error if the scenario refers to nodes that are not in the
+// topology, in which case the caller should skip it.
+func (r *Runner) Check(s *Scenario) error {
+	for _, step := range s.Steps {
+		for _, name := range step.nodes() {
+			if name == node.CLI {
+				continue
+			}
+
+			if r.Topology == nil {
+				return fmt.Errorf("scenario %s needs node %s but no topology is loaded", s.Name, name)
+			}
+
+			if r.Topology.Node(name) == nil {
+				return fmt.Errorf("scenario %s needs node %s which is not in the topology", s.Name, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Run executes the scenario. Every top level step is reported as a subtest,
+// the run stops at the first failed one.
+func (r *Runner) Run(s *Scenario) {
+	ctx := context.Background()
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(s.Timeout))
+		defer cancel()
+	}
+
+	for i, step := range s.Steps {
+		ok := r.t.Run(fmt.Sprintf("%02d_%s", i, step.title()), func(t *testing.T) {
+			start := time.Now()
+			if err := r.exec(ctx, step); err != nil {
+				t.Fatal(err)
+			}
+			t.Logf("%s done in %s", step.title(), time.Since(start))
+		})
+
+		if !ok {
+			return
+		}
+	}
+}
+
+func (r *Runner) exec(ctx context.Context, step *Step) error {
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(step.Timeout))
+		defer cancel()
+	}
+
+	var err error
+	switch {
+	case step.Action != "":
+		err = r.action(ctx, step)
+	case len(step.Parallel) > 0:
+		err = r.parallel(ctx, step)
+	case step.isLoop():
+		err = r.loop(ctx, step)
+	default:
+		err = r.sequence(ctx, step.Steps)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %s", step.title(), err)
+	}
+
+	return nil
+}
+
+func (r *Runner) action(ctx context.Context, step *Step) error {
+	do := actions[step.Action]
+	if step.Within <= 0 {
+		return do(ctx, r, step.Args)
+	}
+
+	deadline := time.Now().Add(time.Duration(step.Within))
+	for {
+		err := do(ctx, r, step.Args)
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+
+		if err = sleep(ctx, assertInterval); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *Runner) sequence(ctx context.Context, steps []*Step) error {
+	for _, step := range steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := r.exec(ctx, step); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Runner) loop(ctx context.Context, step *Step) error {
+	var deadline <-chan time.Time
+	if step.For > 0 {
+		deadline = time.After(time.Duration(step.For))
+	}
+
+	for i := 0; step.Loop == 0 || i < step.Loop; i++ {
+		start := time.Now()
+		if err := r.sequence(ctx, step.Steps); err != nil {
+			return fmt.Errorf("iteration %d: %s", i, err)
+		}
+
+		wait := time.Duration(step.Every) - time.Since(start)
+		if wait < 0 {
+			wait = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return nil
+		case <-time.After(wait):
+		}
+	}
+
+	return nil
+}
+
+// parallel runs the branches concurrently. The first foreground failure
+// cancels all branches. Background branches are cancelled once the
+// foreground is done, that cancellation is not a failure.
+func (r *Runner) parallel(ctx context.Context, step *Step) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	backgroundCtx, stopBackground := context.WithCancel(ctx)
+	defer stopBackground()
+
+	var (
+		mutex    sync.Mutex
+		firstErr error
+		fg, bg   sync.WaitGroup
+	)
+
+	fail := func(err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	for _, branch := range step.Parallel {
+		branch := branch
+		if branch.Background {
+			bg.Add(1)
+			go func() {
+				defer bg.Done()
+				err := r.exec(backgroundCtx, branch)
+				if err != nil && !(backgroundCtx.Err() == context.Canceled && ctx.Err() == nil) {
+					fail(err)
+				}
+			}()
+			continue
+		}
+
+		fg.Add(1)
+		go func() {
+			defer fg.Done()
+			if err := r.exec(ctx, branch); err != nil {
+				fail(err)
+			}
+		}()
+	}
+
+	fg.Wait()
+	stopBackground()
+	bg.Wait()
+
+	return firstErr
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
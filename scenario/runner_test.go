@@ -0,0 +1,124 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// calls counts the calls of the fake action by id.
+var calls = struct {
+	sync.Mutex
+	byID map[string]int
+}{byID: make(map[string]int)}
+
+// fakeAction fails its first "fail" calls, then blocks until cancelled if
+// "block" is set, and succeeds otherwise.
+func fakeAction(ctx context.Context, r *Runner, args map[string]string) error {
+	calls.Lock()
+	calls.byID[args["id"]]++
+	n := calls.byID[args["id"]]
+	calls.Unlock()
+
+	if failures, _ := strconv.Atoi(args["fail"]); n <= failures {
+		return fmt.Errorf("fake failure %s", args["id"])
+	}
+
+	if args["block"] != "" {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+func fake(id string, args ...string) *Step {
+	step := &Step{Action: "fake", Args: map[string]string{"id": id}}
+	for i := 0; i+1 < len(args); i += 2 {
+		step.Args[args[i]] = args[i+1]
+	}
+
+	return step
+}
+
+func ms(n int) Duration {
+	return Duration(time.Duration(n) * time.Millisecond)
+}
+
+func Test_Runner_Steps(t *testing.T) {
+	actions["fake"] = fakeAction
+	defer delete(actions, "fake")
+	calls.byID = make(map[string]int)
+
+	cases := []struct {
+		name string
+		step *Step
+		// calls are the expected calls by id, min and max for timed loops
+		calls map[string][2]int
+		err   string
+		// max bounds the time the step may take
+		max time.Duration
+	}{
+		{"loop", &Step{Loop: 3, Steps: []*Step{fake("loop")}}, map[string][2]int{"loop": {3, 3}}, "", time.Second},
+		{"sequence stops at the first failure", &Step{Steps: []*Step{fake("a"), fake("b", "fail", "9"), fake("c")}},
+			map[string][2]int{"a": {1, 1}, "b": {1, 1}, "c": {0, 0}}, "fake failure b", time.Second},
+		{"loop reports the failed iteration", &Step{Loop: 5, Steps: []*Step{fake("iteration", "fail", "9")}},
+			map[string][2]int{"iteration": {1, 1}}, "iteration 0: fake: fake failure iteration", time.Second},
+		{"every for", &Step{Every: ms(100), For: ms(450), Steps: []*Step{fake("every")}},
+			map[string][2]int{"every": {4, 6}}, "", time.Second},
+		{"parallel stops the background", &Step{Parallel: []*Step{
+			{Loop: 2, Every: ms(100), Steps: []*Step{fake("foreground")}},
+			{Background: true, Every: ms(10), Steps: []*Step{fake("background")}},
+		}}, map[string][2]int{"foreground": {2, 2}, "background": {5, 30}}, "", time.Second},
+		{"parallel failure cancels the branches", &Step{Parallel: []*Step{
+			fake("failing", "fail", "9"),
+			fake("blocked", "block", "1"),
+		}}, map[string][2]int{"failing": {1, 1}, "blocked": {1, 1}}, "fake failure failing", time.Second},
+		{"background failure fails the step", &Step{Parallel: []*Step{
+			fake("waiting", "block", "1"),
+			{Background: true, Steps: []*Step{fake("background failing", "fail", "9")}},
+		}}, map[string][2]int{"waiting": {1, 1}, "background failing": {1, 1}}, "fake failure background failing", time.Second},
+		{"timeout", &Step{Timeout: ms(50), Steps: []*Step{fake("slow", "block", "1")}},
+			map[string][2]int{"slow": {1, 1}}, context.DeadlineExceeded.Error(), time.Second},
+		{"within retries", &Step{Steps: []*Step{{Action: "fake", Args: map[string]string{"id": "retried", "fail": "1"}, Within: ms(5000)}}},
+			map[string][2]int{"retried": {2, 2}}, "", assertInterval + time.Second},
+		{"within expires", &Step{Steps: []*Step{{Action: "fake", Args: map[string]string{"id": "expired", "fail": "9"}, Within: ms(1)}}},
+			map[string][2]int{"expired": {1, 2}}, "fake failure expired", assertInterval + time.Second},
+	}
+
+	r := &Runner{}
+	for _, c := range cases {
+		start := time.Now()
+		err := r.exec(context.Background(), c.step)
+		elapsed := time.Since(start)
+
+		if c.err == "" && err != nil {
+			t.Fatalf("Test_Runner_Steps %s err: %s", c.name, err)
+		}
+
+		if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Fatalf("Test_Runner_Steps %s err is %v, expected %s", c.name, err, c.err)
+		}
+
+		if elapsed > c.max {
+			t.Fatalf("Test_Runner_Steps %s takes %s, expected at most %s", c.name, elapsed, c.max)
+		}
+
+		calls.Lock()
+		for id, bounds := range c.calls {
+			if n := calls.byID[id]; n < bounds[0] || n > bounds[1] {
+				t.Errorf("Test_Runner_Steps %s calls %s %d times, expected %d to %d", c.name, id, n, bounds[0], bounds[1])
+			}
+		}
+		calls.Unlock()
+	}
+}
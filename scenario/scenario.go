@@ -0,0 +1,213 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Scenario is a declarative chaos test loaded from a json file, e.g.
+//
+//	{
+//		"name": "cross shard transfers under restarts",
+//		"timeout": "30m",
+//		"steps": [
+//			{"parallel": [
+//				{"loop": 500, "steps": [{"action": "sendtx", "args": {"from": "shard1_4", "to": "shard2_4", "amount": "1"}}]},
+//				{"background": true, "every": "30s", "steps": [{"action": "restart", "args": {"node": "shard1-b"}}]}
+//			]},
+//			{"action": "assert", "args": {"check": "ledger"}, "within": "5m"}
+//		]
+//	}
+type Scenario struct {
+	Name    string   `json:"name"`
+	Timeout Duration `json:"timeout"`
+	Steps   []*Step  `json:"steps"`
+}
+
+// Step is one node of the scenario tree. It is either an action with its
+// arguments, a loop over its child steps (loop, every or for is set), a
+// parallel group of branches, or a plain sequence of child steps.
+type Step struct {
+	Name string `json:"name"`
+
+	Action string            `json:"action"`
+	Args   map[string]string `json:"args"`
+
+	// Steps is the body of a loop or sequence
+	Steps []*Step `json:"steps"`
+	// Loop is the number of iterations, 0 with every or for set means until stopped
+	Loop int `json:"loop"`
+	// Every is the minimum interval between the starts of two iterations
+	Every Duration `json:"every"`
+	// For is the maximum time the loop runs
+	For Duration `json:"for"`
+
+	// Parallel branches run concurrently, the step ends when all of the
+	// branches that are not in the background are done
+	Parallel []*Step `json:"parallel"`
+	// Background branches are stopped once the foreground branches are done
+	Background bool `json:"background"`
+
+	// Timeout fails the step if it runs longer
+	Timeout Duration `json:"timeout"`
+	// Within retries an assert until it holds or the duration elapses
+	Within Duration `json:"within"`
+}
+
+// Duration is a time.Duration written as a string like "30s" in json.
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("duration should be a string like \"30s\": %s", err)
+	}
+
+	value, err := time.ParseDuration(str)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(value)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Load reads and validates a scenario file.
+func Load(file string) (*Scenario, error) {
+	bytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Scenario
+	if err = json.Unmarshal(bytes, &s); err != nil {
+		return nil, fmt.Errorf("invalid scenario file %s: %s", file, err)
+	}
+
+	if s.Name == "" {
+		s.Name = file
+	}
+
+	for i, step := range s.Steps {
+		if err = step.validate(fmt.Sprintf("steps[%d]", i), false); err != nil {
+			return nil, fmt.Errorf("invalid scenario file %s: %s", file, err)
+		}
+	}
+
+	return &s, nil
+}
+
+func (s *Step) isLoop() bool {
+	return s.Loop > 0 || s.Every > 0 || s.For > 0
+}
+
+func (s *Step) validate(path string, background bool) error {
+	kinds := 0
+	if s.Action != "" {
+		kinds++
+		if _, ok := actions[s.Action]; !ok {
+			return fmt.Errorf("%s: unknown action %q", path, s.Action)
+		}
+	}
+
+	if len(s.Parallel) > 0 {
+		kinds++
+	}
+
+	if len(s.Steps) > 0 {
+		kinds++
+	}
+
+	if kinds != 1 {
+		return fmt.Errorf("%s: a step needs exactly one of action, steps or parallel", path)
+	}
+
+	if s.isLoop() && len(s.Steps) == 0 {
+		return fmt.Errorf("%s: a loop needs steps", path)
+	}
+
+	background = background || s.Background
+	if s.isLoop() && s.Loop == 0 && s.For == 0 && !background {
+		return fmt.Errorf("%s: a loop that is not in the background needs loop or for", path)
+	}
+
+	if s.Within > 0 && s.Action != "assert" {
+		return fmt.Errorf("%s: within is only valid for assert", path)
+	}
+
+	for i, child := range s.Steps {
+		if err := child.validate(fmt.Sprintf("%s.steps[%d]", path, i), background); err != nil {
+			return err
+		}
+	}
+
+	foreground := 0
+	for i, branch := range s.Parallel {
+		if !branch.Background {
+			foreground++
+		}
+
+		if err := branch.validate(fmt.Sprintf("%s.parallel[%d]", path, i), background); err != nil {
+			return err
+		}
+	}
+
+	if len(s.Parallel) > 0 && foreground == 0 {
+		return fmt.Errorf("%s: parallel needs at least one branch that is not in the background", path)
+	}
+
+	return nil
+}
+
+// title is the name shown in the report.
+func (s *Step) title() string {
+	switch {
+	case s.Name != "":
+		return s.Name
+	case s.Action != "":
+		return s.Action
+	case len(s.Parallel) > 0:
+		return "parallel"
+	case s.isLoop():
+		return "loop"
+	default:
+		return "sequence"
+	}
+}
+
+// nodes returns the node names the step and its children refer to.
+func (s *Step) nodes() []string {
+	var names []string
+	if name, ok := s.Args["node"]; ok {
+		names = append(names, name)
+	}
+
+	for _, key := range []string{"groups", "from", "to"} {
+		if value, ok := s.Args[key]; ok && isNodeArg(s.Action, key) {
+			names = append(names, splitGroups(value)...)
+		}
+	}
+
+	for _, child := range s.Steps {
+		names = append(names, child.nodes()...)
+	}
+
+	for _, branch := range s.Parallel {
+		names = append(names, branch.nodes()...)
+	}
+
+	return names
+}
@@ -0,0 +1,81 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package scenario
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Load(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scenario")
+	if err != nil {
+		t.Fatalf("Test_Load create temp dir err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		name     string
+		scenario string
+		err      string
+	}{
+		{"valid", `{"name": "restarts", "timeout": "30m", "steps": [
+			{"parallel": [
+				{"loop": 2, "steps": [{"action": "sendtx", "args": {"from": "shard1_4", "to": "shard2_4", "amount": "1"}}]},
+				{"background": true, "every": "30s", "steps": [{"action": "restart", "args": {"node": "shard1-b"}}]}
+			]},
+			{"action": "assert", "args": {"check": "ledger"}, "within": "5m"}]}`, ""},
+		{"for loop", `{"steps": [{"for": "1m", "steps": [{"action": "sleep", "args": {"duration": "1s"}}]}]}`, ""},
+		{"invalid json", `{"steps": [`, "invalid scenario file"},
+		{"duration without unit", `{"timeout": "30"}`, "missing unit"},
+		{"duration not a string", `{"timeout": 30}`, "duration should be a string"},
+		{"unknown action", `{"steps": [{"action": "reboot"}]}`, `steps[0]: unknown action "reboot"`},
+		{"empty step", `{"steps": [{}]}`, "steps[0]: a step needs exactly one of action, steps or parallel"},
+		{"action and steps", `{"steps": [{"action": "heal", "steps": [{"action": "heal"}]}]}`, "exactly one of"},
+		{"loop without steps", `{"steps": [{"loop": 2, "action": "heal"}]}`, "steps[0]: a loop needs steps"},
+		{"endless foreground loop", `{"steps": [{"every": "1s", "steps": [{"action": "heal"}]}]}`, "a loop that is not in the background needs loop or for"},
+		{"endless loop inside background", `{"steps": [{"parallel": [{"action": "heal"},
+			{"background": true, "steps": [{"every": "1s", "steps": [{"action": "heal"}]}]}]}]}`, ""},
+		{"within on action", `{"steps": [{"action": "heal", "within": "1m"}]}`, "within is only valid for assert"},
+		{"background only", `{"steps": [{"parallel": [{"background": true, "action": "heal"}]}]}`, "parallel needs at least one branch"},
+		{"nested path", `{"steps": [{"action": "heal"}, {"parallel": [{"action": "heal"}, {"steps": [{"action": "reboot"}]}]}]}`,
+			"steps[1].parallel[1].steps[0]: unknown action"},
+	}
+
+	for i, c := range cases {
+		file := filepath.Join(dir, c.name+".json")
+		if err = ioutil.WriteFile(file, []byte(c.scenario), 0644); err != nil {
+			t.Fatalf("Test_Load write case %d err: %s", i, err)
+		}
+
+		s, err := Load(file)
+		if c.err == "" && err != nil {
+			t.Fatalf("Test_Load %s err: %s", c.name, err)
+		}
+
+		if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Fatalf("Test_Load %s err is %v, expected %s", c.name, err, c.err)
+		}
+
+		if err == nil && c.name == "valid" {
+			if s.Name != "restarts" || time.Duration(s.Timeout) != 30*time.Minute || len(s.Steps) != 2 {
+				t.Fatalf("Test_Load %s loads %+v", c.name, s)
+			}
+
+			if within := time.Duration(s.Steps[1].Within); within != 5*time.Minute {
+				t.Fatalf("Test_Load %s within is %s, expected 5m", c.name, within)
+			}
+		}
+
+		if err == nil && c.name == "for loop" && s.Name != file {
+			t.Fatalf("Test_Load %s name is %s, expected the file name", c.name, s.Name)
+		}
+	}
+}
@@ -15,6 +15,14 @@ import (
 const (
 	DbName   = "Seele-blacke2e-test"
 	CoverKey = "Seele-cover-test"
+
+	CheckpointKey = "Seele-audit-checkpoint"
+
+	BenchKey         = "Seele-bench-runs"
+	BenchVersionsKey = "Seele-bench-versions"
+	BenchReportedKey = "Seele-bench-reported"
+
+	MetricsKey = "Seele-metrics-samples"
 )
 
 // DB ...
@@ -56,3 +64,68 @@ func Get(date string) (coverbyte []byte) {
 
 	return coverbyte
 }
+
+// SaveCheckpoint saves the audit checkpoint of the chain
+func SaveCheckpoint(chain string, checkpoint []byte) {
+	db.Put([]byte(chain+CheckpointKey), checkpoint)
+}
+
+// GetCheckpoint gets the audit checkpoint of the chain, nil if there is none
+func GetCheckpoint(chain string) []byte {
+	checkpoint, err := db.Get([]byte(chain + CheckpointKey))
+	if err != nil {
+		return nil
+	}
+
+	return checkpoint
+}
+
+// SaveBench saves the bench runs of the workload on the node version
+func SaveBench(workload, version string, runs []byte) {
+	db.Put([]byte(workload+"@"+version+BenchKey), runs)
+}
+
+// GetBench gets the bench runs of the workload on the node version, nil if there is none
+func GetBench(workload, version string) []byte {
+	runs, err := db.Get([]byte(workload + "@" + version + BenchKey))
+	if err != nil {
+		return nil
+	}
+
+	return runs
+}
+
+// SaveBenchVersions saves the node versions the workload ran on
+func SaveBenchVersions(workload string, versions []byte) {
+	db.Put([]byte(workload+BenchVersionsKey), versions)
+}
+
+// GetBenchVersions gets the node versions the workload ran on, nil if there is none
+func GetBenchVersions(workload string) []byte {
+	versions, err := db.Get([]byte(workload + BenchVersionsKey))
+	if err != nil {
+		return nil
+	}
+
+	return versions
+}
+
+// SaveBenchReported saves the date of the last run of the workload in a daily report
+func SaveBenchReported(workload string, date []byte) {
+	db.Put([]byte(workload+BenchReportedKey), date)
+}
+
+// GetBenchReported gets the date of the last run of the workload in a daily report, nil if there is none
+func GetBenchReported(workload string) []byte {
+	date, err := db.Get([]byte(workload + BenchReportedKey))
+	if err != nil {
+		return nil
+	}
+
+	return date
+}
+
+// SaveMetrics saves the node samples of the daily run
+func SaveMetrics(date string, samples []byte) {
+	db.Put([]byte(date+MetricsKey), samples)
+}
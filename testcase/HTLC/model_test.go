@@ -0,0 +1,463 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package htlc
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+var (
+	modelSeed   = flag.Int64("htlc.seed", 0, "seed of the generated htlc sequences, random if 0")
+	modelRuns   = flag.Int("htlc.runs", 3, "number of generated htlc sequences")
+	modelShrink = flag.Int("htlc.shrink", 20, "maximum number of replays to shrink a failed sequence")
+)
+
+const (
+	// actorCount is the number of accounts the generated sequences run between
+	actorCount = 3
+	// actorFunds pays the amounts and fees of all sequences and shrink replays
+	actorFunds = 1000000000
+)
+
+// keyDir holds the keyfiles of the fresh accounts, removed after the suite
+var keyDir string
+
+// funders pay the fresh accounts of their shard
+var funders = map[uint]struct {
+	keyfile string
+	account string
+	addr    string
+}{
+	1: {common.KeyFileShard1_1, common.AccountShard1_1, common.ServerAddr},
+	2: {common.KeyFileShard2_1, common.AccountShard2_1, common.ServertwoAddr},
+}
+
+// actor is an account of the generated sequences, all on shard 1
+type actor struct {
+	keyfile string
+	account string
+}
+
+// actors are fresh accounts, other packages send from the committed
+// keyfiles concurrently and would break the exact balance checks
+var actors []actor
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "htlc")
+	if err != nil {
+		fmt.Println("create key dir err:", err)
+		os.Exit(1)
+	}
+
+	keyDir = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// freshAccounts generates count accounts of the shard and funds each with
+// amount, they are only used by the calling test.
+func freshAccounts(t *testing.T, name string, shard uint, count, amount int) []actor {
+	var accounts []actor
+	var addresses []string
+	for i := 0; i < count; i++ {
+		keyfile, account, err := common.NewKeyFile(t, common.CmdClient, shard, keyDir)
+		if err != nil {
+			t.Fatalf("%s new account of shard %d err: %s", name, shard, err)
+		}
+
+		accounts = append(accounts, actor{keyfile, account})
+		addresses = append(addresses, account)
+	}
+
+	funder := funders[shard]
+	if err := common.FundAccounts(t, common.CmdClient, funder.keyfile, funder.account, amount, funder.addr, addresses...); err != nil {
+		t.Fatalf("%s fund accounts of shard %d err: %s", name, shard, err)
+	}
+
+	return accounts
+}
+
+// secrets per sequence, withdrawing with the wrong one forges the preimage
+const secretCount = 2
+
+type htlcState int
+
+const (
+	stateLocked htlcState = iota
+	stateWithdrawn
+	stateRefunded
+)
+
+// contract is the model of a created htlc.
+type contract struct {
+	hash     string
+	from     int
+	to       int
+	amount   int64
+	secret   int
+	timelock int64
+	state    htlcState
+}
+
+// status returns the state of the contract at the time, an unspent htlc
+// whose time lock is over is expired.
+func (c *contract) status(now int64) string {
+	switch {
+	case c.state == stateWithdrawn:
+		return "withdrawn"
+	case c.state == stateRefunded:
+		return "refunded"
+	case now >= c.timelock:
+		return "expired"
+	default:
+		return "locked"
+	}
+}
+
+type opKind int
+
+const (
+	opCreate opKind = iota
+	opWithdraw
+	opRefund
+	opWait
+)
+
+// op is a step of a sequence. Withdraw and refund refer to the created
+// contracts by index, so they still apply when a shrink removes a create.
+type op struct {
+	kind     opKind
+	actor    int
+	to       int
+	amount   int64
+	lock     int64
+	contract int
+	secret   int
+	wait     time.Duration
+}
+
+func (o op) String() string {
+	switch o.kind {
+	case opCreate:
+		return fmt.Sprintf("create(from=%d to=%d amount=%d secret=%d lock=%ds)", o.actor, o.to, o.amount, o.secret, o.lock)
+	case opWithdraw:
+		return fmt.Sprintf("withdraw(by=%d contract=%d secret=%d)", o.actor, o.contract, o.secret)
+	case opRefund:
+		return fmt.Sprintf("refund(by=%d contract=%d)", o.actor, o.contract)
+	default:
+		return fmt.Sprintf("wait(%s)", o.wait)
+	}
+}
+
+func formatOps(ops []op) string {
+	strs := make([]string, len(ops))
+	for i, o := range ops {
+		strs[i] = o.String()
+	}
+	return strings.Join(strs, ", ")
+}
+
+// generate returns a random sequence that starts with a create, the locks and
+// waits are chosen so that every contract state is reached.
+func generate(r *rand.Rand) []op {
+	ops := []op{randomCreate(r)}
+	for n := 3 + r.Intn(5); n > 0; n-- {
+		switch r.Intn(5) {
+		case 0:
+			ops = append(ops, randomCreate(r))
+		case 1, 2:
+			ops = append(ops, op{kind: opWithdraw, actor: r.Intn(len(actors)), contract: r.Intn(2), secret: r.Intn(secretCount)})
+		case 3:
+			ops = append(ops, op{kind: opRefund, actor: r.Intn(len(actors)), contract: r.Intn(2)})
+		default:
+			waits := []time.Duration{10 * time.Second, 30 * time.Second, 60 * time.Second}
+			ops = append(ops, op{kind: opWait, wait: waits[r.Intn(len(waits))]})
+		}
+	}
+
+	return ops
+}
+
+func randomCreate(r *rand.Rand) op {
+	from := r.Intn(len(actors))
+	locks := []int64{-60, 40, 90}
+	return op{
+		kind:   opCreate,
+		actor:  from,
+		to:     (from + 1 + r.Intn(len(actors)-1)) % len(actors),
+		amount: int64(1 + r.Intn(5000)),
+		lock:   locks[r.Intn(len(locks))],
+		secret: r.Intn(secretCount),
+	}
+}
+
+// world runs a sequence on chain and tracks the model of its contracts.
+type world struct {
+	t         *testing.T
+	preimages [secretCount]string
+	hashes    [secretCount]string
+	contracts []*contract
+}
+
+// run executes the sequence and returns the first difference between the
+// chain and the model.
+func run(t *testing.T, ops []op) error {
+	w := &world{t: t}
+	for i := range w.preimages {
+		w.preimages[i], w.hashes[i] = common.NewSecret()
+	}
+
+	for i, o := range ops {
+		var err error
+		switch o.kind {
+		case opCreate:
+			err = w.create(o)
+		case opWithdraw:
+			err = w.withdraw(o)
+		case opRefund:
+			err = w.refund(o)
+		default:
+			time.Sleep(o.wait)
+		}
+
+		if err != nil {
+			return fmt.Errorf("step %d %s: %s", i, o, err)
+		}
+	}
+
+	return nil
+}
+
+// mined waits for the tx and returns its receipt and the block time the
+// contract saw when executing it.
+func (w *world) mined(txHash string) (*common.ReceiptInfo, int64, error) {
+	receipt, err := common.WaitReceipt(w.t, common.CmdClient, txHash, common.ServerAddr)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	now, err := common.GetTxBlockTime(w.t, common.CmdClient, txHash, common.ServerAddr)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return receipt, now, nil
+}
+
+// expect compares the outcome with the model. At the exact time lock either
+// outcome is accepted and the model follows the chain.
+func expect(receipt *common.ReceiptInfo, success, boundary bool) (bool, error) {
+	if boundary {
+		return !receipt.Failed, nil
+	}
+
+	if receipt.Failed == success {
+		return false, fmt.Errorf("expected success %t, got failed %t: %s", success, receipt.Failed, receipt.Result)
+	}
+
+	return success, nil
+}
+
+// balance checks the balance change of the actor, the fee is charged whether the tx failed or not.
+func (w *world) balance(actor int, before int64, receipt *common.ReceiptInfo, change int64) error {
+	after, err := common.GetBalance(w.t, common.CmdClient, actors[actor].account, common.ServerAddr)
+	if err != nil {
+		return err
+	}
+
+	if after != before-receipt.TotalFee+change {
+		return fmt.Errorf("balance of %s is %d, expected %d - fee %d + %d", actors[actor].account, after, before, receipt.TotalFee, change)
+	}
+
+	return nil
+}
+
+// decoded checks the htlc returned by a successful tx against the model.
+func (w *world) decoded(receipt *common.ReceiptInfo, c *contract) error {
+	info, err := common.HTLCDecode(w.t, common.CmdClient, receipt.Result)
+	if err != nil {
+		return err
+	}
+
+	preimage := ""
+	if c.state == stateWithdrawn {
+		preimage = w.preimages[c.secret]
+	}
+
+	switch {
+	case info.Withdrawed != (c.state == stateWithdrawn), info.Refunded != (c.state == stateRefunded), info.Preimage != preimage:
+		return fmt.Errorf("htlc withdrawed %t refunded %t preimage %q, model is %d", info.Withdrawed, info.Refunded, info.Preimage, c.state)
+	case info.Tx.TxData.From != actors[c.from].account, info.To != actors[c.to].account, info.Tx.TxData.Amount != c.amount:
+		return fmt.Errorf("htlc %s -> %s amount %d differs from the model", info.Tx.TxData.From, info.To, info.Tx.TxData.Amount)
+	case info.HashLock != w.hashes[c.secret], info.TimeLock != c.timelock:
+		return fmt.Errorf("htlc hash lock %s time lock %d differs from the model", info.HashLock, info.TimeLock)
+	}
+
+	return nil
+}
+
+func (w *world) create(o op) error {
+	before, err := common.GetBalance(w.t, common.CmdClient, actors[o.actor].account, common.ServerAddr)
+	if err != nil {
+		return err
+	}
+
+	c := &contract{from: o.actor, to: o.to, amount: o.amount, secret: o.secret, timelock: time.Now().Unix() + o.lock}
+	info, err := common.HTLCCreate(w.t, common.CmdClient, actors[o.actor].keyfile, actors[o.to].account, o.amount, w.hashes[o.secret], c.timelock, common.ServerAddr)
+	if err != nil {
+		return err
+	}
+
+	receipt, now, err := w.mined(info.Tx.Hash)
+	if err != nil {
+		return err
+	}
+
+	success, err := expect(receipt, now < c.timelock, now == c.timelock)
+	if err != nil {
+		return err
+	}
+
+	var change int64
+	if success {
+		change = -c.amount
+		c.hash = info.Tx.Hash
+		w.contracts = append(w.contracts, c)
+		if err = w.decoded(receipt, c); err != nil {
+			return err
+		}
+	}
+
+	return w.balance(o.actor, before, receipt, change)
+}
+
+func (w *world) withdraw(o op) error {
+	if len(w.contracts) == 0 {
+		return nil
+	}
+
+	c := w.contracts[o.contract%len(w.contracts)]
+	before, err := common.GetBalance(w.t, common.CmdClient, actors[o.actor].account, common.ServerAddr)
+	if err != nil {
+		return err
+	}
+
+	info, err := common.HTLCWithdraw(w.t, common.CmdClient, actors[o.actor].keyfile, c.hash, w.preimages[o.secret], common.ServerAddr)
+	if err != nil {
+		return err
+	}
+
+	receipt, now, err := w.mined(info.Tx.Hash)
+	if err != nil {
+		return err
+	}
+
+	allowed := c.state == stateLocked && o.actor == c.to && o.secret == c.secret
+	success, err := expect(receipt, allowed && now < c.timelock, allowed && now == c.timelock)
+	if err != nil {
+		return fmt.Errorf("%s contract: %s", c.status(now), err)
+	}
+
+	var change int64
+	if success {
+		change = c.amount
+		c.state = stateWithdrawn
+		if err = w.decoded(receipt, c); err != nil {
+			return err
+		}
+	}
+
+	return w.balance(o.actor, before, receipt, change)
+}
+
+func (w *world) refund(o op) error {
+	if len(w.contracts) == 0 {
+		return nil
+	}
+
+	c := w.contracts[o.contract%len(w.contracts)]
+	before, err := common.GetBalance(w.t, common.CmdClient, actors[o.actor].account, common.ServerAddr)
+	if err != nil {
+		return err
+	}
+
+	info, err := common.HTLCRefund(w.t, common.CmdClient, actors[o.actor].keyfile, c.hash, common.ServerAddr)
+	if err != nil {
+		return err
+	}
+
+	receipt, now, err := w.mined(info.Tx.Hash)
+	if err != nil {
+		return err
+	}
+
+	allowed := c.state == stateLocked && o.actor == c.from
+	success, err := expect(receipt, allowed && now >= c.timelock, allowed && now == c.timelock)
+	if err != nil {
+		return fmt.Errorf("%s contract: %s", c.status(now), err)
+	}
+
+	var change int64
+	if success {
+		change = c.amount
+		c.state = stateRefunded
+		if err = w.decoded(receipt, c); err != nil {
+			return err
+		}
+	}
+
+	return w.balance(o.actor, before, receipt, change)
+}
+
+// shrink removes steps of a failed sequence as long as it keeps failing and
+// returns the smallest failing sequence found within the replay budget.
+func shrink(t *testing.T, ops []op, err error) ([]op, error) {
+	replays := *modelShrink
+	for removed := true; removed && replays > 0; {
+		removed = false
+		for i := 0; i < len(ops) && replays > 0; i++ {
+			candidate := append(append([]op{}, ops[:i]...), ops[i+1:]...)
+			replays--
+			if cerr := run(t, candidate); cerr != nil {
+				ops, err, removed = candidate, cerr, true
+				i--
+			}
+		}
+	}
+
+	return ops, err
+}
+
+func Test_HTLC_Model(t *testing.T) {
+	seed := *modelSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	actors = freshAccounts(t, "Test_HTLC_Model", 1, actorCount, actorFunds)
+	r := rand.New(rand.NewSource(seed))
+	for i := 0; i < *modelRuns; i++ {
+		ops := generate(r)
+		t.Logf("Test_HTLC_Model seed %d sequence %d: %s", seed, i, formatOps(ops))
+		err := run(t, ops)
+		if err == nil {
+			continue
+		}
+
+		minimal, merr := shrink(t, ops, err)
+		t.Fatalf("Test_HTLC_Model seed %d sequence %d failed: %s\nminimal sequence: %s\nminimal err: %s", seed, i, err, formatOps(minimal), merr)
+	}
+}
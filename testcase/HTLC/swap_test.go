@@ -0,0 +1,244 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package htlc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+// partyFunds pays the amounts and fees of a swap on each shard
+const partyFunds = 100000000
+
+// party of a swap holds an account on each shard.
+type party struct {
+	keyfile1, account1 string
+	keyfile2, account2 string
+}
+
+// newParties returns count parties with fresh funded accounts, so the exact
+// balance checks are not disturbed by other packages.
+func newParties(t *testing.T, name string, count int) []party {
+	shard1, shard2 := freshAccounts(t, name, 1, count, partyFunds), freshAccounts(t, name, 2, count, partyFunds)
+	parties := make([]party, count)
+	for i := range parties {
+		parties[i] = party{shard1[i].keyfile, shard1[i].account, shard2[i].keyfile, shard2[i].account}
+	}
+
+	return parties
+}
+
+// swapBalances reads the balances of the parties on both shards.
+func swapBalances(t *testing.T, name string, parties ...party) map[string]int64 {
+	balances := make(map[string]int64)
+	for _, p := range parties {
+		for account, addr := range map[string]string{p.account1: common.ServerAddr, p.account2: common.ServertwoAddr} {
+			balance, err := common.GetBalance(t, common.CmdClient, account, addr)
+			if err != nil {
+				t.Fatalf("%s get balance of %s err: %s", name, account, err)
+			}
+			balances[account] = balance
+		}
+	}
+
+	return balances
+}
+
+// checkSwapBalances compares the balances with begin plus the expected changes.
+func checkSwapBalances(t *testing.T, name string, begin, change map[string]int64, parties ...party) {
+	for account, balance := range swapBalances(t, name, parties...) {
+		if expected := begin[account] + change[account]; balance != expected {
+			t.Fatalf("%s balance of %s is %d, expected %d", name, account, balance, expected)
+		}
+	}
+}
+
+func swapMined(t *testing.T, name, txHash, addr string) *common.ReceiptInfo {
+	receipt, err := common.WaitReceipt(t, common.CmdClient, txHash, addr)
+	if err != nil {
+		t.Fatalf("%s get receipt err: %s", name, err)
+	}
+
+	return receipt
+}
+
+// swapLock creates a htlc and returns its hash and the fee paid.
+func swapLock(t *testing.T, name, keyfile, to string, amount int64, hash string, locktime int64, addr string) (string, int64) {
+	info, err := common.HTLCCreate(t, common.CmdClient, keyfile, to, amount, hash, locktime, addr)
+	if err != nil {
+		t.Fatalf("%s create htlc err: %s", name, err)
+	}
+
+	receipt := swapMined(t, name, info.Tx.Hash, addr)
+	if receipt.Failed {
+		t.Fatalf("%s create htlc failed: %s", name, receipt.Result)
+	}
+
+	return info.Tx.Hash, receipt.TotalFee
+}
+
+func swapWithdraw(t *testing.T, name, keyfile, htlc, preimage, addr string) *common.ReceiptInfo {
+	info, err := common.HTLCWithdraw(t, common.CmdClient, keyfile, htlc, preimage, addr)
+	if err != nil {
+		t.Fatalf("%s withdraw htlc err: %s", name, err)
+	}
+
+	return swapMined(t, name, info.Tx.Hash, addr)
+}
+
+func swapRefund(t *testing.T, name, keyfile, htlc, addr string) *common.ReceiptInfo {
+	info, err := common.HTLCRefund(t, common.CmdClient, keyfile, htlc, addr)
+	if err != nil {
+		t.Fatalf("%s refund htlc err: %s", name, err)
+	}
+
+	return swapMined(t, name, info.Tx.Hash, addr)
+}
+
+// revealedPreimage reads the preimage the withdraw revealed on chain, which
+// is how the counterparty learns it.
+func revealedPreimage(t *testing.T, name string, receipt *common.ReceiptInfo) string {
+	info, err := common.HTLCDecode(t, common.CmdClient, receipt.Result)
+	if err != nil {
+		t.Fatalf("%s htlc decode err: %s", name, err)
+	}
+
+	if !info.Withdrawed || info.Preimage == "" {
+		t.Fatalf("%s withdraw revealed no preimage", name)
+	}
+
+	return info.Preimage
+}
+
+// waitUntil sleeps until the time lock is over, with a margin for the block time.
+func waitUntil(locktime int64) {
+	time.Sleep(time.Until(time.Unix(locktime, 0).Add(20 * time.Second)))
+}
+
+// alice swaps amount1 on shard 1 against amount2 of bob on shard 2. The
+// initiator's time lock is twice the counterparty's, so bob always has time to
+// withdraw once alice revealed the preimage.
+func Test_HTLC_Swap_Cross_Shard(t *testing.T) {
+	name := "Test_HTLC_Swap_Cross_Shard"
+	amount1, amount2 := int64(3000), int64(2000)
+	parties := newParties(t, name, 2)
+	alice, bob := parties[0], parties[1]
+	begin := swapBalances(t, name, alice, bob)
+	preimage, hash := common.NewSecret()
+
+	htlc1, fee1 := swapLock(t, name, alice.keyfile1, bob.account1, amount1, hash, common.GenerateTime(20), common.ServerAddr)
+	htlc2, fee2 := swapLock(t, name, bob.keyfile2, alice.account2, amount2, hash, common.GenerateTime(10), common.ServertwoAddr)
+
+	withdraw2 := swapWithdraw(t, name, alice.keyfile2, htlc2, preimage, common.ServertwoAddr)
+	if withdraw2.Failed {
+		t.Fatalf("%s alice withdraw on shard 2 failed: %s", name, withdraw2.Result)
+	}
+
+	revealed := revealedPreimage(t, name, withdraw2)
+	if revealed != preimage {
+		t.Fatalf("%s revealed preimage %s, expected %s", name, revealed, preimage)
+	}
+
+	withdraw1 := swapWithdraw(t, name, bob.keyfile1, htlc1, revealed, common.ServerAddr)
+	if withdraw1.Failed {
+		t.Fatalf("%s bob withdraw on shard 1 failed: %s", name, withdraw1.Result)
+	}
+
+	checkSwapBalances(t, name, begin, map[string]int64{
+		alice.account1: -amount1 - fee1,
+		bob.account1:   amount1 - withdraw1.TotalFee,
+		bob.account2:   -amount2 - fee2,
+		alice.account2: amount2 - withdraw2.TotalFee,
+	}, alice, bob)
+}
+
+// alice misses bob's time lock, her withdraw fails and both sides refund.
+func Test_HTLC_Swap_Cross_Shard_Refund(t *testing.T) {
+	name := "Test_HTLC_Swap_Cross_Shard_Refund"
+	amount1, amount2 := int64(3000), int64(2000)
+	parties := newParties(t, name, 2)
+	alice, bob := parties[0], parties[1]
+	begin := swapBalances(t, name, alice, bob)
+	preimage, hash := common.NewSecret()
+
+	locktime1, locktime2 := time.Now().Unix()+120, time.Now().Unix()+60
+	htlc1, fee1 := swapLock(t, name, alice.keyfile1, bob.account1, amount1, hash, locktime1, common.ServerAddr)
+	htlc2, fee2 := swapLock(t, name, bob.keyfile2, alice.account2, amount2, hash, locktime2, common.ServertwoAddr)
+
+	waitUntil(locktime2)
+	withdraw2 := swapWithdraw(t, name, alice.keyfile2, htlc2, preimage, common.ServertwoAddr)
+	if !withdraw2.Failed {
+		t.Fatalf("%s alice withdraw after the time lock succeeded", name)
+	}
+
+	refund2 := swapRefund(t, name, bob.keyfile2, htlc2, common.ServertwoAddr)
+	if refund2.Failed {
+		t.Fatalf("%s bob refund on shard 2 failed: %s", name, refund2.Result)
+	}
+
+	waitUntil(locktime1)
+	refund1 := swapRefund(t, name, alice.keyfile1, htlc1, common.ServerAddr)
+	if refund1.Failed {
+		t.Fatalf("%s alice refund on shard 1 failed: %s", name, refund1.Result)
+	}
+
+	checkSwapBalances(t, name, begin, map[string]int64{
+		alice.account1: -fee1 - refund1.TotalFee,
+		bob.account1:   0,
+		bob.account2:   -fee2 - refund2.TotalFee,
+		alice.account2: -withdraw2.TotalFee,
+	}, alice, bob)
+}
+
+// the preimage revealed on shard 2 is public, but on shard 1 only the
+// receiver may use it and only once.
+func Test_HTLC_Swap_Cross_Shard_Revealed_Preimage(t *testing.T) {
+	name := "Test_HTLC_Swap_Cross_Shard_Revealed_Preimage"
+	amount1, amount2 := int64(3000), int64(2000)
+	parties := newParties(t, name, 3)
+	alice, bob, carol := parties[0], parties[1], parties[2]
+	begin := swapBalances(t, name, alice, bob, carol)
+	preimage, hash := common.NewSecret()
+
+	htlc1, fee1 := swapLock(t, name, alice.keyfile1, bob.account1, amount1, hash, common.GenerateTime(20), common.ServerAddr)
+	htlc2, fee2 := swapLock(t, name, bob.keyfile2, alice.account2, amount2, hash, common.GenerateTime(10), common.ServertwoAddr)
+
+	withdraw2 := swapWithdraw(t, name, alice.keyfile2, htlc2, preimage, common.ServertwoAddr)
+	if withdraw2.Failed {
+		t.Fatalf("%s alice withdraw on shard 2 failed: %s", name, withdraw2.Result)
+	}
+	revealed := revealedPreimage(t, name, withdraw2)
+
+	stolen := swapWithdraw(t, name, carol.keyfile1, htlc1, revealed, common.ServerAddr)
+	if !stolen.Failed {
+		t.Fatalf("%s carol withdraw with the revealed preimage succeeded", name)
+	}
+
+	withdraw1 := swapWithdraw(t, name, bob.keyfile1, htlc1, revealed, common.ServerAddr)
+	if withdraw1.Failed {
+		t.Fatalf("%s bob withdraw on shard 1 failed: %s", name, withdraw1.Result)
+	}
+
+	again := swapWithdraw(t, name, bob.keyfile1, htlc1, revealed, common.ServerAddr)
+	if !again.Failed {
+		t.Fatalf("%s bob withdraw the same htlc twice", name)
+	}
+
+	reused := swapWithdraw(t, name, alice.keyfile2, htlc2, revealed, common.ServertwoAddr)
+	if !reused.Failed {
+		t.Fatalf("%s alice withdraw the same htlc twice", name)
+	}
+
+	checkSwapBalances(t, name, begin, map[string]int64{
+		alice.account1: -amount1 - fee1,
+		bob.account1:   amount1 - withdraw1.TotalFee - again.TotalFee,
+		carol.account1: -stolen.TotalFee,
+		bob.account2:   -amount2 - fee2,
+		alice.account2: amount2 - withdraw2.TotalFee - reused.TotalFee,
+	}, alice, bob, carol)
+}
@@ -0,0 +1,52 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package chaos
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/seeleteam/e2e-blackbox/node"
+	"github.com/seeleteam/e2e-blackbox/scenario"
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+// Test_Chaos_Scenarios runs every scenario file in ./scenarios. Scenarios
+// that control nodes are skipped when no topology is configured. The
+// scenarios restart and partition nodes, so they only run when the test owns
+// the nodes, see node.Exclusive.
+func Test_Chaos_Scenarios(t *testing.T) {
+	if !node.Exclusive() {
+		t.Skipf("Test_Chaos_Scenarios disrupts nodes other packages use, set %s=1 to run it alone", node.ExclusiveEnv)
+	}
+
+	files, err := filepath.Glob("./scenarios/*.json")
+	if err != nil {
+		t.Fatalf("Test_Chaos_Scenarios list scenario files err: %s", err)
+	}
+
+	topology, network, err := node.Setup(common.TopologyFile)
+	if err != nil {
+		t.Fatalf("Test_Chaos_Scenarios setup topology err: %s", err)
+	}
+	defer node.Teardown(topology, network)
+
+	for _, file := range files {
+		s, err := scenario.Load(file)
+		if err != nil {
+			t.Fatalf("Test_Chaos_Scenarios %s", err)
+		}
+
+		t.Run(s.Name, func(t *testing.T) {
+			runner := scenario.NewRunner(t, topology, network)
+			if err := runner.Check(s); err != nil {
+				t.Skip(err)
+			}
+
+			runner.Run(s)
+		})
+	}
+}
@@ -1757,7 +1757,7 @@ func Test_Client_GetBlockTXCount_ByInvalidHeight0x(t *testing.T) {
 }
 
 func Test_Client_GetBlockTXCount_ByHeight_NodeStart(t *testing.T) {
-	cmd := exec.Command(common.CmdClient, "getblocktxcount", "--height", "1", "--address", common.ServerAddr)
+	cmd := exec.Command(common.CmdClient, "getblocktxcount", "--height", strconv.FormatUint(common.KnownHeight, 10), "--address", common.ServerAddr)
 	if res, err := cmd.CombinedOutput(); err != nil {
 		t.Fatalf("Test_Client_GetBlockTXCount_ByHeight: error, %s", err)
 	} else {
@@ -1846,7 +1846,7 @@ func Test_Client_GetBlockTXCount_ByInvalidHash123(t *testing.T) {
 
 func Test_Client_GetBlock_ByHeight_NodeStop(t *testing.T) {
 	// Normal height
-	cmd := exec.Command(common.CmdClient, "getblock", "--height", "1", "--address", common.ServerAddr)
+	cmd := exec.Command(common.CmdClient, "getblock", "--height", strconv.FormatUint(common.KnownHeight, 10), "--address", common.ServerAddr)
 	if _, err := cmd.CombinedOutput(); err != nil {
 		t.Fatalf("Test_Client_GetBlock_ByHeight_NodeStop: error, %s", err)
 	}
@@ -1854,7 +1854,7 @@ func Test_Client_GetBlock_ByHeight_NodeStop(t *testing.T) {
 
 func Test_Client_GetBlock_ByHeight_NodeStart(t *testing.T) {
 	// Normal height
-	cmd := exec.Command(common.CmdClient, "getblock", "--height", "1", "--address", common.ServerAddr)
+	cmd := exec.Command(common.CmdClient, "getblock", "--height", strconv.FormatUint(common.KnownHeight, 10), "--address", common.ServerAddr)
 	if res, err := cmd.CombinedOutput(); err != nil {
 		t.Fatalf("Test_Client_GetBlock_ByHeight_NodeStart:Node to run returns error: %s", err)
 	} else {
@@ -1864,7 +1864,7 @@ func Test_Client_GetBlock_ByHeight_NodeStart(t *testing.T) {
 		}
 
 		height := blockInfo.Header.Height
-		if height != 1 {
+		if height != common.KnownHeight {
 			t.Fatalf("Test_Client_GetBlock_ByHeight_NodeStart: Expect the return value is not correct!")
 		}
 
@@ -1928,7 +1928,7 @@ func Test_Client_GetBlock_ByInvalidHash(t *testing.T) {
 
 // getblock fulltx support.
 func Test_Client_GetBlock_ByHeightFulltx(t *testing.T) {
-	cmd := exec.Command(common.CmdClient, "getblock", "--height", "1", "--fulltx", "--address", common.ServerAddr)
+	cmd := exec.Command(common.CmdClient, "getblock", "--height", strconv.FormatUint(common.KnownHeight, 10), "--fulltx", "--address", common.ServerAddr)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		t.Fatalf("Test_Client_GetBlock_ByHeightFulltx error, %s", err)
 	} else {
@@ -1938,7 +1938,7 @@ func Test_Client_GetBlock_ByHeightFulltx(t *testing.T) {
 		}
 
 		height := blockInfo.Header.Height
-		if height != 1 {
+		if height != common.KnownHeight {
 			t.Fatalf("Test_Client_GetBlock_ByHeightFulltx: Expect the return value is not correct!")
 		}
 		if len(blockInfo.Transactions) <= 0 {
@@ -2256,7 +2256,7 @@ func Test_Client_SendManyTx(t *testing.T) {
 }
 
 func Test_Client_GetTxInBlock_ByHeightindex(t *testing.T) {
-	cmd := exec.Command(common.CmdClient, "gettxinblock", "--height", "1", "--index", "0")
+	cmd := exec.Command(common.CmdClient, "gettxinblock", "--height", strconv.FormatUint(common.KnownHeight, 10), "--index", "0")
 	_, err := cmd.CombinedOutput()
 	if err != nil {
 		t.Fatalf("Test_Client_GetTxInBlock_ByHeightindex err=%s", err)
@@ -2264,7 +2264,7 @@ func Test_Client_GetTxInBlock_ByHeightindex(t *testing.T) {
 }
 
 func Test_Client_GetTxInBlock_ByHeight(t *testing.T) {
-	cmd := exec.Command(common.CmdClient, "gettxinblock", "--height", "1")
+	cmd := exec.Command(common.CmdClient, "gettxinblock", "--height", strconv.FormatUint(common.KnownHeight, 10))
 	_, err := cmd.CombinedOutput()
 	if err != nil {
 		t.Fatalf("Test_Client_GetTxInBlock_ByHeight err=%s", err)
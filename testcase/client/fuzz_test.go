@@ -0,0 +1,430 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+var (
+	fuzzSeed = flag.Int64("fuzz.seed", 0, "seed of the generated cli arguments, random if 0")
+	fuzzRuns = flag.Int("fuzz.runs", 300, "number of generated cli calls")
+)
+
+const (
+	// fuzzTimeout bounds a cli call, a call running longer hangs
+	fuzzTimeout = 20 * time.Second
+	// fuzzMarker is the first amount of the fuzzed sendtx calls, the amounts
+	// identify the txs in the pool
+	fuzzMarker = 7700000
+	// fuzzNonceGap holds a fuzzed sendtx behind the sender nonce, so a tx that
+	// slips through stays in the pool instead of being mined
+	fuzzNonceGap = 500
+)
+
+type valueKind int
+
+const (
+	kindAddress valueKind = iota
+	kindHash
+	kindHex
+	kindUint
+	kindAmount
+	kindHeight
+	kindShard
+	kindPrivateKey
+	kindDomain
+	kindKeyFile
+	kindNewFile
+	kindABI
+	kindRPCAddr
+)
+
+// fuzzFlag is a flag the fuzzer generates values for. accepts lists the
+// values that look invalid for the kind but are valid for this flag, like an
+// empty filter or the empty receiver of a contract creation, they are never
+// generated as invalid values.
+type fuzzFlag struct {
+	name    string
+	kind    valueKind
+	accepts []string
+}
+
+// fuzzCommand is a cli subcommand and the flags the fuzzer generates values for.
+type fuzzCommand struct {
+	args  []string
+	flags []fuzzFlag
+	// rpc commands get the address of the shard 1 node
+	rpc bool
+	// invalidOnly commands change the node or send txs when the input is
+	// valid, or have no valid random input, only invalid values are generated
+	// for them
+	invalidOnly bool
+}
+
+// empty is accepted by flags whose empty value means not set
+var empty = []string{"", "0x"}
+
+// fuzzCommands cover the subcommands that take values. Left out are the
+// commands without value flags besides the address (getinfo, gettxpoolcount,
+// getpendingtxs and gettxpoolcontent behave like getdebts), those that stop
+// or reconfigure the node without a value to validate (miner start, miner
+// stop, dumpheap) and htlc withdraw and refund, which need an existing htlc
+// and are checked by the HTLC model.
+var fuzzCommands = []fuzzCommand{
+	{args: []string{"getbalance"}, flags: []fuzzFlag{{"account", kindAddress, nil}}, rpc: true},
+	{args: []string{"getnonce"}, flags: []fuzzFlag{{"account", kindAddress, nil}}, rpc: true},
+	{args: []string{"getshardnum"}, flags: []fuzzFlag{{"account", kindAddress, nil}}},
+	{args: []string{"getshardnum"}, flags: []fuzzFlag{{"privatekey", kindPrivateKey, nil}}},
+	// an empty hash is not set, the block is taken by height then
+	{args: []string{"getblock"}, flags: []fuzzFlag{{"height", kindHeight, nil}}, rpc: true},
+	{args: []string{"getblock"}, flags: []fuzzFlag{{"hash", kindHash, []string{""}}}, rpc: true},
+	{args: []string{"getblocktxcount"}, flags: []fuzzFlag{{"height", kindHeight, nil}}, rpc: true},
+	{args: []string{"getblocktxcount"}, flags: []fuzzFlag{{"hash", kindHash, []string{""}}}, rpc: true},
+	{args: []string{"gettxbyhash"}, flags: []fuzzFlag{{"hash", kindHash, nil}}, rpc: true},
+	{args: []string{"getreceipt"}, flags: []fuzzFlag{{"hash", kindHash, nil}}, rpc: true},
+	{args: []string{"getdebtbyhash"}, flags: []fuzzFlag{{"hash", kindHash, nil}}, rpc: true},
+	{args: []string{"getdebts"}, flags: []fuzzFlag{{"address", kindRPCAddr, []string{""}}}},
+	{args: []string{"gettxinblock"}, flags: []fuzzFlag{{"hash", kindHash, nil}, {"index", kindUint, nil}}, rpc: true},
+	// contract and topic are optional filters
+	{args: []string{"getlogs"}, flags: []fuzzFlag{{"height", kindHeight, nil}, {"contract", kindAddress, empty}, {"topic", kindHash, empty}}, rpc: true},
+	{args: []string{"key"}, flags: []fuzzFlag{{"shard", kindShard, nil}}},
+	{args: []string{"savekey"}, flags: []fuzzFlag{{"privatekey", kindPrivateKey, nil}, {"file", kindNewFile, nil}}},
+	{args: []string{"deckeyfile"}, flags: []fuzzFlag{{"file", kindKeyFile, nil}}},
+	{args: []string{"payload", "--method", "set", "--args", "10"}, flags: []fuzzFlag{{"abi", kindABI, nil}}},
+	// an empty receiver signs a contract creation
+	{args: []string{"sign"}, flags: []fuzzFlag{
+		{"privatekey", kindPrivateKey, nil}, {"to", kindAddress, empty}, {"amount", kindAmount, nil}, {"price", kindAmount, nil},
+		{"gas", kindUint, nil}, {"nonce", kindUint, nil}, {"payload", kindHex, nil},
+	}},
+	{args: []string{"miner", "setcoinbase"}, flags: []fuzzFlag{{"coinbase", kindAddress, nil}}, rpc: true, invalidOnly: true},
+	{args: []string{"miner", "setthreads"}, flags: []fuzzFlag{{"threads", kindUint, nil}}, rpc: true, invalidOnly: true},
+	{args: []string{"sendtx", "--from", common.KeyFileShard1_1}, flags: []fuzzFlag{
+		{"amount", kindAmount, nil}, {"price", kindAmount, nil}, {"gas", kindUint, nil}, {"to", kindAddress, empty}, {"nonce", kindUint, nil}, {"payload", kindHex, nil},
+	}, rpc: true, invalidOnly: true},
+	{args: []string{"domain", "register", "--from", common.KeyFileShard1_1, "--price", "15", "--gas", "200000"},
+		flags: []fuzzFlag{{"name", kindDomain, nil}}, rpc: true, invalidOnly: true},
+	{args: []string{"domain", "owner", "--from", common.KeyFileShard1_1, "--price", "15", "--gas", "200000"},
+		flags: []fuzzFlag{{"name", kindDomain, nil}}, rpc: true, invalidOnly: true},
+	{args: []string{"htlc", "create", "--from", common.KeyFileShard1_1, "--price", "15", "--gas", "200000"}, flags: []fuzzFlag{
+		{"to", kindAddress, nil}, {"amount", kindAmount, nil}, {"hash", kindHash, nil}, {"time", kindUint, nil},
+	}, rpc: true, invalidOnly: true},
+	// random bytes are no htlc, so there is no valid value to generate
+	{args: []string{"htlc", "decode"}, flags: []fuzzFlag{{"payload", kindHex, nil}}, invalidOnly: true},
+}
+
+var fuzzAccounts = []string{
+	common.AccountShard1_1, common.AccountShard1_2, common.AccountShard1_3, common.AccountShard1_4, common.AccountShard1_5,
+	common.AccountShard2_1, common.AccountShard2_2, common.AccountShard2_3, common.AccountShard2_4, common.AccountShard2_5,
+}
+
+// fuzzDir holds the keyfiles written by the fuzzed savekey calls
+var fuzzDir string
+
+func randomHex(r *rand.Rand, size int) string {
+	data := make([]byte, size)
+	r.Read(data)
+	return "0x" + hex.EncodeToString(data)
+}
+
+// corrupt returns an invalid variant of a valid 0x prefixed hex value.
+func corrupt(r *rand.Rand, value string) string {
+	variants := []func() string{
+		func() string { return "" },
+		func() string { return "0x" },
+		func() string { return strings.TrimPrefix(value, "0x") },
+		func() string { return value + "1" },
+		func() string { return value[:len(value)/2+1] },
+		func() string { return value + "abcd" },
+		func() string {
+			i := 2 + r.Intn(len(value)-2)
+			return value[:i] + string("gz-$ .:"[r.Intn(7)]) + value[i+1:]
+		},
+	}
+
+	return variants[r.Intn(len(variants))]()
+}
+
+func pick(r *rand.Rand, values ...string) string {
+	return values[r.Intn(len(values))]
+}
+
+// generate returns a random value for the flag, the valid ones are well
+// formed but not necessarily known to the node. Invalid values are never one
+// the flag accepts.
+func generate(r *rand.Rand, f fuzzFlag, valid bool) string {
+	for {
+		value := generateKind(r, f.kind, valid)
+		if valid || !accepted(f, value) {
+			return value
+		}
+	}
+}
+
+func accepted(f fuzzFlag, value string) bool {
+	for _, accept := range f.accepts {
+		if value == accept {
+			return true
+		}
+	}
+
+	return false
+}
+
+func generateKind(r *rand.Rand, kind valueKind, valid bool) string {
+	switch kind {
+	case kindAddress:
+		account := fuzzAccounts[r.Intn(len(fuzzAccounts))]
+		if valid {
+			return account
+		}
+		return corrupt(r, account)
+	case kindHash:
+		if valid {
+			return randomHex(r, 32)
+		}
+		return corrupt(r, randomHex(r, 32))
+	case kindPrivateKey:
+		if valid {
+			return common.AccountPrivateKey2
+		}
+		return corrupt(r, common.AccountPrivateKey2)
+	case kindHex:
+		if valid {
+			return randomHex(r, r.Intn(32))
+		}
+		return pick(r, "0x123", "0x12345-", "-1", "0xzz", "0x 12")
+	case kindUint:
+		if valid {
+			return strconv.Itoa(r.Intn(1000000))
+		}
+		return pick(r, "", "-1", "abc", "1.5", "1e3", "18446744073709551616", "0x")
+	case kindAmount:
+		// amounts and prices are big integers, a value beyond 64 bits is valid
+		if valid {
+			return strconv.Itoa(r.Intn(1000000))
+		}
+		return pick(r, "", "-1", "abc", "1.5", "1e3", "0x")
+	case kindHeight:
+		if valid {
+			return strconv.FormatUint(uint64(r.Int63n(int64(common.KnownHeight)+1)), 10)
+		}
+		return pick(r, "", "abc", "1.5", "9223372036854775808", "--1")
+	case kindShard:
+		if valid {
+			return strconv.Itoa(1 + r.Intn(common.CurShard))
+		}
+		return pick(r, "", "a", "-1", "1.5", "100")
+	case kindDomain:
+		// a name is stored in a hash, so it is 1 to 32 bytes long
+		if valid {
+			return randomHex(r, 1+r.Intn(15))[2:]
+		}
+		return pick(r, "", randomHex(r, 17)[2:]+"x")
+	case kindKeyFile:
+		if valid {
+			return common.KeyFileShard1_1
+		}
+		return pick(r, "", "../../config/keyfile/missing", "../../config/keyfile", "fuzz_test.go")
+	case kindNewFile:
+		if valid {
+			return filepath.Join(fuzzDir, strconv.Itoa(r.Int()))
+		}
+		return ""
+	case kindABI:
+		if valid {
+			return "../contract/simplestorage/SimpleStorage.abi"
+		}
+		return pick(r, "", "../contract/simplestorage/missing.abi", "fuzz_test.go", "../contract/simplestorage/SimpleStorage.bin")
+	case kindRPCAddr:
+		if valid {
+			return common.ServerAddr
+		}
+		return pick(r, "127.0.0.1", "127.0.0.1:", "127.0.0.1:99999", "127.0.0.1:-1", "host:port")
+	}
+
+	panic(fmt.Sprintf("unknown value kind %d", kind))
+}
+
+type fuzzCall struct {
+	args    []string
+	invalid bool
+	// amount is the marker of a sendtx call, 0 if the amount is fuzzed
+	amount int
+}
+
+func (c *fuzzCall) String() string {
+	quoted := make([]string, len(c.args))
+	for i, arg := range c.args {
+		quoted[i] = strconv.Quote(arg)
+	}
+
+	return "client " + strings.Join(quoted, " ")
+}
+
+// newCall generates a call of the command with one fuzzed flag, the other
+// flags get valid values.
+func newCall(r *rand.Rand, command fuzzCommand, id, nonce int) *fuzzCall {
+	call := &fuzzCall{args: append([]string{}, command.args...)}
+	call.invalid = command.invalidOnly || r.Intn(2) == 0
+	fuzzed := r.Intn(len(command.flags))
+	for i, f := range command.flags {
+		value := generate(r, f, !(call.invalid && i == fuzzed))
+		if command.args[0] == "sendtx" && i != fuzzed {
+			switch f.name {
+			case "amount":
+				call.amount = fuzzMarker + id
+				value = strconv.Itoa(call.amount)
+			case "nonce":
+				value = strconv.Itoa(nonce + fuzzNonceGap)
+			case "price":
+				value = "1"
+			case "gas":
+				value = "21000"
+			}
+		}
+
+		call.args = append(call.args, "--"+f.name, value)
+	}
+
+	if command.rpc {
+		call.args = append(call.args, "--address", common.ServerAddr)
+	}
+
+	return call
+}
+
+// minerCall calls a miner subcommand on the shard 1 node.
+func minerCall(args ...string) (string, error) {
+	args = append(append([]string{"miner"}, args...), "--address", common.ServerAddr)
+	output, err := exec.Command(common.CmdClient, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("miner %s err: %s %s", strings.Join(args, " "), err, output)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// restoreMiner sets the coinbase and threads back after a fuzzed miner call,
+// an invalid value the node accepts would reconfigure the shared miner.
+func restoreMiner(coinbase, threads string) error {
+	if _, err := minerCall("setcoinbase", "--coinbase", coinbase); err != nil {
+		return err
+	}
+
+	_, err := minerCall("setthreads", "--threads", threads)
+	return err
+}
+
+// run calls the cli with the keyfile password on stdin, twice for the
+// commands that ask to repeat it.
+func (c *fuzzCall) run() (string, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fuzzTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, common.CmdClient, c.args...)
+	cmd.Stdin = strings.NewReader("123\n123\n")
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return string(output), false, fmt.Errorf("no exit in %s", fuzzTimeout)
+	}
+
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return string(output), false, err
+		}
+	}
+
+	return string(output), err == nil, nil
+}
+
+// the cli never panics, exits with an error on every invalid value and sends
+// no tx for rejected input.
+func Test_Client_Fuzz_Validation(t *testing.T) {
+	seed := *fuzzSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	t.Logf("Test_Client_Fuzz_Validation seed %d, rerun with -fuzz.seed %d", seed, seed)
+	r := rand.New(rand.NewSource(seed))
+
+	dir, err := ioutil.TempDir("", "fuzz")
+	if err != nil {
+		t.Fatalf("Test_Client_Fuzz_Validation temp dir err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	fuzzDir = dir
+
+	nonce, err := common.GetNonce(t, common.CmdClient, common.AccountShard1_1, common.ServerAddr)
+	if err != nil {
+		t.Fatalf("Test_Client_Fuzz_Validation get nonce err: %s", err)
+	}
+
+	coinbase, err := minerCall("getcoinbase")
+	if err != nil {
+		t.Fatalf("Test_Client_Fuzz_Validation %s", err)
+	}
+
+	threads, err := minerCall("threads")
+	if err != nil {
+		t.Fatalf("Test_Client_Fuzz_Validation %s", err)
+	}
+
+	markers := make(map[int64]*fuzzCall)
+	for i := 0; i < *fuzzRuns; i++ {
+		call := newCall(r, fuzzCommands[r.Intn(len(fuzzCommands))], i, nonce)
+		output, ok, err := call.run()
+		if call.args[0] == "miner" {
+			if restoreErr := restoreMiner(coinbase, threads); restoreErr != nil {
+				t.Fatalf("Test_Client_Fuzz_Validation restore after %s: %s", call, restoreErr)
+			}
+		}
+
+		switch {
+		case err != nil:
+			t.Errorf("Test_Client_Fuzz_Validation %s err: %s", call, err)
+		case strings.Contains(output, "panic:") || strings.Contains(output, "goroutine "):
+			t.Errorf("Test_Client_Fuzz_Validation %s panics:\n%s", call, output)
+		case call.invalid && ok:
+			t.Errorf("Test_Client_Fuzz_Validation %s exits with 0 on an invalid value:\n%s", call, output)
+		}
+
+		if call.amount > 0 {
+			markers[int64(call.amount)] = call
+		}
+	}
+
+	if len(markers) == 0 {
+		return
+	}
+
+	content, err := common.GetPoolContentTxs(t, common.CmdClient, common.ServerAddr)
+	if err != nil {
+		t.Fatalf("Test_Client_Fuzz_Validation gettxpoolcontent err: %s", err)
+	}
+
+	for _, txs := range content {
+		for _, tx := range txs {
+			if call, ok := markers[tx.Amount]; ok {
+				t.Errorf("Test_Client_Fuzz_Validation %s is rejected but tx %s is in the pool", call, tx.Hash)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package common
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fundRetries is how often a funding is sent again when its nonce was taken
+// by a tx of another package sending from the same keyfile
+const fundRetries = 3
+
+var (
+	publicKeyRe  = regexp.MustCompile(`public key:\s*(0x[0-9a-fA-F]+)`)
+	privateKeyRe = regexp.MustCompile(`private key:\s*(0x[0-9a-fA-F]+)`)
+)
+
+// NewKeyFile generates a key of the shard and saves it into dir with the
+// password of the committed keyfiles. The account is unknown to every other
+// test, so only the caller's txs change its balance.
+func NewKeyFile(t *testing.T, command string, shard uint, dir string) (keyfile, account string, err error) {
+	output, err := exec.Command(command, "key", "--shard", strconv.FormatUint(uint64(shard), 10)).CombinedOutput()
+	if err != nil {
+		return "", "", fmt.Errorf("key --shard %d err: %s %s", shard, err, output)
+	}
+
+	public, private := publicKeyRe.FindSubmatch(output), privateKeyRe.FindSubmatch(output)
+	if public == nil || private == nil {
+		return "", "", fmt.Errorf("key --shard %d prints no key: %s", shard, output)
+	}
+
+	account = strings.ToLower(string(public[1]))
+	keyfile = filepath.Join(dir, fmt.Sprintf("shard%d-%s", shard, account))
+	cmd := exec.Command(command, "savekey", "--privatekey", string(private[1]), "--file", keyfile)
+	cmd.Stdin = strings.NewReader("123\n123\n")
+	if output, err = cmd.CombinedOutput(); err != nil {
+		return "", "", fmt.Errorf("savekey err: %s %s", err, output)
+	}
+
+	return keyfile, account, nil
+}
+
+// FundAccounts sends amount from the keyfile account to every account, one
+// tx at a time, and waits until each is mined.
+func FundAccounts(t *testing.T, command, keyfile, from string, amount int, serverAddr string, to ...string) error {
+	for _, account := range to {
+		var txHash string
+		for i := 0; ; i++ {
+			nonce, err := GetNonce(t, command, from, serverAddr)
+			if err != nil {
+				return err
+			}
+
+			if txHash, _, err = SendTx(t, command, amount, nonce, 0, keyfile, account, "", serverAddr); err == nil {
+				break
+			}
+
+			if i == fundRetries {
+				return fmt.Errorf("fund %s err: %s", account, err)
+			}
+		}
+
+		receipt, err := WaitReceipt(t, command, txHash, serverAddr)
+		if err != nil {
+			return err
+		}
+
+		if receipt.Failed {
+			return fmt.Errorf("fund %s failed: %s", account, receipt.Result)
+		}
+	}
+
+	return nil
+}
@@ -132,6 +132,31 @@ type SendTxInfo struct {
 	BMined  bool
 }
 
+// DebtData is the transfer a debt makes on the target shard
+type DebtData struct {
+	TxHash  string `json:"TxHash"`
+	From    string `json:"From"`
+	Nonce   uint64 `json:"Nonce"`
+	Account string `json:"Account"`
+	Amount  int64  `json:"Amount"`
+	Price   int64  `json:"Price"`
+	Code    string `json:"Code"`
+}
+
+// DebtInfo debt of a cross shard tx
+type DebtInfo struct {
+	Hash string   `json:"Hash"`
+	Data DebtData `json:"Data"`
+}
+
+// DebtByHashInfo output of getdebtbyhash, the block is empty while the debt is pending
+type DebtByHashInfo struct {
+	Status      string   `json:"status"`
+	BlockHash   string   `json:"blockHash"`
+	BlockHeight uint64   `json:"blockHeight"`
+	Debt        DebtInfo `json:"debt"`
+}
+
 // LogByTopic contains Seele log
 type LogByTopic struct {
 	Log      Log    `json:"log"`
@@ -189,6 +214,22 @@ func GetBalance(t *testing.T, command, account, serverAddr string) (int64, error
 	return info.Balance, nil
 }
 
+// GetShardNum returns the shard of the account, read from the getshardnum output like "shard number: 1"
+func GetShardNum(t *testing.T, command, account string) (uint, error) {
+	output, err := exec.Command(command, "getshardnum", "--account", account).CombinedOutput()
+	if err != nil {
+		return 0, errors.New(string(bytes.TrimSpace(output)))
+	}
+
+	output = bytes.TrimSpace(output)
+	if idx := bytes.LastIndexByte(output, ' '); idx >= 0 {
+		output = output[idx+1:]
+	}
+
+	shard, err := strconv.ParseUint(string(output), 10, 32)
+	return uint(shard), err
+}
+
 func GetBlock(t *testing.T, command string, height int64, serverAddr string) (ret *BlockInfo, err error) {
 	cmd := exec.Command(command, "getblock", "--height", strconv.FormatInt(height, 10), "--address", serverAddr)
 	output, err := cmd.CombinedOutput()
@@ -205,6 +246,23 @@ func GetBlock(t *testing.T, command string, height int64, serverAddr string) (re
 	return
 }
 
+// GetBlockTxs returns the txs of the block at height with their sender and price
+func GetBlockTxs(t *testing.T, command string, height int64, serverAddr string) ([]TxInfoInBlock, error) {
+	output, err := exec.Command(command, "getblock", "--height", strconv.FormatInt(height, 10), "--fulltx", "--address", serverAddr).CombinedOutput()
+	if err != nil {
+		return nil, errors.New(string(bytes.TrimSpace(output)))
+	}
+
+	var block struct {
+		Transactions []TxInfoInBlock `json:"transactions"`
+	}
+	if err = json.Unmarshal(output, &block); err != nil {
+		return nil, err
+	}
+
+	return block.Transactions, nil
+}
+
 func GetNonce(t *testing.T, command, account, serverAddr string) (int, error) {
 	cmd := exec.Command(command, "getnonce", "--account", account, "--address", serverAddr)
 	//var curNonce int
@@ -220,15 +278,20 @@ func GetNonce(t *testing.T, command, account, serverAddr string) (int, error) {
 
 // SendTx send a tx
 func SendTx(t *testing.T, command string, amount, nonce, gaslimit int, keystore, to, payload, serverAddr string) (txHash, debtHash string, err error) {
+	return SendTxWithPrice(t, command, amount, 1, nonce, gaslimit, keystore, to, payload, serverAddr)
+}
+
+// SendTxWithPrice send a tx with the gas price
+func SendTxWithPrice(t *testing.T, command string, amount, price, nonce, gaslimit int, keystore, to, payload, serverAddr string) (txHash, debtHash string, err error) {
 	if gaslimit <= 0 {
 		gaslimit = 3000000
 	}
 
 	var cmd *exec.Cmd
 	if payload == "" || payload == "0x" {
-		cmd = exec.Command(command, "sendtx", "--amount", strconv.Itoa(amount), "--price", "1", "--gas", strconv.Itoa(gaslimit), "--from", keystore, "--to", to, "--nonce", strconv.Itoa(nonce))
+		cmd = exec.Command(command, "sendtx", "--amount", strconv.Itoa(amount), "--price", strconv.Itoa(price), "--gas", strconv.Itoa(gaslimit), "--from", keystore, "--to", to, "--nonce", strconv.Itoa(nonce), "--address", serverAddr)
 	} else {
-		cmd = exec.Command(command, "sendtx", "--amount", strconv.Itoa(amount), "--price", "1", "--gas", strconv.Itoa(gaslimit), "--from", keystore, "--to", to, "--nonce", strconv.Itoa(nonce), "--payload", payload)
+		cmd = exec.Command(command, "sendtx", "--amount", strconv.Itoa(amount), "--price", strconv.Itoa(price), "--gas", strconv.Itoa(gaslimit), "--from", keystore, "--to", to, "--nonce", strconv.Itoa(nonce), "--payload", payload, "--address", serverAddr)
 	}
 
 	stdin, err := cmd.StdinPipe()
@@ -257,7 +320,19 @@ func SendTx(t *testing.T, command string, amount, nonce, gaslimit int, keystore,
 
 	// fmt.Println("sendtx nonce=", nonce)
 
-	outStr = outStr[strings.Index(outStr, "{"):]
+	// a cross shard tx prints its debt after the tx
+	debtStr := ""
+	if idx := strings.Index(outStr, DebtPrompt); idx >= 0 {
+		outStr, debtStr = outStr[:idx], outStr[idx+len(DebtPrompt):]
+	}
+
+	start := strings.Index(outStr, "{")
+	if start < 0 {
+		err = fmt.Errorf("sendtx output is not json: %s", outStr)
+		return
+	}
+
+	outStr = outStr[start:]
 	outStr = strings.Trim(outStr, "\n")
 	outStr = strings.Trim(outStr, " ")
 	// fmt.Println("sendtx out:[", outStr, "]")
@@ -267,9 +342,65 @@ func SendTx(t *testing.T, command string, amount, nonce, gaslimit int, keystore,
 	}
 
 	txHash = txInfo.Hash
+	if debtStr == "" {
+		return
+	}
+
+	var debt DebtInfo
+	if err = json.Unmarshal([]byte(strings.TrimSpace(debtStr)), &debt); err != nil {
+		return
+	}
+
+	debtHash = debt.Hash
 	return
 }
 
+// GetDebtByHash returns the debt from the node of its target shard
+func GetDebtByHash(t *testing.T, command, debtHash, serverAddr string) (*DebtByHashInfo, error) {
+	output, err := exec.Command(command, "getdebtbyhash", "--hash", debtHash, "--address", serverAddr).CombinedOutput()
+	if err != nil {
+		return nil, errors.New(string(bytes.TrimSpace(output)))
+	}
+
+	var info DebtByHashInfo
+	if err = json.Unmarshal(output, &info); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// GetDebts returns the pending debts of the node
+func GetDebts(t *testing.T, command, serverAddr string) ([]DebtInfo, error) {
+	output, err := exec.Command(command, "getdebts", "--address", serverAddr).CombinedOutput()
+	if err != nil {
+		return nil, errors.New(string(bytes.TrimSpace(output)))
+	}
+
+	var debts []DebtInfo
+	if err = json.Unmarshal(output, &debts); err != nil {
+		return nil, err
+	}
+
+	return debts, nil
+}
+
+// GetLogs returns the logs of the contract with the topic in the block at height
+func GetLogs(t *testing.T, command string, height int, contract, topic, serverAddr string) ([]LogByTopic, error) {
+	output, err := exec.Command(command, "getlogs", "--height", strconv.Itoa(height), "--contract", contract,
+		"--topic", topic, "--address", serverAddr).CombinedOutput()
+	if err != nil {
+		return nil, errors.New(string(bytes.TrimSpace(output)))
+	}
+
+	var logs []LogByTopic
+	if err = json.Unmarshal(output, &logs); err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
+
 func GetPendingTxs(t *testing.T, command, serverAddr string) (infoL []PoolTxInfo, err error) {
 	var output []byte
 	cmd := exec.Command(command, "getpendingtxs", "--address", serverAddr)
@@ -390,6 +521,40 @@ func FindTxHashFromPool(txHash string, infoL *[]PoolTxInfo, infoM *map[string][]
 	return
 }
 
+// RunWithPassword runs a command that asks for the keyfile password and
+// parses the json it prints after the prompt into info.
+func RunWithPassword(command, password string, info interface{}, args ...string) error {
+	cmd := exec.Command(command, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	defer stdin.Close()
+
+	var out bytes.Buffer
+	var outErr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &outErr
+
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+
+	io.WriteString(stdin, password+"\n")
+	cmd.Wait()
+
+	output, errStr := out.String(), outErr.String()
+	if errStr != "" {
+		return errors.New(errStr)
+	}
+
+	start, end := strings.Index(output, "{"), strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return fmt.Errorf("%s output is not json: %s", strings.Join(args, " "), output)
+	}
+
+	return json.Unmarshal([]byte(output[start:end+1]), info)
+}
+
 // GenerateTime generate time
 func GenerateTime(minutes int64) int64 {
 	return time.Now().Unix() + minutes*60
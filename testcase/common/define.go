@@ -9,12 +9,22 @@ var (
 	CurShard  int    = 2
 	CmdClient string = "../../bin/client"
 	CmdLight  string = "../../bin/light"
+	CmdNode   string = "../../bin/node"
 
 	ServerAddr string = "127.0.0.1:8027"
 	AccountErr string = "0xaaaaaaaaaaaaaaaaa"
 
 	ServertwoAddr string = "127.0.0.1:8028"
 
+	// WSAddr and WStwoAddr are the wsserver addresses of the shard 1 and shard 2 nodes
+	WSAddr    string = "127.0.0.1:8046"
+	WStwoAddr string = "127.0.0.1:8047"
+
+	// TopologyFile describes the nodes managed by the harness, optional
+	TopologyFile string = "../../config/topology.json"
+	// FixtureFile overrides KnownHeight and BlockHash, optional
+	FixtureFile string = "../../config/fixture.json"
+
 	Account1_Aux  string = "0x7c00f5a4312a6a3e458a07c2d650ce13c76b68b1"
 	Account1_Aux2 string = "0xa00d22dc3624d4696eff8d1641b442f79c3379b1" // account for shard1
 
@@ -26,6 +36,7 @@ var (
 	InvalidAccountType string = "0xff0fb1e59e92e94fac74febec98cfd58b956fa6f" // account type == 15, invalid
 	AccountShard2             = [...]string{"0xc910e52e3a314c93fdf545b88d264f39becb8d41", "0xff0fb1e59e92e94fac74febec98cfd58b956fa61"}
 
+	KnownHeight  uint64 = 1
 	BlockHash    string = "0x00000025b4c24f12eb3a050cb9e3ece1e3fe9ce368ba9d2d514e3de39b88f930"
 	BlockHashErr string = "0x88aad2ac0921f7784d0d3f6d7865e48ec0e454dbd7dc60e4ecf6eaa08c548410"
 )
@@ -73,4 +84,11 @@ const (
 	Secretehash  = "0x57e685963f607851af252e7922483a61fbceced12accd745444f412295517768"
 
 	FlagErr = "flag is not specified for value"
+
+	// price and gas of the txs calling system contracts like htlc and domain
+	SystemTxPrice = 15
+	SystemTxGas   = 200000
+
+	// DebtPrompt is printed by sendtx before the debt of a cross shard tx
+	DebtPrompt = "It is a cross shard transaction, its debt is:"
 )
@@ -0,0 +1,36 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package common
+
+import (
+	"strconv"
+	"testing"
+)
+
+// DomainRegister registers the domain name for the owner of the keyfile.
+func DomainRegister(t *testing.T, command, keyfile, name, serverAddr string) (*TxInfo, error) {
+	var tx TxInfo
+	err := RunWithPassword(command, "123", &tx, "domain", "register", "--from", keyfile, "--price", strconv.Itoa(SystemTxPrice),
+		"--gas", strconv.Itoa(SystemTxGas), "--name", name, "--address", serverAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tx, nil
+}
+
+// DomainOwner sends a tx that looks up the owner of the domain name, the
+// owner is in the result of its receipt.
+func DomainOwner(t *testing.T, command, keyfile, name, serverAddr string) (*TxInfo, error) {
+	var tx TxInfo
+	err := RunWithPassword(command, "123", &tx, "domain", "owner", "--from", keyfile, "--price", strconv.Itoa(SystemTxPrice),
+		"--gas", strconv.Itoa(SystemTxGas), "--name", name, "--address", serverAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tx, nil
+}
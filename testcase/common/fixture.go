@@ -0,0 +1,72 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// Fixture is the known state of the chain the suite runs against. It is
+// recorded by `run fixture record` after the nodes were started from a
+// genesis generated by `run fixture build`.
+type Fixture struct {
+	Shard       uint              `json:"shard"`
+	Difficulty  int64             `json:"difficulty"`
+	Timestamp   int64             `json:"timestamp"`
+	GenesisHash string            `json:"genesisHash"`
+	Blocks      map[uint64]string `json:"blocks"`
+	Accounts    map[string]int64  `json:"accounts"`
+}
+
+// Chain is the loaded fixture, nil if no fixture is recorded.
+var Chain *Fixture
+
+func init() {
+	fixture, err := LoadFixture(FixtureFile)
+	if os.IsNotExist(err) {
+		return
+	}
+
+	// a fixture that is recorded but unreadable must not silently fall back
+	// to the constants of define.go
+	if err != nil {
+		panic(fmt.Sprintf("load fixture %s err: %s", FixtureFile, err))
+	}
+
+	Chain = fixture
+	if height, hash, ok := fixture.KnownBlock(); ok {
+		KnownHeight, BlockHash = height, hash
+	}
+}
+
+// LoadFixture loads a fixture file.
+func LoadFixture(file string) (*Fixture, error) {
+	bytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture Fixture
+	if err = json.Unmarshal(bytes, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixture file %s: %s", file, err)
+	}
+
+	return &fixture, nil
+}
+
+// KnownBlock returns the lowest recorded block above genesis.
+func (f *Fixture) KnownBlock() (height uint64, hash string, ok bool) {
+	for h, blockHash := range f.Blocks {
+		if h > 0 && (!ok || h < height) {
+			height, hash, ok = h, blockHash, true
+		}
+	}
+
+	return
+}
@@ -0,0 +1,100 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package common
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// ReceiptTimeout bounds WaitReceipt
+const ReceiptTimeout = 3 * time.Minute
+
+// NewSecret returns a random preimage and its hash lock, both 0x prefixed.
+func NewSecret() (preimage, hash string) {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	sum := sha256.Sum256(secret)
+	return "0x" + hex.EncodeToString(secret), "0x" + hex.EncodeToString(sum[:])
+}
+
+// HTLCCreate locks amount for to with the hash lock until locktime.
+func HTLCCreate(t *testing.T, command, keyfile, to string, amount int64, hash string, locktime int64, serverAddr string) (*HTLCCreateInfo, error) {
+	var info HTLCCreateInfo
+	err := runHTLC(command, &info, "create", "--from", keyfile, "--to", to, "--amount", strconv.FormatInt(amount, 10),
+		"--price", strconv.Itoa(SystemTxPrice), "--gas", strconv.Itoa(SystemTxGas), "--hash", hash, "--time", strconv.FormatInt(locktime, 10), "--address", serverAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// HTLCWithdraw reveals the preimage to withdraw the htlc created by the tx hash.
+func HTLCWithdraw(t *testing.T, command, keyfile, hash, preimage, serverAddr string) (*HTLCWithDrawInfo, error) {
+	var info HTLCWithDrawInfo
+	err := runHTLC(command, &info, "withdraw", "--from", keyfile, "--price", strconv.Itoa(SystemTxPrice), "--gas", strconv.Itoa(SystemTxGas),
+		"--hash", hash, "--preimage", preimage, "--address", serverAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// HTLCRefund refunds the htlc created by the tx hash to its owner.
+func HTLCRefund(t *testing.T, command, keyfile, hash, serverAddr string) (*HTLCRefundInfo, error) {
+	var info HTLCRefundInfo
+	err := runHTLC(command, &info, "refund", "--from", keyfile, "--price", strconv.Itoa(SystemTxPrice), "--gas", strconv.Itoa(SystemTxGas),
+		"--hash", hash, "--address", serverAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+func runHTLC(command string, info interface{}, args ...string) error {
+	return RunWithPassword(command, "123", info, append([]string{"htlc"}, args...)...)
+}
+
+// WaitReceipt polls the receipt of the tx until it is mined.
+func WaitReceipt(t *testing.T, command, txHash, serverAddr string) (*ReceiptInfo, error) {
+	deadline := time.Now().Add(ReceiptTimeout)
+	for {
+		receipt, err := GetReceipt(t, command, txHash, serverAddr)
+		if err == nil {
+			return receipt, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("tx %s is not mined in %s, err: %s", txHash, ReceiptTimeout, err)
+		}
+
+		time.Sleep(time.Second)
+	}
+}
+
+// GetTxBlockTime returns the timestamp of the block that includes the tx,
+// which is the time contracts see when the tx is executed.
+func GetTxBlockTime(t *testing.T, command, txHash, serverAddr string) (int64, error) {
+	tx, err := GetTxByHash(t, command, txHash, serverAddr)
+	if err != nil {
+		return 0, err
+	}
+
+	block, err := GetBlock(t, command, int64(tx.Height), serverAddr)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(block.Header.CreateTimestamp), nil
+}
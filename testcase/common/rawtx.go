@@ -0,0 +1,88 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// SignedTx is a tx signed offline by the sign command, Raw is the tx as
+// printed and submitted to the node
+type SignedTx struct {
+	Hash string
+	Raw  json.RawMessage
+}
+
+// SignTx signs a tx offline with the private key
+func SignTx(t *testing.T, command, privateKey, to string, amount, price, gaslimit, nonce int, payload string) (*SignedTx, error) {
+	args := []string{"sign", "--privatekey", privateKey, "--to", to, "--amount", strconv.Itoa(amount), "--price", strconv.Itoa(price),
+		"--gas", strconv.Itoa(gaslimit), "--nonce", strconv.Itoa(nonce)}
+	if payload != "" {
+		args = append(args, "--payload", payload)
+	}
+
+	output, err := exec.Command(command, args...).CombinedOutput()
+	if err != nil {
+		return nil, errors.New(string(bytes.TrimSpace(output)))
+	}
+
+	idx := bytes.IndexByte(output, '{')
+	if idx < 0 {
+		return nil, fmt.Errorf("sign prints no tx: %s", bytes.TrimSpace(output))
+	}
+
+	var tx TxInfo
+	if err = json.Unmarshal(output[idx:], &tx); err != nil {
+		return nil, err
+	}
+
+	return &SignedTx{Hash: tx.Hash, Raw: json.RawMessage(bytes.TrimSpace(output[idx:]))}, nil
+}
+
+// AddTx submits a signed tx to the node with the seele_addTx rpc, the cli
+// has no command to send a raw tx
+func AddTx(serverAddr string, tx json.RawMessage) error {
+	conn, err := net.DialTimeout("tcp", serverAddr, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(20 * time.Second))
+
+	request := map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "seele_addTx", "params": []json.RawMessage{tx}}
+	if err = json.NewEncoder(conn).Encode(request); err != nil {
+		return err
+	}
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err = json.NewDecoder(conn).Decode(&response); err != nil {
+		return err
+	}
+
+	if response.Error != nil {
+		return errors.New(response.Error.Message)
+	}
+
+	if string(response.Result) == "false" {
+		return errors.New("seele_addTx returns false")
+	}
+
+	return nil
+}
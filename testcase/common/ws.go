@@ -0,0 +1,380 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package common
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID is appended to the key of the handshake, RFC 6455 section 1.3
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xa
+)
+
+// ErrWSClosed is returned for calls on a closed connection
+var ErrWSClosed = errors.New("websocket connection closed")
+
+// rpcMessage is a json rpc response or subscription notification
+type rpcMessage struct {
+	ID     *uint64         `json:"id"`
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Params struct {
+		Subscription string          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	} `json:"params"`
+}
+
+// Subscription receives the notifications of a subscription in the order the
+// node sends them, C is closed with the connection
+type Subscription struct {
+	ID string
+	C  chan json.RawMessage
+}
+
+// WSClient is a json rpc client over a websocket connection
+type WSClient struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	writeMu sync.Mutex
+
+	mutex   sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan *rpcMessage
+	subs    map[string]*Subscription
+	// early holds notifications that arrive before their subscribe response
+	early  map[string][]json.RawMessage
+	closed bool
+	err    error
+}
+
+// DialWS opens a websocket connection to the wsserver at addr
+func DialWS(addr string) (*WSClient, error) {
+	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	request := "GET / HTTP/1.1\r\nHost: " + addr + "\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\nSec-WebSocket-Version: 13\r\nOrigin: http://" + addr + "\r\n\r\n"
+	if _, err = io.WriteString(conn, request); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake with %s returns %s", addr, response.Status)
+	}
+
+	accept := sha1.Sum([]byte(key + websocketGUID))
+	if response.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(accept[:]) {
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake with %s returns an invalid accept key", addr)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return newWSClient(conn, reader), nil
+}
+
+// newWSClient serves the json rpc over an upgraded connection, reader buffers
+// what follows the handshake response
+func newWSClient(conn net.Conn, reader *bufio.Reader) *WSClient {
+	c := &WSClient{
+		conn:    conn,
+		reader:  reader,
+		pending: make(map[uint64]chan *rpcMessage),
+		subs:    make(map[string]*Subscription),
+		early:   make(map[string][]json.RawMessage),
+	}
+	go c.loop()
+	return c
+}
+
+// Call calls the rpc method and decodes its result into result if not nil
+func (c *WSClient) Call(result interface{}, method string, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return ErrWSClosed
+	}
+	c.nextID++
+	id := c.nextID
+	done := make(chan *rpcMessage, 1)
+	c.pending[id] = done
+	c.mutex.Unlock()
+
+	request, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+	if err == nil {
+		err = c.write(opText, request)
+	}
+	if err != nil {
+		c.mutex.Lock()
+		delete(c.pending, id)
+		c.mutex.Unlock()
+		return err
+	}
+
+	var response *rpcMessage
+	select {
+	case response = <-done:
+	case <-time.After(20 * time.Second):
+		c.mutex.Lock()
+		delete(c.pending, id)
+		c.mutex.Unlock()
+		return fmt.Errorf("%s returns no response in 20s", method)
+	}
+
+	if response == nil {
+		return ErrWSClosed
+	}
+
+	if response.Error != nil {
+		return errors.New(response.Error.Message)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(response.Result, result)
+}
+
+// Subscribe calls seele_subscribe with the kind, like newHeads, logs or
+// newPendingTransactions, and its filter params
+func (c *WSClient) Subscribe(kind string, params ...interface{}) (*Subscription, error) {
+	// the notifications may arrive before the response, loop keeps them by id
+	var id string
+	if err := c.Call(&id, "seele_subscribe", append([]interface{}{kind}, params...)...); err != nil {
+		return nil, err
+	}
+
+	sub := &Subscription{ID: id, C: make(chan json.RawMessage, 1024)}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		close(sub.C)
+		return sub, nil
+	}
+
+	for _, result := range c.early[id] {
+		sub.C <- result
+	}
+	delete(c.early, id)
+	c.subs[id] = sub
+	return sub, nil
+}
+
+// Unsubscribe cancels the subscription, its channel receives nothing after
+func (c *WSClient) Unsubscribe(sub *Subscription) error {
+	var ok bool
+	if err := c.Call(&ok, "seele_unsubscribe", sub.ID); err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("seele_unsubscribe of %s returns false", sub.ID)
+	}
+
+	c.mutex.Lock()
+	if c.subs[sub.ID] == sub {
+		delete(c.subs, sub.ID)
+		close(sub.C)
+	}
+	c.mutex.Unlock()
+	return nil
+}
+
+// Err returns why the connection was closed, nil while it is open
+func (c *WSClient) Err() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.err
+}
+
+// Close sends a close frame and closes the connection
+func (c *WSClient) Close() error {
+	c.write(opClose, []byte{0x03, 0xe8})
+	return c.conn.Close()
+}
+
+// write sends a single masked frame, clients always mask, RFC 6455 section 5.3
+func (c *WSClient) write(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, 0x80|byte(n))
+	case n <= 0xffff:
+		header = append(header, 0x80|126, byte(n>>8), byte(n))
+	default:
+		header = append(header, 0x80|127)
+		header = append(header, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(header[len(header)-8:], uint64(n))
+	}
+
+	mask := make([]byte, 4)
+	rand.Read(mask)
+	frame := append(append(header, mask...), payload...)
+	for i := range payload {
+		frame[len(header)+4+i] ^= mask[i%4]
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// readMessage reads the frames of the next data message, answering pings
+func (c *WSClient) readMessage() ([]byte, error) {
+	var message []byte
+	for {
+		var head [2]byte
+		if _, err := io.ReadFull(c.reader, head[:]); err != nil {
+			return nil, err
+		}
+
+		final, opcode := head[0]&0x80 != 0, head[0]&0x0f
+		size := uint64(head[1] & 0x7f)
+		switch size {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+				return nil, err
+			}
+			size = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+				return nil, err
+			}
+			size = binary.BigEndian.Uint64(ext[:])
+		}
+
+		var mask []byte
+		if head[1]&0x80 != 0 {
+			mask = make([]byte, 4)
+			if _, err := io.ReadFull(c.reader, mask); err != nil {
+				return nil, err
+			}
+		}
+
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(c.reader, payload); err != nil {
+			return nil, err
+		}
+		for i := range mask {
+			for j := i; j < len(payload); j += 4 {
+				payload[j] ^= mask[i]
+			}
+		}
+
+		switch opcode {
+		case opClose:
+			return nil, io.EOF
+		case opPing:
+			c.write(opPong, payload)
+			continue
+		case opPong:
+			continue
+		}
+
+		message = append(message, payload...)
+		if final {
+			return message, nil
+		}
+	}
+}
+
+// loop dispatches the responses and notifications until the connection ends
+func (c *WSClient) loop() {
+	var err error
+	for {
+		var data []byte
+		if data, err = c.readMessage(); err != nil {
+			break
+		}
+
+		var message rpcMessage
+		if json.Unmarshal(data, &message) != nil {
+			continue
+		}
+
+		c.mutex.Lock()
+		switch {
+		case message.ID != nil:
+			if done, ok := c.pending[*message.ID]; ok {
+				delete(c.pending, *message.ID)
+				done <- &message
+			}
+		case strings.HasSuffix(message.Method, "_subscription"):
+			id := message.Params.Subscription
+			if sub, ok := c.subs[id]; ok {
+				select {
+				case sub.C <- message.Params.Result:
+				default:
+					// a full channel means the reader is gone, the connection is dropped
+					// instead of losing notifications silently
+					c.conn.Close()
+				}
+			} else {
+				c.early[id] = append(c.early[id], message.Params.Result)
+			}
+		}
+		c.mutex.Unlock()
+	}
+
+	c.mutex.Lock()
+	c.closed, c.err = true, err
+	for id, done := range c.pending {
+		close(done)
+		delete(c.pending, id)
+	}
+	for id, sub := range c.subs {
+		close(sub.C)
+		delete(c.subs, id)
+	}
+	c.mutex.Unlock()
+	c.conn.Close()
+}
@@ -0,0 +1,333 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// frame is a frame as the server sees it, payload is unmasked
+type frame struct {
+	final   bool
+	opcode  byte
+	masked  bool
+	size    uint64
+	header  int
+	payload []byte
+}
+
+// readFrame reads a frame the client wrote
+func readFrame(r io.Reader) (*frame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, err
+	}
+
+	f := &frame{final: head[0]&0x80 != 0, opcode: head[0] & 0x0f, masked: head[1]&0x80 != 0, header: 2}
+	f.size = uint64(head[1] & 0x7f)
+	switch f.size {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return nil, err
+		}
+		f.size, f.header = uint64(binary.BigEndian.Uint16(ext[:])), 4
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return nil, err
+		}
+		f.size, f.header = binary.BigEndian.Uint64(ext[:]), 10
+	}
+
+	var mask [4]byte
+	if f.masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return nil, err
+		}
+	}
+
+	f.payload = make([]byte, f.size)
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return nil, err
+	}
+	for i := range f.payload {
+		f.payload[i] ^= mask[i%4]
+	}
+
+	return f, nil
+}
+
+// serverFrame encodes a frame as the server writes it, masked only if mask is set
+func serverFrame(final bool, opcode byte, mask []byte, payload []byte) []byte {
+	first := opcode
+	if final {
+		first |= 0x80
+	}
+
+	var maskBit byte
+	if mask != nil {
+		maskBit = 0x80
+	}
+
+	header := []byte{first}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, maskBit|byte(n))
+	case n <= 0xffff:
+		header = append(header, maskBit|126, byte(n>>8), byte(n))
+	default:
+		header = append(header, maskBit|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	data := append(append(header, mask...), payload...)
+	for i := range mask {
+		for j := len(header) + len(mask) + i; j < len(data); j += 4 {
+			data[j] ^= mask[i]
+		}
+	}
+
+	return data
+}
+
+func pattern(size int) []byte {
+	payload := make([]byte, size)
+	for i := range payload {
+		payload[i] = byte(i % 251)
+	}
+
+	return payload
+}
+
+func Test_WSClient_Write(t *testing.T) {
+	cases := []struct {
+		name   string
+		size   int
+		header int
+	}{
+		{"empty", 0, 2},
+		{"7 bit length", 125, 2},
+		{"16 bit length lower bound", 126, 4},
+		{"16 bit length upper bound", 0xffff, 4},
+		{"64 bit length", 0x10000, 10},
+	}
+
+	for _, c := range cases {
+		client, server := net.Pipe()
+		ws := &WSClient{conn: client}
+		payload := pattern(c.size)
+
+		done := make(chan error, 1)
+		go func() { done <- ws.write(opText, payload) }()
+
+		f, err := readFrame(server)
+		if err != nil {
+			t.Fatalf("Test_WSClient_Write %s read frame err: %s", c.name, err)
+		}
+
+		if err = <-done; err != nil {
+			t.Fatalf("Test_WSClient_Write %s write err: %s", c.name, err)
+		}
+
+		if !f.final || f.opcode != opText || !f.masked {
+			t.Fatalf("Test_WSClient_Write %s frame is final %v opcode %d masked %v, expected a final masked text frame",
+				c.name, f.final, f.opcode, f.masked)
+		}
+
+		if f.header != c.header || f.size != uint64(c.size) {
+			t.Fatalf("Test_WSClient_Write %s header is %d bytes for length %d, expected %d bytes for length %d",
+				c.name, f.header, f.size, c.header, c.size)
+		}
+
+		if !bytes.Equal(f.payload, payload) {
+			t.Fatalf("Test_WSClient_Write %s unmasked payload differs from the sent one", c.name)
+		}
+
+		client.Close()
+		server.Close()
+	}
+}
+
+func Test_WSClient_ReadMessage(t *testing.T) {
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	cases := []struct {
+		name    string
+		frames  [][]byte
+		message []byte
+	}{
+		{"7 bit length", [][]byte{serverFrame(true, opText, nil, pattern(125))}, pattern(125)},
+		{"16 bit length", [][]byte{serverFrame(true, opText, nil, pattern(126))}, pattern(126)},
+		{"64 bit length", [][]byte{serverFrame(true, opText, nil, pattern(0x10001))}, pattern(0x10001)},
+		{"masked", [][]byte{serverFrame(true, opText, mask, pattern(300))}, pattern(300)},
+		{"fragmented", [][]byte{
+			serverFrame(false, opText, nil, pattern(200)[:100]),
+			serverFrame(true, 0, nil, pattern(200)[100:]),
+		}, pattern(200)},
+		{"pong skipped", [][]byte{
+			serverFrame(true, opPong, nil, []byte("pong")),
+			serverFrame(true, opText, nil, []byte("{}")),
+		}, []byte("{}")},
+	}
+
+	for _, c := range cases {
+		client, server := net.Pipe()
+		ws := &WSClient{conn: client, reader: bufio.NewReader(client)}
+		go func() {
+			for _, f := range c.frames {
+				server.Write(f)
+			}
+		}()
+
+		message, err := ws.readMessage()
+		if err != nil {
+			t.Fatalf("Test_WSClient_ReadMessage %s err: %s", c.name, err)
+		}
+
+		if !bytes.Equal(message, c.message) {
+			t.Fatalf("Test_WSClient_ReadMessage %s message has %d bytes, expected %d", c.name, len(message), len(c.message))
+		}
+
+		client.Close()
+		server.Close()
+	}
+}
+
+func Test_WSClient_Ping(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	ws := &WSClient{conn: client, reader: bufio.NewReader(client)}
+	defer client.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		ping := []byte("are you there")
+		server.Write(serverFrame(true, opPing, nil, ping))
+		// the pong must arrive before the next message is read
+		f, err := readFrame(server)
+		if err != nil || f.opcode != opPong || !f.masked || !bytes.Equal(f.payload, ping) {
+			received <- nil
+			return
+		}
+
+		received <- f.payload
+		server.Write(serverFrame(true, opText, nil, []byte("{}")))
+	}()
+
+	message, err := ws.readMessage()
+	if err != nil {
+		t.Fatalf("Test_WSClient_Ping read err: %s", err)
+	}
+
+	if pong := <-received; pong == nil {
+		t.Fatalf("Test_WSClient_Ping ping is not answered by a masked pong with its payload")
+	}
+
+	if string(message) != "{}" {
+		t.Fatalf("Test_WSClient_Ping message is %s, expected {}", message)
+	}
+
+	closeFrame := serverFrame(true, opClose, nil, []byte{0x03, 0xe8})
+	go server.Write(closeFrame)
+	if _, err = ws.readMessage(); err != io.EOF {
+		t.Fatalf("Test_WSClient_Ping close frame returns %v, expected EOF", err)
+	}
+}
+
+// notifications sent before the subscribe response are delivered first and in
+// order, and the channel is closed with the connection.
+func Test_WSClient_EarlyNotifications(t *testing.T) {
+	client, server := net.Pipe()
+	ws := newWSClient(client, bufio.NewReader(client))
+	defer ws.Close()
+
+	notify := func(result int) []byte {
+		data, _ := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  "seele_subscription",
+			"params":  map[string]interface{}{"subscription": "0x1", "result": result},
+		})
+		return serverFrame(true, opText, nil, data)
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		f, err := readFrame(server)
+		if err != nil {
+			served <- err
+			return
+		}
+
+		var request struct {
+			ID     uint64        `json:"id"`
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		if err = json.Unmarshal(f.payload, &request); err != nil {
+			served <- err
+			return
+		}
+
+		if request.Method != "seele_subscribe" || len(request.Params) != 1 || request.Params[0] != "newHeads" {
+			served <- fmt.Errorf("request is %s", f.payload)
+			return
+		}
+
+		response, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": "0x1"})
+		server.Write(notify(1))
+		server.Write(notify(2))
+		server.Write(serverFrame(true, opText, nil, response))
+		server.Write(notify(3))
+		served <- nil
+	}()
+
+	sub, err := ws.Subscribe("newHeads")
+	if err != nil {
+		t.Fatalf("Test_WSClient_EarlyNotifications subscribe err: %s", err)
+	}
+
+	if err = <-served; err != nil {
+		t.Fatalf("Test_WSClient_EarlyNotifications server err: %s", err)
+	}
+
+	if sub.ID != "0x1" {
+		t.Fatalf("Test_WSClient_EarlyNotifications subscription id is %s, expected 0x1", sub.ID)
+	}
+
+	for expected := 1; expected <= 3; expected++ {
+		select {
+		case raw := <-sub.C:
+			if string(raw) != strconv.Itoa(expected) {
+				t.Fatalf("Test_WSClient_EarlyNotifications notification is %s, expected %d", raw, expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Test_WSClient_EarlyNotifications notification %d does not arrive", expected)
+		}
+	}
+
+	server.Close()
+	select {
+	case raw, ok := <-sub.C:
+		if ok {
+			t.Fatalf("Test_WSClient_EarlyNotifications gets %s after the connection is closed", raw)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Test_WSClient_EarlyNotifications channel is not closed with the connection")
+	}
+
+	if ws.Err() == nil {
+		t.Fatalf("Test_WSClient_EarlyNotifications connection is closed without an error")
+	}
+}
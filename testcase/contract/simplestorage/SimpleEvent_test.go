@@ -0,0 +1,184 @@
+package contract
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+	"github.com/seeleteam/e2e-blackbox/testcase/contract"
+)
+
+var (
+	// testcase\contract\simplestorage\SimpleEvent.sol, get emits getX(1, 2)
+	eventAbiFile = "./SimpleEvent.abi"
+	eventBinFile = "./SimpleEvent.bin"
+)
+
+const (
+	// getXTopic is the keccak256 of getX(uint256,uint256)
+	getXTopic = "0x672e793f48f65acb771442258a567e553d1620c0684e1cbd9fe06ee380d1b642"
+	// setTopic is the keccak256 of set(uint256), no log has it
+	setTopic = "0x60fe47b16ed402aae66ca03d2bfc51478ee897c26a1158669c7058d5f24898f4"
+)
+
+// emitGetX calls get of the SimpleEvent contract and returns the tx hash and its block height.
+func emitGetX(t *testing.T, command, from, contractAddr string) (string, int) {
+	payload := contract.GeneratePayload(t, command, eventAbiFile, "get")
+	receipt := contract.HandleTx(t, 0, command, from, contractAddr, payload)
+	if len(receipt.Logs) != 1 {
+		t.Fatalf("get of %s emits %d logs, expected 1", contractAddr, len(receipt.Logs))
+	}
+
+	tx, err := common.GetTxByHash(t, contract.CmdClient, receipt.Hash, common.ServerAddr)
+	if err != nil {
+		t.Fatalf("gettxbyhash %s err: %s", receipt.Hash, err)
+	}
+
+	return receipt.Hash, tx.Height
+}
+
+// getSimpleEvent calls get of the SimpleEvent contract and returns the stored value.
+func getSimpleEvent(t *testing.T, command, from, contractAddr string) string {
+	payload := contract.GeneratePayload(t, command, eventAbiFile, "get")
+	return contract.HandleTx(t, 0, command, from, contractAddr, payload).Result
+}
+
+func getLogs(t *testing.T, height int, contractAddr, topic string) []common.LogByTopic {
+	logs, err := common.GetLogs(t, contract.CmdClient, height, contractAddr, topic, common.ServerAddr)
+	if err != nil {
+		t.Fatalf("getlogs at %d of %s err: %s", height, contractAddr, err)
+	}
+
+	return logs
+}
+
+func checkGetX(t *testing.T, log common.LogByTopic, contractAddr, txHash string) {
+	if log.Txhash != txHash || log.Log.Address != contractAddr {
+		t.Fatalf("log of tx %s from %s, expected tx %s from %s", log.Txhash, log.Log.Address, txHash, contractAddr)
+	}
+
+	if len(log.Log.Topics) != 1 || log.Log.Topics[0] != getXTopic {
+		t.Fatalf("log topics %v, expected [%s]", log.Log.Topics, getXTopic)
+	}
+
+	if data := uint256(1) + uint256(2)[2:]; log.Log.Data != data {
+		t.Fatalf("log data %s, expected %s", log.Log.Data, data)
+	}
+}
+
+// the events emitted through the command are found by getlogs filtered by
+// contract, topic and height, and by nothing else.
+func testEvents(t *testing.T, command, from string) {
+	emitter := deploy(t, command, from, eventBinFile)
+	silent := deploy(t, command, from, eventBinFile)
+	txHash1, height1 := emitGetX(t, command, from, emitter)
+	txHash2, height2 := emitGetX(t, command, from, emitter)
+
+	// every block of the range holds the logs of its own txs only
+	begin := height1 - 2
+	if begin < 1 {
+		begin = 1
+	}
+
+	found := make(map[string]int)
+	for height := begin; height <= height2; height++ {
+		for _, log := range getLogs(t, height, emitter, getXTopic) {
+			if log.Log.BklockNumber != uint(height) {
+				t.Fatalf("getlogs at %d returns a log of block %d", height, log.Log.BklockNumber)
+			}
+			found[log.Txhash] = height
+		}
+	}
+
+	if len(found) != 2 || found[txHash1] != height1 || found[txHash2] != height2 {
+		t.Fatalf("getlogs from %d to %d found %v, expected %s at %d and %s at %d", begin, height2, found, txHash1, height1, txHash2, height2)
+	}
+
+	logs := getLogs(t, height1, emitter, getXTopic)
+	for _, log := range logs {
+		if log.Txhash == txHash1 {
+			checkGetX(t, log, emitter, txHash1)
+		}
+	}
+
+	if logs := getLogs(t, height1, emitter, setTopic); len(logs) != 0 {
+		t.Fatalf("getlogs with topic %s returns %d logs, expected 0", setTopic, len(logs))
+	}
+
+	if logs := getLogs(t, height1, silent, getXTopic); len(logs) != 0 {
+		t.Fatalf("getlogs of the silent contract returns %d logs, expected 0", len(logs))
+	}
+}
+
+func Test_Contract_Events_client(t *testing.T) {
+	testEvents(t, contract.CmdClient, contract.Sender(t, keyDir))
+}
+
+func Test_Contract_Events_light(t *testing.T) {
+	testEvents(t, contract.CmdLight, contract.Sender(t, keyDir))
+}
+
+// failed runs the tx expecting it to fail and checks that from paid the fee only.
+func failed(t *testing.T, name string, amount, gas int, command, from, to, payload string) {
+	account := contract.KeyFileAccount(from)
+	before, err := common.GetBalance(t, contract.CmdClient, account, common.ServerAddr)
+	if err != nil {
+		t.Fatalf("%s get balance err: %s", name, err)
+	}
+
+	receipt := contract.SendAndWait(t, amount, gas, command, from, to, payload)
+	if !receipt.Failed {
+		t.Fatalf("%s succeeded", name)
+	}
+
+	if len(receipt.Logs) != 0 {
+		t.Fatalf("%s kept %d logs", name, len(receipt.Logs))
+	}
+
+	after, err := common.GetBalance(t, contract.CmdClient, account, common.ServerAddr)
+	if err != nil {
+		t.Fatalf("%s get balance err: %s", name, err)
+	}
+
+	if after != before-receipt.TotalFee {
+		t.Fatalf("%s balance %d, expected %d - fee %d", name, after, before, receipt.TotalFee)
+	}
+}
+
+// reverted calls fail, charge the fee, refund the amount and leave the state unchanged.
+func testRevert(t *testing.T, command, from string) {
+	contractAddr := deploy(t, command, from, eventBinFile)
+
+	failed(t, "unknown method", 0, 0, command, from, contractAddr, "0xdeadbeef")
+	failed(t, "set with amount", 100, 0, command, from, contractAddr, contract.GeneratePayload(t, command, eventAbiFile, "set", "23"))
+	failed(t, "get with amount", 100, 0, command, from, contractAddr, contract.GeneratePayload(t, command, eventAbiFile, "get"))
+
+	if result := getSimpleEvent(t, command, from, contractAddr); result != uint256(5) {
+		t.Fatalf("get after the reverted set returns %s, expected %s", result, uint256(5))
+	}
+}
+
+func Test_Contract_Revert_client(t *testing.T) {
+	testRevert(t, contract.CmdClient, contract.Sender(t, keyDir))
+}
+
+func Test_Contract_Revert_light(t *testing.T) {
+	testRevert(t, contract.CmdLight, contract.Sender(t, keyDir))
+}
+
+// a deployment given one gas less than it uses runs out of gas.
+func testOutOfGas(t *testing.T, command, from string) {
+	code := contract.ParseBinFile(t, eventBinFile)
+	receipt := contract.HandleTx(t, 0, command, from, "", code)
+
+	name := fmt.Sprintf("deploy with gas %d", receipt.UsedGas-1)
+	failed(t, name, 0, int(receipt.UsedGas-1), command, from, "", code)
+}
+
+func Test_Contract_Deploy_OutOfGas_client(t *testing.T) {
+	testOutOfGas(t, contract.CmdClient, contract.Sender(t, keyDir))
+}
+
+func Test_Contract_Deploy_OutOfGas_light(t *testing.T) {
+	testOutOfGas(t, contract.CmdLight, contract.Sender(t, keyDir))
+}
@@ -1,11 +1,16 @@
 package contract
 
 import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/seeleteam/e2e-blackbox/testcase/contract"
-	"github.com/seeleteam/go-seele/common"
+	seele "github.com/seeleteam/go-seele/common"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -13,51 +18,126 @@ var (
 	// testcase\contract\simplestorage\simplestorage.sol
 	abiFile = "./SimpleStorage.abi"
 	binFile = "./SimpleStorage.bin"
+
+	// keyDir holds the keyfile of the fresh sender, removed after the suite
+	keyDir string
 )
 
-// func Test_DeployAndCallContract_client(t *testing.T) {
-// 	// deploy contract
-// 	receipt := contract.HandleTx(t, 0, contract.CmdClient, contract.KeyFileShard11, "", contract.ParseBinFile(t, binFile))
-// 	// fmt.Println("receipt:", receipt)
-// 	callSimpleStorage(t, contract.CmdClient, contract.KeyFileShard11, receipt.Contract)
-// }
+// TestMain compiles every contract of the folder, the suite fails as a whole
+// on stale artifacts. Without solc the committed artifacts are used with a
+// warning, on CI, where CI is set, that fails the suite as well.
+func TestMain(m *testing.M) {
+	flag.Parse()
+	sols, err := filepath.Glob("./*.sol")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	artifacts := make(map[string]*contract.Artifact)
+	for _, sol := range sols {
+		artifact, err := contract.Compile(sol)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if artifact.Unchecked {
+			fmt.Printf("warning: solc is missing, %s and %s are not checked against %s\n", artifact.Abi, artifact.Bin, sol)
+			if os.Getenv("CI") != "" {
+				os.Exit(1)
+			}
+		}
+		artifacts[strings.TrimSuffix(filepath.Base(sol), ".sol")] = artifact
+	}
+
+	for name, files := range map[string][2]*string{
+		"SimpleStorage": {&abiFile, &binFile},
+		"SimpleEvent":   {&eventAbiFile, &eventBinFile},
+	} {
+		if artifact, ok := artifacts[name]; ok {
+			*files[0], *files[1] = artifact.Abi, artifact.Bin
+		}
+	}
+
+	if keyDir, err = ioutil.TempDir("", "contract"); err != nil {
+		fmt.Println("create key dir err:", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(keyDir)
+	os.Exit(code)
+}
+
+// uint256 encodes x as a 32 bytes abi word, 0x prefixed
+func uint256(x int) string {
+	return fmt.Sprintf("0x%064x", x)
+}
+
+// deploy deploys the contract of the bin file, args are the encoded
+// constructor arguments, and returns the contract address.
+func deploy(t *testing.T, command, from, bin string, args ...string) string {
+	code := contract.ParseBinFile(t, bin)
+	for _, arg := range args {
+		code += arg[2:]
+	}
 
-// func Test_DeployAndCallContract_light(t *testing.T) {
-// 	// deploy contract
-// 	receipt := contract.HandleTx(t, 0, contract.CmdLight, contract.KeyFileShard12, "", contract.ParseBinFile(t, binFile))
-// 	// fmt.Println("receipt:", receipt)
-// 	callSimpleStorage(t, contract.CmdLight, contract.KeyFileShard12, receipt.Contract)
-// }
+	receipt := contract.HandleTx(t, 0, command, from, "", code)
+	if receipt.Contract == "" {
+		t.Fatalf("deploy %s returns no contract address", bin)
+	}
+
+	return receipt.Contract
+}
+
+func Test_DeployAndCallContract_client(t *testing.T) {
+	// deploy contract
+	from := contract.Sender(t, keyDir)
+	contractAddr := deploy(t, contract.CmdClient, from, binFile, uint256(7))
+	callSimpleStorage(t, contract.CmdClient, from, contractAddr)
+}
+
+func Test_DeployAndCallContract_light(t *testing.T) {
+	// deploy contract
+	from := contract.Sender(t, keyDir)
+	contractAddr := deploy(t, contract.CmdLight, from, binFile, uint256(7))
+	callSimpleStorage(t, contract.CmdLight, from, contractAddr)
+}
+
+// the state of a contract is its own, setting one instance leaves another unchanged.
+func Test_DeployAndCallContract_Instances(t *testing.T) {
+	from := contract.Sender(t, keyDir)
+	contract1 := deploy(t, contract.CmdClient, from, binFile, uint256(7))
+	contract2 := deploy(t, contract.CmdClient, from, binFile, uint256(7))
+
+	setSimpleStorage(t, contract.CmdClient, from, contract1, 41)
+	assert.Equal(t, uint256(41), getSimpleStorage(t, contract.CmdClient, from, contract1))
+	assert.Equal(t, uint256(5), getSimpleStorage(t, contract.CmdClient, from, contract2))
+}
 
 func callSimpleStorage(t *testing.T, command, from, contractAddr string) {
-	if !common.FileOrFolderExists(abiFile) {
+	if !seele.FileOrFolderExists(abiFile) {
 		t.Fatal("abi file not found")
 	}
 
-	// call get contract
+	// the constructor ignores its argument and stores 5
+	assert.Equal(t, uint256(5), getSimpleStorage(t, command, from, contractAddr))
+
+	setSimpleStorage(t, command, from, contractAddr, 23)
+	assert.Equal(t, uint256(23), getSimpleStorage(t, command, from, contractAddr))
+
+	setSimpleStorage(t, command, from, contractAddr, 0)
+	assert.Equal(t, uint256(0), getSimpleStorage(t, command, from, contractAddr))
+}
+
+func getSimpleStorage(t *testing.T, command, from, contractAddr string) string {
 	payload := contract.GeneratePayload(t, command, abiFile, "get")
-	payload = payload[strings.IndexAny(payload, "0x"):strings.IndexAny(payload, "\n")]
-
-	callRec1 := contract.HandleTx(t, 0, command, from, contractAddr, payload)
-	// fmt.Println("callRec1:", callRec1)
-	assert.NotNil(t, callRec1)
-	assert.Equal(t, false, callRec1.Failed)
-	assert.Equal(t, "0x0000000000000000000000000000000000000000000000000000000000000005", callRec1.Result)
-	// call set contract
-	payload = contract.GeneratePayload(t, command, abiFile, "set", "23")
-	payload = payload[strings.IndexAny(payload, "0x"):strings.IndexAny(payload, "\n")]
-
-	callRec2 := contract.HandleTx(t, 0, command, from, contractAddr, payload)
-	// fmt.Println("callRec2:", callRec2)
-	assert.NotNil(t, callRec2)
-	assert.Equal(t, false, callRec2.Failed)
-	// call get contract
-	payload = contract.GeneratePayload(t, command, abiFile, "get")
-	payload = payload[strings.IndexAny(payload, "0x"):strings.IndexAny(payload, "\n")]
-
-	callRec3 := contract.HandleTx(t, 0, command, from, contractAddr, payload)
-	// fmt.Println("callRec3:", callRec3)
-	assert.NotNil(t, callRec3)
-	assert.Equal(t, false, callRec3.Failed)
-	assert.Equal(t, "0x0000000000000000000000000000000000000000000000000000000000000017", callRec3.Result)
+	receipt := contract.HandleTx(t, 0, command, from, contractAddr, payload)
+	return receipt.Result
+}
+
+func setSimpleStorage(t *testing.T, command, from, contractAddr string, x int) {
+	payload := contract.GeneratePayload(t, command, abiFile, "set", fmt.Sprint(x))
+	contract.HandleTx(t, 0, command, from, contractAddr, payload)
 }
@@ -0,0 +1,234 @@
+package contract
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	seele "github.com/seeleteam/go-seele/common"
+)
+
+// solcCache is the folder in the repository root that keeps the compiled
+// artifacts by the hash of the compiler version and the source
+var solcCache = filepath.Join("artifacts", "solc")
+
+var solc = flag.String("contract.solc", "solc", "solc binary compiling the .sol contracts")
+
+// metadataRe matches the swarm hash of the metadata solc appends to the code,
+// which changes with the compiler and the source path, not with the code.
+var metadataRe = regexp.MustCompile("a165627a7a72305820[0-9a-f]{64}0029")
+
+// Artifact is a compiled contract. Hash is the cache key of the source and
+// the Compiler version. Unchecked is set when solc is missing and the
+// committed files are used without knowing whether they are stale.
+type Artifact struct {
+	Source    string
+	Compiler  string
+	Hash      string
+	Abi       string
+	Bin       string
+	Unchecked bool
+}
+
+// cacheDir returns the solc cache in the repository root, found as the first
+// folder above the working directory that holds testcase/common, so a suite
+// may be nested at any depth.
+func cacheDir() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if seele.FileOrFolderExists(filepath.Join(dir, "testcase", "common")) {
+			return filepath.Join(dir, solcCache), nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no repository root above the working directory")
+		}
+		dir = parent
+	}
+}
+
+// Compile returns the abi and bin files of the .sol file. They are reused
+// from the cache when the source was compiled by the same solc version,
+// compiled with solc otherwise. An .abi and .bin committed next to the .sol
+// must match what solc compiled, or they are stale. Without solc, the
+// committed files are used as they are and the artifact is marked unchecked.
+func Compile(sol string) (*Artifact, error) {
+	source, err := ioutil.ReadFile(sol)
+	if err != nil {
+		return nil, fmt.Errorf("read contract %s err: %s", sol, err)
+	}
+
+	cache, err := cacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	artifact := &Artifact{Source: sol}
+	name := strings.TrimSuffix(filepath.Base(sol), filepath.Ext(sol))
+	committedAbi, committedBin := strings.TrimSuffix(sol, ".sol")+".abi", strings.TrimSuffix(sol, ".sol")+".bin"
+	if _, err = exec.LookPath(*solc); err != nil {
+		if seele.FileOrFolderExists(committedAbi) && seele.FileOrFolderExists(committedBin) {
+			artifact.Abi, artifact.Bin, artifact.Unchecked = committedAbi, committedBin, true
+			return artifact, nil
+		}
+
+		return nil, fmt.Errorf("contract %s is not compiled and solc %q is not found, install solc or pass -contract.solc", sol, *solc)
+	}
+
+	if artifact.Compiler, err = solcVersion(); err != nil {
+		return nil, err
+	}
+
+	// another solc version compiles other code from the same source
+	sum := sha256.Sum256(append([]byte(artifact.Compiler+"\n"), source...))
+	artifact.Hash = hex.EncodeToString(sum[:])
+	dir := filepath.Join(cache, artifact.Hash)
+	if !seele.FileOrFolderExists(filepath.Join(dir, name+".bin")) {
+		if err = solcCompile(sol, name, cache, dir); err != nil {
+			return nil, err
+		}
+	}
+
+	artifact.Abi, artifact.Bin = filepath.Join(dir, name+".abi"), filepath.Join(dir, name+".bin")
+	if err = checkStale(artifact, committedAbi, committedBin); err != nil {
+		return nil, err
+	}
+
+	return artifact, nil
+}
+
+// solcVersion returns the output of solc --version, which names the release
+// and the commit of the compiler.
+func solcVersion() (string, error) {
+	output, err := exec.Command(*solc, "--version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("solc --version err: %s, %s", err, bytes.TrimSpace(output))
+	}
+
+	return string(bytes.TrimSpace(output)), nil
+}
+
+// solcCompile compiles the .sol into dir of the cache as name.abi and name.bin.
+// solc names its output after the contract, which is taken as is when the
+// source holds a single contract.
+func solcCompile(sol, name, cache, dir string) error {
+	if err := os.MkdirAll(cache, os.ModePerm); err != nil {
+		return fmt.Errorf("create solc cache err: %s", err)
+	}
+
+	tmp, err := ioutil.TempDir(cache, "tmp")
+	if err != nil {
+		return fmt.Errorf("create solc cache err: %s", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if output, err := exec.Command(*solc, "--abi", "--bin", "--overwrite", "-o", tmp, sol).CombinedOutput(); err != nil {
+		return fmt.Errorf("solc %s err: %s, %s", sol, err, bytes.TrimSpace(output))
+	}
+
+	if !seele.FileOrFolderExists(filepath.Join(tmp, name+".bin")) {
+		bins, _ := filepath.Glob(filepath.Join(tmp, "*.bin"))
+		if len(bins) != 1 {
+			return fmt.Errorf("solc %s compiled %d contracts and none is named %s", sol, len(bins), name)
+		}
+
+		contract := strings.TrimSuffix(bins[0], ".bin")
+		for _, ext := range []string{".abi", ".bin"} {
+			if err = os.Rename(contract+ext, filepath.Join(tmp, name+ext)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// another test may have compiled the same source meanwhile
+	if err = os.Rename(tmp, dir); err != nil && !seele.FileOrFolderExists(filepath.Join(dir, name+".bin")) {
+		return fmt.Errorf("store solc output of %s err: %s", sol, err)
+	}
+
+	return nil
+}
+
+// checkStale compares the committed artifacts with the compiled ones.
+func checkStale(artifact *Artifact, committedAbi, committedBin string) error {
+	if seele.FileOrFolderExists(committedBin) {
+		committed, compiled, err := readCode(committedBin, artifact.Bin)
+		if err != nil {
+			return err
+		}
+
+		if committed != compiled {
+			return fmt.Errorf("%s is stale, it does not match the code compiled from %s by %s (%s), recompile it or remove it", committedBin, artifact.Source, *solc, artifact.Compiler)
+		}
+	}
+
+	if seele.FileOrFolderExists(committedAbi) {
+		committed, compiled, err := readAbi(committedAbi, artifact.Abi)
+		if err != nil {
+			return err
+		}
+
+		if !reflect.DeepEqual(committed, compiled) {
+			return fmt.Errorf("%s is stale, it does not match the abi compiled from %s by %s (%s), recompile it or remove it", committedAbi, artifact.Source, *solc, artifact.Compiler)
+		}
+	}
+
+	return nil
+}
+
+// readCode reads bin files without 0x prefix and metadata
+func readCode(files ...string) (string, string, error) {
+	var code []string
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", "", err
+		}
+
+		bin := strings.TrimPrefix(strings.TrimSpace(string(content)), "0x")
+		code = append(code, metadataRe.ReplaceAllString(strings.ToLower(bin), ""))
+	}
+
+	return code[0], code[1], nil
+}
+
+// readAbi reads abi files as sorted entries, solc versions order them differently
+func readAbi(files ...string) ([]string, []string, error) {
+	var abis [][]string
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var entries []interface{}
+		if err = json.Unmarshal(content, &entries); err != nil {
+			return nil, nil, fmt.Errorf("abi %s err: %s", file, err)
+		}
+
+		var abi []string
+		for _, entry := range entries {
+			encoded, _ := json.Marshal(entry)
+			abi = append(abi, string(encoded))
+		}
+		sort.Strings(abi)
+		abis = append(abis, abi)
+	}
+
+	return abis[0], abis[1], nil
+}
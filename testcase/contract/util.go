@@ -3,9 +3,10 @@ package contract
 import (
 	"io/ioutil"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
-	"time"
 
 	"github.com/seeleteam/e2e-blackbox/testcase/common"
 	seele "github.com/seeleteam/go-seele/common"
@@ -17,37 +18,72 @@ const (
 	CmdClient = "../../../bin/client"
 	CmdLight  = "../../../bin/light"
 
+	// KeyFileShard11 funds the fresh sender of the suite
 	KeyFileShard11 = "../../../config/keyfile/shard1-0x4fb7c8b0287378f0cf8b5a9262bf3ef7e101f8d1"
-	KeyFileShard12 = "../../../config/keyfile/shard1-0xec759db47a65f6537d630517f6cd3ca39c6f93d1"
+
+	// senderFunds pays the deployments and calls of the suite
+	senderFunds = 1000000000
 )
 
-// HandleTx handle tx and return the receipt
-func HandleTx(t *testing.T, amount int, command, from, contract, payload string) (receipt *common.ReceiptInfo) {
-	txHash, _, err1 := common.SendTx(t, command, amount, 0, 0, from, contract, payload, common.ServerAddr)
-	if err1 != nil {
-		t.Fatal(err1)
-	}
+var sender struct {
+	once    sync.Once
+	keyfile string
+	err     error
+}
 
-	timeoutC := time.After(150 * time.Second)
-	for {
-		var err2 error
-		// fmt.Println("txHash:", txHash)
-		receipt, err2 = common.GetReceipt(t, command, txHash, common.ServerAddr)
-		if err2 != nil && !strings.Contains(err2.Error(), "leveldb: not found") {
-			t.Fatal(err2)
+// Sender returns the keyfile of a fresh shard 1 account saved in dir and
+// funded from KeyFileShard11 on first use. Other packages send from the
+// committed keyfiles, a fresh account keeps the nonce and balance the suite
+// asserts on its own.
+func Sender(t *testing.T, dir string) string {
+	sender.once.Do(func() {
+		var account string
+		if sender.keyfile, account, sender.err = common.NewKeyFile(t, CmdClient, 1, dir); sender.err != nil {
+			return
 		}
 
-		// fmt.Println("receipt:", receipt)
-		if receipt != nil && receipt.Failed == false {
-			break
-		}
+		sender.err = common.FundAccounts(t, CmdClient, KeyFileShard11, KeyFileAccount(KeyFileShard11), senderFunds, common.ServerAddr, account)
+	})
 
-		select {
-		case <-timeoutC:
-			t.Fatalf("over time. err: %s", err2.Error())
-		default:
-			time.Sleep(100 * time.Millisecond)
-		}
+	if sender.err != nil {
+		t.Fatalf("fresh sender err: %s", sender.err)
+	}
+
+	return sender.keyfile
+}
+
+// KeyFileAccount returns the account a keyfile is named after
+func KeyFileAccount(keyfile string) string {
+	name := filepath.Base(keyfile)
+	return name[strings.Index(name, "-")+1:]
+}
+
+// SendAndWait sends a tx with the current nonce of from and returns its receipt,
+// failed or not. A zero gas uses the default gas limit of common.SendTx.
+func SendAndWait(t *testing.T, amount, gas int, command, from, contract, payload string) *common.ReceiptInfo {
+	nonce, err := common.GetNonce(t, command, KeyFileAccount(from), common.ServerAddr)
+	if err != nil {
+		t.Fatalf("get nonce of %s err: %s", from, err)
+	}
+
+	txHash, _, err := common.SendTx(t, command, amount, nonce, gas, from, contract, payload, common.ServerAddr)
+	if err != nil {
+		t.Fatalf("sendtx err: %s", err)
+	}
+
+	receipt, err := common.WaitReceipt(t, command, txHash, common.ServerAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return receipt
+}
+
+// HandleTx handle tx and return the receipt
+func HandleTx(t *testing.T, amount int, command, from, contract, payload string) (receipt *common.ReceiptInfo) {
+	receipt = SendAndWait(t, amount, 0, command, from, contract, payload)
+	if receipt.Failed {
+		t.Fatalf("tx %s failed: %s", receipt.Hash, receipt.Result)
 	}
 
 	return receipt
@@ -61,8 +97,13 @@ func GeneratePayload(t *testing.T, command, abi, method string, args ...string)
 	}
 
 	bytes, err := cmd.CombinedOutput()
-	assert.NoError(t, err)
-	return string(bytes)
+	payload = strings.TrimSpace(string(bytes))
+	if err != nil || !strings.Contains(payload, "0x") {
+		t.Fatalf("payload of %s err: %v, output: %s", method, err, payload)
+	}
+
+	// the payload is printed as "payload: 0x..."
+	return payload[strings.Index(payload, "0x"):]
 }
 
 // ParseBinFile parse bin
@@ -73,5 +114,6 @@ func ParseBinFile(t *testing.T, filePath string) string {
 	bytes, err := ioutil.ReadFile(filePath)
 	assert.Nil(t, err)
 
-	return string(bytes)
+	// solc writes the code without 0x
+	return "0x" + strings.TrimPrefix(strings.TrimSpace(string(bytes)), "0x")
 }
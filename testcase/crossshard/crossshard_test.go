@@ -0,0 +1,280 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package crossshard
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/node"
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+const (
+	amount = 1000
+	// senderFunds pays the transfer and its fee from the fresh sender
+	senderFunds = 10000000
+
+	// debtTimeout bounds the time from sending to the debt included on the target shard
+	debtTimeout = 10 * time.Minute
+	pollPeriod  = 2 * time.Second
+)
+
+// keyDir holds the keyfiles of the fresh accounts, removed after the suite
+var keyDir string
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "crossshard")
+	if err != nil {
+		fmt.Println("create key dir err:", err)
+		os.Exit(1)
+	}
+
+	keyDir = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// transfer is a cross shard tx followed from shard 1 to shard 2.
+type transfer struct {
+	name       string
+	from, to   string
+	keyfile    string
+	addr1      string
+	addr2      string
+	nonce      int
+	txHash     string
+	debtHash   string
+	sent       time.Time
+	begin1     int64
+	begin2     int64
+	fee        int64
+	debtPooled bool
+}
+
+// newTransfer creates a fresh sender on shard 1, funded from a committed
+// keyfile, and a fresh receiver on shard 2. No other test sends from or to
+// them, so their balances and the nonce only change with the transfer.
+func newTransfer(t *testing.T, name, addr1, addr2 string) *transfer {
+	keyfile, from, err := common.NewKeyFile(t, common.CmdClient, 1, keyDir)
+	if err != nil {
+		t.Fatalf("%s new sender err: %s", name, err)
+	}
+
+	_, to, err := common.NewKeyFile(t, common.CmdClient, 2, keyDir)
+	if err != nil {
+		t.Fatalf("%s new receiver err: %s", name, err)
+	}
+
+	if err = common.FundAccounts(t, common.CmdClient, common.KeyFileShard1_4, common.AccountShard1_4, senderFunds, addr1, from); err != nil {
+		t.Fatalf("%s fund sender err: %s", name, err)
+	}
+
+	tr := &transfer{
+		name:    name,
+		from:    from,
+		to:      to,
+		keyfile: keyfile,
+		addr1:   addr1,
+		addr2:   addr2,
+	}
+
+	if tr.begin1, err = common.GetBalance(t, common.CmdClient, tr.from, addr1); err != nil {
+		t.Fatalf("%s get balance on shard 1 err: %s", name, err)
+	}
+
+	if tr.begin2, err = common.GetBalance(t, common.CmdClient, tr.to, addr2); err != nil {
+		t.Fatalf("%s get balance on shard 2 err: %s", name, err)
+	}
+
+	if tr.nonce, err = common.GetNonce(t, common.CmdClient, tr.from, addr1); err != nil {
+		t.Fatalf("%s get nonce err: %s", name, err)
+	}
+
+	return tr
+}
+
+// send sends the tx and checks that it is taken as a cross shard tx.
+func (tr *transfer) send(t *testing.T) {
+	var err error
+	tr.sent = time.Now()
+	if tr.txHash, tr.debtHash, err = common.SendTx(t, common.CmdClient, amount, tr.nonce, 0, tr.keyfile, tr.to, "", tr.addr1); err != nil {
+		t.Fatalf("%s sendtx err: %s", tr.name, err)
+	}
+
+	if tr.debtHash == "" {
+		t.Fatalf("%s sendtx returns no debt for a cross shard tx", tr.name)
+	}
+}
+
+// mined waits for the tx on shard 1 and returns the latency.
+func (tr *transfer) mined(t *testing.T) time.Duration {
+	receipt, err := common.WaitReceipt(t, common.CmdClient, tr.txHash, tr.addr1)
+	if err != nil {
+		t.Fatalf("%s tx is not mined on shard 1: %s", tr.name, err)
+	}
+
+	if receipt.Failed {
+		t.Fatalf("%s tx failed on shard 1: %s", tr.name, receipt.Result)
+	}
+
+	tr.fee = receipt.TotalFee
+	return time.Since(tr.sent)
+}
+
+// included follows the debt until the target shard includes it in a block
+// and returns the latency. Seeing the debt pending is noted, but not
+// required, a fast target shard may include it between two polls.
+func (tr *transfer) included(t *testing.T) (*common.DebtByHashInfo, time.Duration) {
+	deadline := tr.sent.Add(debtTimeout)
+	for {
+		if debts, err := common.GetDebts(t, common.CmdClient, tr.addr2); err == nil {
+			for _, debt := range debts {
+				tr.debtPooled = tr.debtPooled || debt.Hash == tr.debtHash
+			}
+		}
+
+		info, err := common.GetDebtByHash(t, common.CmdClient, tr.debtHash, tr.addr2)
+		if err == nil && info.BlockHash != "" {
+			return info, time.Since(tr.sent)
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("%s debt %s is not included on shard 2 in %s, last err: %v", tr.name, tr.debtHash, debtTimeout, err)
+		}
+
+		time.Sleep(pollPeriod)
+	}
+}
+
+// verify checks the included debt against the tx and the balances of both shards.
+func (tr *transfer) verify(t *testing.T, info *common.DebtByHashInfo, times int64) {
+	debt := info.Debt
+	if debt.Hash != tr.debtHash || debt.Data.TxHash != tr.txHash {
+		t.Fatalf("%s debt %s of tx %s, expected debt %s of tx %s", tr.name, debt.Hash, debt.Data.TxHash, tr.debtHash, tr.txHash)
+	}
+
+	if debt.Data.Account != tr.to || debt.Data.Amount != amount {
+		t.Fatalf("%s debt pays %d to %s, expected %d to %s", tr.name, debt.Data.Amount, debt.Data.Account, amount, tr.to)
+	}
+
+	balance1, err := common.GetBalance(t, common.CmdClient, tr.from, tr.addr1)
+	if err != nil {
+		t.Fatalf("%s get balance on shard 1 err: %s", tr.name, err)
+	}
+
+	if balance1 != tr.begin1-amount-tr.fee {
+		t.Fatalf("%s balance on shard 1 is %d, expected %d - %d - fee %d", tr.name, balance1, tr.begin1, amount, tr.fee)
+	}
+
+	balance2, err := common.GetBalance(t, common.CmdClient, tr.to, tr.addr2)
+	if err != nil {
+		t.Fatalf("%s get balance on shard 2 err: %s", tr.name, err)
+	}
+
+	if balance2 != tr.begin2+times*amount {
+		t.Fatalf("%s balance on shard 2 is %d, expected %d + %d x %d", tr.name, balance2, tr.begin2, times, amount)
+	}
+}
+
+func Test_CrossShard_Debt_Lifecycle(t *testing.T) {
+	tr := newTransfer(t, "Test_CrossShard_Debt_Lifecycle", common.ServerAddr, common.ServertwoAddr)
+	tr.send(t)
+	txLatency := tr.mined(t)
+	info, debtLatency := tr.included(t)
+	tr.verify(t, info, 1)
+
+	t.Logf("Test_CrossShard_Debt_Lifecycle tx mined on shard 1 in %s, debt included on shard 2 in %s at height %d, seen pending: %t",
+		txLatency, debtLatency, info.BlockHeight, tr.debtPooled)
+}
+
+// a resent tx must not create a second debt, and the included debt must
+// be applied once. The debt itself is not resubmitted, neither the cli nor
+// the rpc can send a debt to a node, they only travel between the nodes.
+func Test_CrossShard_Tx_Resend_Debt_Once(t *testing.T) {
+	name := "Test_CrossShard_Tx_Resend_Debt_Once"
+	tr := newTransfer(t, name, common.ServerAddr, common.ServertwoAddr)
+	tr.send(t)
+	tr.mined(t)
+	info, _ := tr.included(t)
+
+	if _, _, err := common.SendTx(t, common.CmdClient, amount, tr.nonce, 0, tr.keyfile, tr.to, "", tr.addr1); err == nil {
+		t.Fatalf("%s resending the tx on shard 1 succeeded", name)
+	}
+
+	if _, _, err := common.SendTx(t, common.CmdClient, amount, tr.nonce, 0, tr.keyfile, tr.to, "", tr.addr2); err == nil {
+		t.Fatalf("%s sending the tx to shard 2 succeeded", name)
+	}
+
+	// give a replayed debt the time to be included
+	time.Sleep(time.Minute)
+
+	again, err := common.GetDebtByHash(t, common.CmdClient, tr.debtHash, tr.addr2)
+	if err != nil {
+		t.Fatalf("%s getdebtbyhash err: %s", name, err)
+	}
+
+	if again.BlockHash != info.BlockHash {
+		t.Fatalf("%s debt moved from block %s to %s", name, info.BlockHash, again.BlockHash)
+	}
+
+	tr.verify(t, again, 1)
+}
+
+// the debt of a tx sent while the target shard is down is included once
+// the target shard is back. Other packages use the shard 2 nodes, so it only
+// runs when the test owns them, see node.Exclusive.
+func Test_CrossShard_Debt_Target_Down(t *testing.T) {
+	name := "Test_CrossShard_Debt_Target_Down"
+	if !node.Exclusive() {
+		t.Skipf("%s stops nodes other packages use, set %s=1 to run it alone", name, node.ExclusiveEnv)
+	}
+
+	topology, network, err := node.Setup(common.TopologyFile)
+	if err != nil {
+		t.Fatalf("%s setup topology err: %s", name, err)
+	}
+	defer node.Teardown(topology, network)
+
+	if topology == nil {
+		t.Skip("no topology, the target shard can not be stopped")
+	}
+
+	shard1, shard2 := topology.Shard(1), topology.Shard(2)
+	if len(shard1) == 0 || len(shard2) == 0 {
+		t.Skip("the topology has no node of shard 1 or 2")
+	}
+
+	for _, n := range shard2 {
+		if !n.Managed() {
+			t.Skipf("node %s of shard 2 is not managed", n.Name)
+		}
+	}
+
+	tr := newTransfer(t, name, shard1[0].Addr(), shard2[0].Addr())
+	for _, n := range shard2 {
+		if err = n.Stop(); err != nil {
+			t.Fatalf("%s stop %s err: %s", name, n.Name, err)
+		}
+	}
+
+	tr.send(t)
+	tr.mined(t)
+
+	for _, n := range shard2 {
+		if err = n.Start(); err != nil {
+			t.Fatalf("%s start %s err: %s", name, n.Name, err)
+		}
+	}
+
+	info, latency := tr.included(t)
+	tr.verify(t, info, 1)
+	t.Logf("%s debt included %s after sending", name, latency)
+}
@@ -5,358 +5,289 @@
 
 package domain
 
-// import (
-// 	"bytes"
-// 	"encoding/json"
-// 	"fmt"
-// 	"io"
-// 	"os/exec"
-// 	"strings"
-// 	"testing"
-// 	"time"
-
-// 	"github.com/seeleteam/e2e-blackbox/testcase/common"
-
-// 	seele "github.com/seeleteam/go-seele/common"
-// )
-
-// func Test_Client_Domain_register_Invalid_KeyFile(t *testing.T) {
-// 	validateInfo := `invalid sender key file`
-// 	domainInvalidRegister(t, "Test_Client_Domain_register_Invalid_KeyFile", "common.KeyFileShard1_1",
-// 		"123456", "15", "200000", "", "game", validateInfo)
-// }
-
-// func Test_Client_Domain_register_Unmatched_keyfile_And_Pass(t *testing.T) {
-// 	validateInfo := `invalid sender key file`
-// 	domainInvalidRegister(t, "Test_Client_Domain_register_Unmatched_keyfile_And_Pass", common.KeyFileShard1_1,
-// 		"123456", "15", "200000", "", "game", validateInfo)
-// }
-
-// func Test_Client_Domain_register_Invalid_PriceValue(t *testing.T) {
-// 	validateInfo := `invalid gas price value`
-// 	domainInvalidRegister(t, "Test_Client_Domain_register_Invalid_PriceValue", common.KeyFileShard1_1,
-// 		"123", "q2", "200000", "", "game", validateInfo)
-// }
-
-// func Test_Client_Domain_register_Invalid_Gas(t *testing.T) {
-// 	validateInfo := `invalid value "qw" for flag -gas: strconv.ParseUint: parsing "qw"`
-// 	domainInvalidRegister(t, "Test_Client_Domain_register_Invalid_Gas", common.KeyFileShard1_1,
-// 		"123", "15", "qw", "", "game", validateInfo)
-// }
-
-// func Test_Client_Domain_register_Invalid_Nonce(t *testing.T) {
-// 	validateInfo := `invalid value "er" for flag -nonce: strconv.ParseUint: parsing "er": invalid syntax`
-// 	domainInvalidRegister(t, "Test_Client_Domain_register_Invalid_Nonce", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "er", "game", validateInfo)
-// }
-
-// // Name cannot contain special characters, such as /, \, `
-// // it may be a string consisting of numbers, letters, and middle lines, etc.
-// // func Test_Client_Domain_register_Invalid_Name(t *testing.T) {
-// // 	validateInfo := `invalid name, only numbers, letters, and dash lines are allowed`
-// // 	domainInvalidRegister(t, "Test_Client_Domain_register_Invalid_Name", common.KeyFileShard1_1,
-// // 		"123", "15", "200000", "", "seele.game_23", validateInfo)
-// // }
-
-// func Test_Client_Domain_register_Invalid_Name_Empty(t *testing.T) {
-// 	validateInfo := `name is empty`
-// 	domainInvalidRegister(t, "Test_Client_Domain_register_Invalid_Name_Empty", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "", "", validateInfo)
-// }
-
-// func Test_Client_Domain_register_Invalid_Name_Exceed_Max_Length(t *testing.T) {
-// 	domainName := ""
-// 	for i := 0; i < len(seele.EmptyHash)+1; i++ {
-// 		domainName += "s"
-// 	}
-
-// 	validateInfo := `name too long`
-// 	domainInvalidRegister(t, "Test_Client_Domain_register_Invalid_Name_Exceed_Max_Length", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "", domainName, validateInfo)
-// }
-
-// func Test_Client_Domain_register(t *testing.T) {
-// 	receipt1 := domainRegister(t, "Test_Client_Domain_register", "seele")
-// 	if receipt1.Failed {
-// 		t.Fatalf("Test_Client_Domain_register recepit error, %s", receipt1.Result)
-// 	}
-// }
-
-// func Test_Client_Domain_register_Invalid_Name_Existed(t *testing.T) {
-// 	receipt1 := domainRegister(t, "Test_Client_Domain_register_Invalid_Name_Existed", "game")
-// 	if receipt1.Failed {
-// 		t.Fatalf("Test_Client_Domain_register_Invalid_Name_Existed recepit error, %s", receipt1.Result)
-// 	}
-
-// 	receipt2 := domainRegister(t, "Test_Client_Domain_register_Invalid_Name_Existed", "game")
-
-// 	if !receipt2.Failed {
-// 		t.Fatalf("Test_Client_Domain_register_Invalid_Name_Existed, Domain name repeated registration successully")
-// 	}
-
-// 	if !strings.Contains(receipt2.Result, "already exists") {
-// 		t.Fatalf("Test_Client_Domain_register_Invalid_Name_Existed result does not contain already exists, Result:%s", receipt2.Result)
-// 	}
-// }
-
-// func domainInvalidRegister(t *testing.T, funcName, keyFile, passWord, price, gas, nonce, domainName, validateInfo string) {
-// 	if len(nonce) == 0 {
-// 		accountNonce, err := common.GetNonce(t, common.CmdClient, common.AccountShard1_1, common.ServerAddr)
-// 		if err != nil {
-// 			t.Fatalf("%s, err:%s", funcName, err)
-// 		}
-
-// 		nonce = fmt.Sprintf("%d", accountNonce)
-// 	}
-
-// 	cmd := exec.Command(common.CmdClient, "domain", "register", "--from", keyFile, "--price", price, "--gas", gas,
-// 		"--nonce", nonce, "--name", domainName)
-
-// 	stdin, err := cmd.StdinPipe()
-// 	if err != nil {
-// 		t.Fatalf("%s err: %s", funcName, err)
-// 	}
-
-// 	defer stdin.Close()
-
-// 	var outErr bytes.Buffer
-// 	cmd.Stderr = &outErr
-
-// 	if err = cmd.Start(); err != nil {
-// 		t.Fatalf("%s: An error occured: %s", funcName, err)
-// 	}
-
-// 	io.WriteString(stdin, passWord+"\n")
-// 	cmd.Wait()
-
-// 	errStr := outErr.String()
-// 	if !strings.Contains(errStr, validateInfo) {
-// 		t.Fatalf("%s get err=:%s, should be %s", funcName, errStr, validateInfo)
-// 	}
-// }
-
-// func domainRegister(t *testing.T, funcName, domainName string) *common.ReceiptInfo {
-// 	cmd := exec.Command(common.CmdClient, "domain", "register", "--from", common.KeyFileShard1_2, "--price", "15", "--gas", "200000",
-// 		"--name", domainName)
-
-// 	stdin, err := cmd.StdinPipe()
-// 	if err != nil {
-// 		t.Fatalf("%s err: %s", funcName, err)
-// 	}
-
-// 	defer stdin.Close()
-
-// 	var out bytes.Buffer
-// 	var outErr bytes.Buffer
-// 	cmd.Stdout, cmd.Stderr = &out, &outErr
-
-// 	if err = cmd.Start(); err != nil {
-// 		t.Fatalf("%s: An error occured: %s", funcName, err)
-// 	}
-
-// 	io.WriteString(stdin, "123\n")
-// 	cmd.Wait()
-
-// 	output, errStr := out.String(), outErr.String()
-// 	if errStr != "" {
-// 		t.Fatalf("%s cmd err: %s", funcName, errStr)
-// 	}
-
-// 	str := output[strings.Index(output, "{") : strings.LastIndex(output, "}")+1]
-
-// 	var tx common.TxInfo
-// 	if err := json.Unmarshal([]byte(str), &tx); err != nil {
-// 		t.Fatalf("%s unmarshal register domain tx err: %s", funcName, err)
-// 	}
-
-// 	for {
-// 		time.Sleep(10)
-// 		number, err := common.GetPoolCountTxs(t, common.CmdClient, common.ServerAddr)
-// 		if err != nil {
-// 			t.Fatalf("%s get pool count err: %s", funcName, err)
-// 		}
-
-// 		if number == 0 {
-// 			break
-// 		}
-// 	}
-
-// 	time.Sleep(20)
-
-// 	receipt, err := common.GetReceipt(t, common.CmdClient, tx.Hash, common.ServerAddr)
-// 	if err != nil {
-// 		t.Fatalf("%s get receipt err: %s", funcName, err)
-// 	}
-
-// 	return receipt
-// }
-
-// func Test_Client_Domain_owner_Invalid_KeyFile(t *testing.T) {
-// 	validateInfo := `invalid sender key file`
-// 	domainInvalidOwner(t, "Test_Client_Domain_owner_Invalid_KeyFile", "common.KeyFileShard1_1",
-// 		"123456", "15", "200000", "", "game", validateInfo)
-// }
-
-// func Test_Client_Domain_owner_Unmatched_keyfile_And_Pass(t *testing.T) {
-// 	validateInfo := `invalid sender key file`
-// 	domainInvalidOwner(t, "Test_Client_Domain_owner_Unmatched_keyfile_And_Pass", common.KeyFileShard1_1,
-// 		"123456", "15", "200000", "", "game", validateInfo)
-// }
-
-// func Test_Client_Domain_owner_Invalid_PriceValue(t *testing.T) {
-// 	validateInfo := `invalid gas price value`
-// 	domainInvalidOwner(t, "Test_Client_Domain_owner_Invalid_PriceValue", common.KeyFileShard1_1,
-// 		"123", "q2", "200000", "", "game", validateInfo)
-// }
-
-// func Test_Client_Domain_owner_Invalid_Gas(t *testing.T) {
-// 	validateInfo := `invalid value "qw" for flag -gas: strconv.ParseUint: parsing "qw"`
-// 	domainInvalidOwner(t, "Test_Client_Domain_owner_Invalid_Gas", common.KeyFileShard1_1,
-// 		"123", "15", "qw", "", "game", validateInfo)
-// }
-
-// func Test_Client_Domain_owner_Invalid_Nonce(t *testing.T) {
-// 	validateInfo := `invalid value "er" for flag -nonce: strconv.ParseUint: parsing "er": invalid syntax`
-// 	domainInvalidOwner(t, "Test_Client_Domain_owner_Invalid_Nonce", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "er", "game", validateInfo)
-// }
-
-// // Name cannot contain special characters, such as /, \, `
-// // it may be a string consisting of numbers, letters, and middle lines, etc.
-// // func Test_Client_Domain_owner_Invalid_Name(t *testing.T) {
-// // 	validateInfo := `invalid name, only numbers, letters, and dash lines are allowed`
-// // 	domainInvalidOwner(t, "Test_Client_Domain_owner_Invalid_Name", common.KeyFileShard1_1,
-// // 		"123", "15", "200000", "", "seele.game_23", validateInfo)
-// // }
-
-// func Test_Client_Domain_owner_Invalid_Name_Empty(t *testing.T) {
-// 	validateInfo := `name is empty`
-// 	domainInvalidOwner(t, "Test_Client_Domain_owner_Invalid_Name_Empty", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "", "", validateInfo)
-// }
-
-// func Test_Client_Domain_owner_Invalid_Name_Exceed_Max_Length(t *testing.T) {
-// 	domainName := ""
-// 	for i := 0; i < len(seele.EmptyHash)+1; i++ {
-// 		domainName += "s"
-// 	}
-
-// 	validateInfo := `name too long`
-// 	domainInvalidOwner(t, "Test_Client_Domain_owner_Invalid_Name_Exceed_Max_Length", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "", domainName, validateInfo)
-// }
-
-// func Test_Client_Domain_owner_Invalid_Name_Not_Found(t *testing.T) {
-// 	receipt1 := domainOwner(t, "Test_Client_Domain_owner_Invalid_Name_Not_Found", "testownernotfound")
-// 	if !receipt1.Failed {
-// 		t.Fatalf("Test_Client_Domain_owner_Invalid_Name_Not_Found get domain, result:%s", receipt1.Result)
-// 	}
-
-// 	if !strings.Contains(receipt1.Result, "Failed to get data with key") {
-// 		t.Fatalf("Test_Client_Domain_owner_Invalid_Name_Not_Found, result:%s", receipt1.Result)
-// 	}
-// }
-
-// func Test_Client_Domain_owner(t *testing.T) {
-// 	receipt1 := domainRegister(t, "Test_Client_Domain_owner", "testowner")
-// 	if receipt1.Failed {
-// 		t.Fatalf("Test_Client_Domain_owner register domain error, result:%s", receipt1.Result)
-// 	}
-
-// 	receipt2 := domainOwner(t, "Test_Client_Domain_owner", "testowner")
-// 	if receipt2.Failed {
-// 		t.Fatalf("Test_Client_Domain_owner get domain, result:%s", receipt2.Result)
-// 	}
-// }
-
-// func domainInvalidOwner(t *testing.T, funcName, keyFile, passWord, price, gas, nonce, domainName, validateInfo string) {
-// 	if len(nonce) == 0 {
-// 		accountNonce, err := common.GetNonce(t, common.CmdClient, common.AccountShard1_1, common.ServerAddr)
-// 		if err != nil {
-// 			t.Fatalf("%s, err:%s", funcName, err)
-// 		}
-
-// 		nonce = fmt.Sprintf("%d", accountNonce)
-// 	}
-
-// 	cmd := exec.Command(common.CmdClient, "domain", "owner", "--from", keyFile, "--price", price, "--gas", gas,
-// 		"--nonce", nonce, "--name", domainName)
-
-// 	stdin, err := cmd.StdinPipe()
-// 	if err != nil {
-// 		t.Fatalf("%s err: %s", funcName, err)
-// 	}
-
-// 	defer stdin.Close()
-
-// 	var outErr bytes.Buffer
-// 	cmd.Stderr = &outErr
-
-// 	if err = cmd.Start(); err != nil {
-// 		t.Fatalf("%s: An error occured: %s", funcName, err)
-// 	}
-
-// 	io.WriteString(stdin, passWord+"\n")
-// 	cmd.Wait()
-
-// 	errStr := outErr.String()
-// 	if !strings.Contains(errStr, validateInfo) {
-// 		t.Fatalf("%s err:%s, should be %s", funcName, errStr, validateInfo)
-// 	}
-// }
-
-// func domainOwner(t *testing.T, funcName, domainName string) *common.ReceiptInfo {
-// 	cmd := exec.Command(common.CmdClient, "domain", "owner", "--from", common.KeyFileShard1_1, "--price", "15", "--gas", "200000",
-// 		"--name", domainName)
-
-// 	stdin, err := cmd.StdinPipe()
-// 	if err != nil {
-// 		t.Fatalf("%s err: %s", funcName, err)
-// 	}
-
-// 	defer stdin.Close()
-
-// 	var out bytes.Buffer
-// 	var outErr bytes.Buffer
-// 	cmd.Stdout, cmd.Stderr = &out, &outErr
-
-// 	if err = cmd.Start(); err != nil {
-// 		t.Fatalf("%s: An error occured: %s", funcName, err)
-// 	}
-
-// 	io.WriteString(stdin, "123\n")
-// 	cmd.Wait()
-
-// 	output, errStr := out.String(), outErr.String()
-// 	if errStr != "" {
-// 		t.Fatalf("%s cmd err: %s", funcName, errStr)
-// 	}
-
-// 	str := output[strings.Index(output, "{") : strings.LastIndex(output, "}")+1]
-
-// 	var tx common.TxInfo
-// 	if err := json.Unmarshal([]byte(str), &tx); err != nil {
-// 		t.Fatalf("%s unmarshal register domain tx err: %s", funcName, err)
-// 	}
-
-// 	for {
-// 		time.Sleep(10)
-// 		number, err := common.GetPoolCountTxs(t, common.CmdClient, common.ServerAddr)
-// 		if err != nil {
-// 			t.Fatalf("%s get pool count err: %s", funcName, err)
-// 		}
-
-// 		if number == 0 {
-// 			break
-// 		}
-// 	}
-
-// 	time.Sleep(20)
-
-// 	receipt, err := common.GetReceipt(t, common.CmdClient, tx.Hash, common.ServerAddr)
-// 	if err != nil {
-// 		t.Fatalf("%s get receipt err: %s", funcName, err)
-// 	}
-
-// 	return receipt
-// }
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/seeleteam/e2e-blackbox/node"
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+
+	seele "github.com/seeleteam/go-seele/common"
+)
+
+// server is the shard 1 node the suite registers on, a node of the topology
+// if there is one.
+var server = common.ServerAddr
+
+func TestMain(m *testing.M) {
+	topology, network, err := node.Setup(common.TopologyFile)
+	if err != nil {
+		fmt.Println("setup topology err:", err)
+		os.Exit(1)
+	}
+
+	if topology != nil {
+		if nodes := topology.Shard(1); len(nodes) > 0 {
+			server = nodes[0].Addr()
+		}
+	}
+
+	code := m.Run()
+	node.Teardown(topology, network)
+	os.Exit(code)
+}
+
+// uniqueName returns a name of the length that is not registered yet, so
+// reruns against the same chain do not collide.
+func uniqueName(length int) string {
+	random := make([]byte, length)
+	rand.Read(random)
+	return hex.EncodeToString(random)[:length]
+}
+
+func Test_Client_Domain_register_Invalid_KeyFile(t *testing.T) {
+	validateInfo := `invalid sender key file`
+	domainInvalid(t, "Test_Client_Domain_register_Invalid_KeyFile", "register", "common.KeyFileShard1_1",
+		"123456", "15", "200000", "", "game", validateInfo)
+}
+
+func Test_Client_Domain_register_Unmatched_keyfile_And_Pass(t *testing.T) {
+	validateInfo := `invalid sender key file`
+	domainInvalid(t, "Test_Client_Domain_register_Unmatched_keyfile_And_Pass", "register", common.KeyFileShard1_1,
+		"123456", "15", "200000", "", "game", validateInfo)
+}
+
+func Test_Client_Domain_register_Invalid_PriceValue(t *testing.T) {
+	validateInfo := `invalid gas price value`
+	domainInvalid(t, "Test_Client_Domain_register_Invalid_PriceValue", "register", common.KeyFileShard1_1,
+		"123", "q2", "200000", "", "game", validateInfo)
+}
+
+func Test_Client_Domain_register_Invalid_Gas(t *testing.T) {
+	validateInfo := `invalid value "qw" for flag -gas: strconv.ParseUint: parsing "qw"`
+	domainInvalid(t, "Test_Client_Domain_register_Invalid_Gas", "register", common.KeyFileShard1_1,
+		"123", "15", "qw", "", "game", validateInfo)
+}
+
+func Test_Client_Domain_register_Invalid_Nonce(t *testing.T) {
+	validateInfo := `invalid value "er" for flag -nonce: strconv.ParseUint: parsing "er": invalid syntax`
+	domainInvalid(t, "Test_Client_Domain_register_Invalid_Nonce", "register", common.KeyFileShard1_1,
+		"123", "15", "200000", "er", "game", validateInfo)
+}
+
+func Test_Client_Domain_register_Invalid_Name_Empty(t *testing.T) {
+	validateInfo := `name is empty`
+	domainInvalid(t, "Test_Client_Domain_register_Invalid_Name_Empty", "register", common.KeyFileShard1_1,
+		"123", "15", "200000", "", "", validateInfo)
+}
+
+// the name is stored in a hash, so it may be as long as a hash but not longer
+func Test_Client_Domain_register_Invalid_Name_Exceed_Max_Length(t *testing.T) {
+	validateInfo := `name too long`
+	domainInvalid(t, "Test_Client_Domain_register_Invalid_Name_Exceed_Max_Length", "register", common.KeyFileShard1_1,
+		"123", "15", "200000", "", uniqueName(len(seele.EmptyHash)+1), validateInfo)
+}
+
+func Test_Client_Domain_register_Name_Max_Length(t *testing.T) {
+	receipt := domainRegister(t, "Test_Client_Domain_register_Name_Max_Length", common.KeyFileShard1_2, uniqueName(len(seele.EmptyHash)))
+	if receipt.Failed {
+		t.Fatalf("Test_Client_Domain_register_Name_Max_Length recepit error, %s", receipt.Result)
+	}
+}
+
+func Test_Client_Domain_register_Name_Max_Length_Minus_One(t *testing.T) {
+	receipt := domainRegister(t, "Test_Client_Domain_register_Name_Max_Length_Minus_One", common.KeyFileShard1_2, uniqueName(len(seele.EmptyHash)-1))
+	if receipt.Failed {
+		t.Fatalf("Test_Client_Domain_register_Name_Max_Length_Minus_One recepit error, %s", receipt.Result)
+	}
+}
+
+func Test_Client_Domain_register(t *testing.T) {
+	receipt := domainRegister(t, "Test_Client_Domain_register", common.KeyFileShard1_2, uniqueName(12))
+	if receipt.Failed {
+		t.Fatalf("Test_Client_Domain_register recepit error, %s", receipt.Result)
+	}
+}
+
+func Test_Client_Domain_register_Invalid_Name_Existed(t *testing.T) {
+	name := uniqueName(12)
+	receipt1 := domainRegister(t, "Test_Client_Domain_register_Invalid_Name_Existed", common.KeyFileShard1_2, name)
+	if receipt1.Failed {
+		t.Fatalf("Test_Client_Domain_register_Invalid_Name_Existed recepit error, %s", receipt1.Result)
+	}
+
+	receipt2 := domainRegister(t, "Test_Client_Domain_register_Invalid_Name_Existed", common.KeyFileShard1_2, name)
+	if !receipt2.Failed {
+		t.Fatalf("Test_Client_Domain_register_Invalid_Name_Existed, Domain name repeated registration successully")
+	}
+
+	if !strings.Contains(receipt2.Result, "already exists") {
+		t.Fatalf("Test_Client_Domain_register_Invalid_Name_Existed result does not contain already exists, Result:%s", receipt2.Result)
+	}
+}
+
+// a name registered by one account can not be taken over by another one
+func Test_Client_Domain_register_Existed_By_Other_Account(t *testing.T) {
+	name := uniqueName(12)
+	receipt1 := domainRegister(t, "Test_Client_Domain_register_Existed_By_Other_Account", common.KeyFileShard1_2, name)
+	if receipt1.Failed {
+		t.Fatalf("Test_Client_Domain_register_Existed_By_Other_Account recepit error, %s", receipt1.Result)
+	}
+
+	receipt2 := domainRegister(t, "Test_Client_Domain_register_Existed_By_Other_Account", common.KeyFileShard1_3, name)
+	if !receipt2.Failed {
+		t.Fatalf("Test_Client_Domain_register_Existed_By_Other_Account, another account registered the name")
+	}
+
+	if !strings.Contains(receipt2.Result, "already exists") {
+		t.Fatalf("Test_Client_Domain_register_Existed_By_Other_Account result does not contain already exists, Result:%s", receipt2.Result)
+	}
+
+	checkOwner(t, "Test_Client_Domain_register_Existed_By_Other_Account", name, common.AccountShard1_2)
+}
+
+func Test_Client_Domain_owner_Invalid_KeyFile(t *testing.T) {
+	validateInfo := `invalid sender key file`
+	domainInvalid(t, "Test_Client_Domain_owner_Invalid_KeyFile", "owner", "common.KeyFileShard1_1",
+		"123456", "15", "200000", "", "game", validateInfo)
+}
+
+func Test_Client_Domain_owner_Unmatched_keyfile_And_Pass(t *testing.T) {
+	validateInfo := `invalid sender key file`
+	domainInvalid(t, "Test_Client_Domain_owner_Unmatched_keyfile_And_Pass", "owner", common.KeyFileShard1_1,
+		"123456", "15", "200000", "", "game", validateInfo)
+}
+
+func Test_Client_Domain_owner_Invalid_PriceValue(t *testing.T) {
+	validateInfo := `invalid gas price value`
+	domainInvalid(t, "Test_Client_Domain_owner_Invalid_PriceValue", "owner", common.KeyFileShard1_1,
+		"123", "q2", "200000", "", "game", validateInfo)
+}
+
+func Test_Client_Domain_owner_Invalid_Gas(t *testing.T) {
+	validateInfo := `invalid value "qw" for flag -gas: strconv.ParseUint: parsing "qw"`
+	domainInvalid(t, "Test_Client_Domain_owner_Invalid_Gas", "owner", common.KeyFileShard1_1,
+		"123", "15", "qw", "", "game", validateInfo)
+}
+
+func Test_Client_Domain_owner_Invalid_Nonce(t *testing.T) {
+	validateInfo := `invalid value "er" for flag -nonce: strconv.ParseUint: parsing "er": invalid syntax`
+	domainInvalid(t, "Test_Client_Domain_owner_Invalid_Nonce", "owner", common.KeyFileShard1_1,
+		"123", "15", "200000", "er", "game", validateInfo)
+}
+
+func Test_Client_Domain_owner_Invalid_Name_Empty(t *testing.T) {
+	validateInfo := `name is empty`
+	domainInvalid(t, "Test_Client_Domain_owner_Invalid_Name_Empty", "owner", common.KeyFileShard1_1,
+		"123", "15", "200000", "", "", validateInfo)
+}
+
+func Test_Client_Domain_owner_Invalid_Name_Exceed_Max_Length(t *testing.T) {
+	validateInfo := `name too long`
+	domainInvalid(t, "Test_Client_Domain_owner_Invalid_Name_Exceed_Max_Length", "owner", common.KeyFileShard1_1,
+		"123", "15", "200000", "", uniqueName(len(seele.EmptyHash)+1), validateInfo)
+}
+
+func Test_Client_Domain_owner_Invalid_Name_Not_Found(t *testing.T) {
+	receipt1 := domainOwner(t, "Test_Client_Domain_owner_Invalid_Name_Not_Found", uniqueName(12))
+	if !receipt1.Failed {
+		t.Fatalf("Test_Client_Domain_owner_Invalid_Name_Not_Found get domain, result:%s", receipt1.Result)
+	}
+
+	if !strings.Contains(receipt1.Result, "Failed to get data with key") {
+		t.Fatalf("Test_Client_Domain_owner_Invalid_Name_Not_Found, result:%s", receipt1.Result)
+	}
+}
+
+// the owner is looked up from another account than the one that registered the name
+func Test_Client_Domain_owner(t *testing.T) {
+	name := uniqueName(12)
+	receipt1 := domainRegister(t, "Test_Client_Domain_owner", common.KeyFileShard1_2, name)
+	if receipt1.Failed {
+		t.Fatalf("Test_Client_Domain_owner register domain error, result:%s", receipt1.Result)
+	}
+
+	checkOwner(t, "Test_Client_Domain_owner", name, common.AccountShard1_2)
+}
+
+func checkOwner(t *testing.T, funcName, name, owner string) {
+	receipt := domainOwner(t, funcName, name)
+	if receipt.Failed {
+		t.Fatalf("%s get domain, result:%s", funcName, receipt.Result)
+	}
+
+	if !strings.Contains(strings.ToLower(receipt.Result), strings.TrimPrefix(owner, "0x")) {
+		t.Fatalf("%s owner of %s is %s, expected %s", funcName, name, receipt.Result, owner)
+	}
+}
+
+func domainInvalid(t *testing.T, funcName, subcommand, keyFile, passWord, price, gas, nonce, domainName, validateInfo string) {
+	if len(nonce) == 0 {
+		accountNonce, err := common.GetNonce(t, common.CmdClient, common.AccountShard1_1, server)
+		if err != nil {
+			t.Fatalf("%s, err:%s", funcName, err)
+		}
+
+		nonce = fmt.Sprintf("%d", accountNonce)
+	}
+
+	cmd := exec.Command(common.CmdClient, "domain", subcommand, "--from", keyFile, "--price", price, "--gas", gas,
+		"--nonce", nonce, "--name", domainName, "--address", server)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("%s err: %s", funcName, err)
+	}
+
+	defer stdin.Close()
+
+	var outErr bytes.Buffer
+	cmd.Stderr = &outErr
+
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("%s: An error occured: %s", funcName, err)
+	}
+
+	io.WriteString(stdin, passWord+"\n")
+	cmd.Wait()
+
+	errStr := outErr.String()
+	if !strings.Contains(errStr, validateInfo) {
+		t.Fatalf("%s get err=:%s, should be %s", funcName, errStr, validateInfo)
+	}
+}
+
+func domainRegister(t *testing.T, funcName, keyFile, domainName string) *common.ReceiptInfo {
+	tx, err := common.DomainRegister(t, common.CmdClient, keyFile, domainName, server)
+	if err != nil {
+		t.Fatalf("%s register domain err: %s", funcName, err)
+	}
+
+	receipt, err := common.WaitReceipt(t, common.CmdClient, tx.Hash, server)
+	if err != nil {
+		t.Fatalf("%s get receipt err: %s", funcName, err)
+	}
+
+	return receipt
+}
+
+func domainOwner(t *testing.T, funcName, domainName string) *common.ReceiptInfo {
+	tx, err := common.DomainOwner(t, common.CmdClient, common.KeyFileShard1_1, domainName, server)
+	if err != nil {
+		t.Fatalf("%s domain owner err: %s", funcName, err)
+	}
+
+	receipt, err := common.WaitReceipt(t, common.CmdClient, tx.Hash, server)
+	if err != nil {
+		t.Fatalf("%s get receipt err: %s", funcName, err)
+	}
+
+	return receipt
+}
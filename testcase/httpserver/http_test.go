@@ -0,0 +1,329 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/node"
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+const (
+	templateFile  = "node.json"
+	allowedOrigin = "http://allowed.example"
+	deniedOrigin  = "http://denied.example"
+	allowedHost   = "allowed.example"
+	deniedHost    = "denied.example"
+	// oversized is far above the request size limit of the http server
+	oversized = 8 * 1024 * 1024
+)
+
+var httpClient = &http.Client{Timeout: 20 * time.Second}
+
+func freeAddr(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("find free port err: %s", err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+// boot starts a node from the template with the http server settings and
+// returns its http address and a func stopping it.
+func boot(t *testing.T, name string, crossorigins, whiteHost []string) (string, func()) {
+	if _, err := os.Stat(common.CmdNode); err != nil {
+		t.Skipf("%s no node binary to boot: %s", name, err)
+	}
+
+	var config map[string]map[string]interface{}
+	data, err := ioutil.ReadFile(templateFile)
+	if err == nil {
+		err = json.Unmarshal(data, &config)
+	}
+	if err != nil {
+		t.Fatalf("%s read %s err: %s", name, templateFile, err)
+	}
+
+	dir, err := ioutil.TempDir("", "httpserver")
+	if err != nil {
+		t.Fatalf("%s temp dir err: %s", name, err)
+	}
+
+	rpcAddr, httpAddr := freeAddr(t), freeAddr(t)
+	config["basic"]["address"], config["basic"]["dataDir"] = rpcAddr, filepath.Join(dir, "data")
+	config["p2p"]["address"], config["wsserver"]["address"] = freeAddr(t), freeAddr(t)
+	config["httpServer"]["address"] = httpAddr
+	config["httpServer"]["crossorigins"], config["httpServer"]["whiteHost"] = crossorigins, whiteHost
+
+	configFile := filepath.Join(dir, "node.json")
+	if data, err = json.MarshalIndent(config, "", "\t"); err == nil {
+		err = ioutil.WriteFile(configFile, data, 0644)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("%s write node config err: %s", name, err)
+	}
+
+	n := node.New(common.CmdNode, name, configFile, rpcAddr)
+	n.LogFile = filepath.Join(dir, "node.log")
+	if err = n.Start(); err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("%s boot node err: %s", name, err)
+	}
+
+	// the http server may listen a moment after the rpc port
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(200 * time.Millisecond) {
+		conn, err := net.DialTimeout("tcp", httpAddr, time.Second)
+		if err == nil {
+			conn.Close()
+			break
+		}
+
+		if time.Now().After(deadline) {
+			n.Stop()
+			os.RemoveAll(dir)
+			t.Fatalf("%s http server %s is not reachable: %s", name, httpAddr, err)
+		}
+	}
+
+	return httpAddr, func() {
+		n.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+// request sends a request to the http server, an empty host keeps the address.
+func request(t *testing.T, name, method, addr, host string, header map[string]string, body []byte) (*http.Response, []byte) {
+	req, err := http.NewRequest(method, "http://"+addr+"/", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("%s new request err: %s", name, err)
+	}
+
+	if host != "" {
+		req.Host = host
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s err: %s", name, method, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s read response err: %s", name, err)
+	}
+
+	return resp, data
+}
+
+func call(method string, id int) []byte {
+	data, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": []interface{}{}})
+	return data
+}
+
+// rpcResponse is a json rpc response over http
+type rpcResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func decode(t *testing.T, name string, data []byte, v interface{}) {
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("%s invalid response %s: %s", name, data, err)
+	}
+}
+
+func preflight(t *testing.T, name, addr, origin string) *http.Response {
+	resp, _ := request(t, name, http.MethodOptions, addr, "", map[string]string{
+		"Origin":                         origin,
+		"Access-Control-Request-Method":  http.MethodPost,
+		"Access-Control-Request-Headers": "content-type",
+	}, nil)
+	return resp
+}
+
+// a node with one allowed origin and host answers only them, and rejects
+// oversized, malformed and mistyped requests without going down.
+func Test_HTTP_Restricted(t *testing.T) {
+	addr, stop := boot(t, "Test_HTTP_Restricted", []string{allowedOrigin}, []string{allowedHost})
+	defer stop()
+
+	t.Run("Preflight", func(t *testing.T) {
+		resp := preflight(t, "Test_HTTP_Restricted preflight", addr, allowedOrigin)
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != allowedOrigin {
+			t.Fatalf("Test_HTTP_Restricted preflight of %s allows origin %q", allowedOrigin, got)
+		}
+
+		if got := resp.Header.Get("Access-Control-Allow-Methods"); !strings.Contains(strings.ToUpper(got), http.MethodPost) {
+			t.Fatalf("Test_HTTP_Restricted preflight allows methods %q", got)
+		}
+
+		if got := resp.Header.Get("Access-Control-Allow-Headers"); !strings.Contains(strings.ToLower(got), "content-type") {
+			t.Fatalf("Test_HTTP_Restricted preflight allows headers %q", got)
+		}
+
+		resp = preflight(t, "Test_HTTP_Restricted preflight", addr, deniedOrigin)
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "" {
+			t.Fatalf("Test_HTTP_Restricted preflight of %s allows origin %q", deniedOrigin, got)
+		}
+	})
+
+	t.Run("Origin", func(t *testing.T) {
+		resp, data := request(t, "Test_HTTP_Restricted origin", http.MethodPost, addr, "", map[string]string{"Origin": allowedOrigin}, call("seele_getInfo", 1))
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Test_HTTP_Restricted call from %s returns %s: %s", allowedOrigin, resp.Status, data)
+		}
+
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != allowedOrigin {
+			t.Fatalf("Test_HTTP_Restricted call from %s allows origin %q", allowedOrigin, got)
+		}
+
+		if got := resp.Header.Get("Vary"); !strings.Contains(got, "Origin") {
+			t.Fatalf("Test_HTTP_Restricted response varies on %q, not on Origin", got)
+		}
+
+		resp, _ = request(t, "Test_HTTP_Restricted origin", http.MethodPost, addr, "", map[string]string{"Origin": deniedOrigin}, call("seele_getInfo", 1))
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "" {
+			t.Fatalf("Test_HTTP_Restricted call from %s allows origin %q", deniedOrigin, got)
+		}
+	})
+
+	t.Run("Host", func(t *testing.T) {
+		port := addr[strings.LastIndex(addr, ":"):]
+		for _, host := range []string{allowedHost, allowedHost + port, addr} {
+			if resp, data := request(t, "Test_HTTP_Restricted host", http.MethodPost, addr, host, nil, call("seele_getInfo", 1)); resp.StatusCode != http.StatusOK {
+				t.Fatalf("Test_HTTP_Restricted host %s returns %s: %s", host, resp.Status, data)
+			}
+		}
+
+		for _, host := range []string{deniedHost, deniedHost + port, "sub." + allowedHost} {
+			if resp, data := request(t, "Test_HTTP_Restricted host", http.MethodPost, addr, host, nil, call("seele_getInfo", 1)); resp.StatusCode != http.StatusForbidden {
+				t.Fatalf("Test_HTTP_Restricted host %s returns %s, want 403: %s", host, resp.Status, data)
+			}
+		}
+	})
+
+	t.Run("Body", func(t *testing.T) {
+		body := append([]byte(`{"jsonrpc":"2.0","id":1,"method":"seele_getInfo","params":["`), bytes.Repeat([]byte("a"), oversized)...)
+		body = append(body, `"]}`...)
+		if resp, _ := request(t, "Test_HTTP_Restricted body", http.MethodPost, addr, "", nil, body); resp.StatusCode != http.StatusRequestEntityTooLarge {
+			t.Fatalf("Test_HTTP_Restricted body of %d bytes returns %s, want 413", len(body), resp.Status)
+		}
+
+		if resp, _ := request(t, "Test_HTTP_Restricted body", http.MethodPost, addr, "", map[string]string{"Content-Type": "text/plain"}, call("seele_getInfo", 1)); resp.StatusCode != http.StatusUnsupportedMediaType {
+			t.Fatalf("Test_HTTP_Restricted text/plain body returns %s, want 415", resp.Status)
+		}
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		cases := []struct {
+			name string
+			body string
+			code int
+		}{
+			{"truncated", `{"jsonrpc":"2.0","id":1,"method":`, -32700},
+			{"not json", `seele_getInfo`, -32700},
+			{"unknown method", `{"jsonrpc":"2.0","id":1,"method":"seele_noSuchMethod","params":[]}`, -32601},
+			{"no namespace", `{"jsonrpc":"2.0","id":1,"method":"getInfo","params":[]}`, -32601},
+			{"invalid params", `{"jsonrpc":"2.0","id":1,"method":"seele_getBlockByHeight","params":"height"}`, -32602},
+		}
+
+		for _, c := range cases {
+			resp, data := request(t, "Test_HTTP_Restricted malformed", http.MethodPost, addr, "", nil, []byte(c.body))
+			var response rpcResponse
+			decode(t, "Test_HTTP_Restricted malformed "+c.name, data, &response)
+			if response.Error == nil || response.Error.Code != c.code {
+				t.Fatalf("Test_HTTP_Restricted malformed %s returns %s %s, want error %d", c.name, resp.Status, data, c.code)
+			}
+		}
+
+		// the node still serves after the malformed requests
+		_, data := request(t, "Test_HTTP_Restricted malformed", http.MethodPost, addr, "", nil, call("seele_getInfo", 7))
+		var response rpcResponse
+		decode(t, "Test_HTTP_Restricted malformed", data, &response)
+		if response.Error != nil || len(response.Result) == 0 || string(response.ID) != "7" {
+			t.Fatalf("Test_HTTP_Restricted valid call after malformed ones returns %s", data)
+		}
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		body := []byte("[" + string(call("seele_getInfo", 1)) + "," + string(call("seele_noSuchMethod", 2)) + "," + string(call("seele_getInfo", 3)) + "]")
+		_, data := request(t, "Test_HTTP_Restricted batch", http.MethodPost, addr, "", nil, body)
+
+		var responses []rpcResponse
+		decode(t, "Test_HTTP_Restricted batch", data, &responses)
+		if len(responses) != 3 {
+			t.Fatalf("Test_HTTP_Restricted batch of 3 returns %d responses: %s", len(responses), data)
+		}
+
+		byID := make(map[string]rpcResponse)
+		for _, response := range responses {
+			byID[string(response.ID)] = response
+		}
+
+		for _, id := range []string{"1", "3"} {
+			if response, ok := byID[id]; !ok || response.Error != nil || len(response.Result) == 0 {
+				t.Fatalf("Test_HTTP_Restricted batch call %s returns %s", id, data)
+			}
+		}
+
+		if response, ok := byID["2"]; !ok || response.Error == nil || response.Error.Code != -32601 {
+			t.Fatalf("Test_HTTP_Restricted batch unknown method returns %s", data)
+		}
+
+		_, data = request(t, "Test_HTTP_Restricted batch", http.MethodPost, addr, "", nil, []byte("[]"))
+		var response rpcResponse
+		decode(t, "Test_HTTP_Restricted empty batch", data, &response)
+		if response.Error == nil || response.Error.Code != -32600 {
+			t.Fatalf("Test_HTTP_Restricted empty batch returns %s, want error -32600", data)
+		}
+	})
+}
+
+// a node with wildcard settings answers every origin and host.
+func Test_HTTP_Wildcard(t *testing.T) {
+	addr, stop := boot(t, "Test_HTTP_Wildcard", []string{"*"}, []string{"*"})
+	defer stop()
+
+	for _, origin := range []string{allowedOrigin, deniedOrigin} {
+		resp := preflight(t, "Test_HTTP_Wildcard preflight", addr, origin)
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "*" && got != origin {
+			t.Fatalf("Test_HTTP_Wildcard preflight of %s allows origin %q", origin, got)
+		}
+
+		resp, data := request(t, "Test_HTTP_Wildcard origin", http.MethodPost, addr, deniedHost, map[string]string{"Origin": origin}, call("seele_getInfo", 1))
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Test_HTTP_Wildcard call from %s on host %s returns %s: %s", origin, deniedHost, resp.Status, data)
+		}
+
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "*" && got != origin {
+			t.Fatalf("Test_HTTP_Wildcard call from %s allows origin %q", origin, got)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package keystore
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+// keysPerShard is the number of keys generated for every shard
+const keysPerShard = 3
+
+var (
+	publicRe  = regexp.MustCompile(`public key:\s*(0x[0-9a-fA-F]+)`)
+	privateRe = regexp.MustCompile(`private key:\s*(0x[0-9a-fA-F]+)`)
+	// servers of the shards, a shard 1 account is refused by the shard 2 node
+	servers = map[uint]string{1: common.ServerAddr, 2: common.ServertwoAddr}
+)
+
+type key struct {
+	address    string
+	privateKey string
+}
+
+// parseKey reads the keys printed by key and deckeyfile.
+func parseKey(output []byte) (*key, bool) {
+	public, private := publicRe.FindSubmatch(output), privateRe.FindSubmatch(output)
+	if public == nil || private == nil {
+		return nil, false
+	}
+
+	return &key{strings.ToLower(string(public[1])), strings.ToLower(string(private[1]))}, true
+}
+
+func newKey(t *testing.T, name string, shard int) *key {
+	output, err := exec.Command(common.CmdClient, "key", "--shard", strconv.Itoa(shard)).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s key --shard %d err: %s %s", name, shard, err, output)
+	}
+
+	k, ok := parseKey(output)
+	if !ok {
+		t.Fatalf("%s key --shard %d prints no key: %s", name, shard, output)
+	}
+
+	return k
+}
+
+// runWithInput runs the cli with the lines on stdin.
+func runWithInput(input string, args ...string) ([]byte, error) {
+	cmd := exec.Command(common.CmdClient, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	io.WriteString(stdin, input)
+	stdin.Close()
+	err = cmd.Wait()
+	return out.Bytes(), err
+}
+
+func saveKey(t *testing.T, name, privateKey, file, password string) {
+	if output, err := runWithInput(password+"\n"+password+"\n", "savekey", "--privatekey", privateKey, "--file", file); err != nil {
+		t.Fatalf("%s savekey err: %s %s", name, err, output)
+	}
+}
+
+func decKeyFile(file, password string) (*key, []byte, error) {
+	output, err := runWithInput(password+"\n", "deckeyfile", "--file", file)
+	if err != nil {
+		return nil, output, err
+	}
+
+	k, _ := parseKey(output)
+	return k, output, nil
+}
+
+func shardOfPrivateKey(t *testing.T, name, privateKey string) uint {
+	output, err := exec.Command(common.CmdClient, "getshardnum", "--privatekey", privateKey).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s getshardnum --privatekey err: %s %s", name, err, output)
+	}
+
+	output = bytes.TrimSpace(output)
+	shard, err := strconv.ParseUint(string(output[bytes.LastIndexByte(output, ' ')+1:]), 10, 32)
+	if err != nil {
+		t.Fatalf("%s getshardnum --privatekey prints %s", name, output)
+	}
+
+	return uint(shard)
+}
+
+func randomPassword(r *rand.Rand) string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#%^&*_-+="
+	password := make([]byte, 8+r.Intn(17))
+	for i := range password {
+		password[i] = alphabet[r.Intn(len(alphabet))]
+	}
+
+	return string(password)
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatalf("temp dir err: %s", err)
+	}
+
+	return dir
+}
+
+// keys generated for a shard belong to it, survive a save and decrypt with a
+// random password, and are refused by the nodes of the other shards.
+func Test_Keystore_RoundTrip(t *testing.T) {
+	name := "Test_Keystore_RoundTrip"
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	seed := time.Now().UnixNano()
+	t.Logf("%s password seed %d", name, seed)
+	r := rand.New(rand.NewSource(seed))
+
+	for shard := 1; shard <= common.CurShard; shard++ {
+		for i := 0; i < keysPerShard; i++ {
+			k := newKey(t, name, shard)
+
+			if got, err := common.GetShardNum(t, common.CmdClient, k.address); err != nil || got != uint(shard) {
+				t.Fatalf("%s key --shard %d makes %s of shard %d, err: %v", name, shard, k.address, got, err)
+			}
+
+			if got := shardOfPrivateKey(t, name, k.privateKey); got != uint(shard) {
+				t.Fatalf("%s key --shard %d makes a private key of shard %d", name, shard, got)
+			}
+
+			for s, server := range servers {
+				_, err := common.GetBalance(t, common.CmdClient, k.address, server)
+				if s == uint(shard) && err != nil {
+					t.Fatalf("%s getbalance of %s on its shard %d err: %s", name, k.address, shard, err)
+				}
+
+				if s != uint(shard) && err == nil {
+					t.Fatalf("%s getbalance of %s of shard %d succeeds on shard %d", name, k.address, shard, s)
+				}
+			}
+
+			file, password := filepath.Join(dir, k.address), randomPassword(r)
+			saveKey(t, name, k.privateKey, file, password)
+
+			var stored struct {
+				Address string `json:"address"`
+			}
+			if data, err := ioutil.ReadFile(file); err != nil || json.Unmarshal(data, &stored) != nil {
+				t.Fatalf("%s savekey writes an invalid keyfile %s, err: %v", name, file, err)
+			}
+
+			if strings.ToLower(stored.Address) != k.address {
+				t.Fatalf("%s keyfile of %s holds address %s", name, k.address, stored.Address)
+			}
+
+			decrypted, output, err := decKeyFile(file, password)
+			if err != nil || decrypted == nil {
+				t.Fatalf("%s deckeyfile of %s with its password err: %v %s", name, k.address, err, output)
+			}
+
+			if *decrypted != *k {
+				t.Fatalf("%s keyfile of %s decrypts to %+v", name, k.address, decrypted)
+			}
+		}
+	}
+
+	// shard 0 lets the cli pick any shard
+	k := newKey(t, name, 0)
+	if got, err := common.GetShardNum(t, common.CmdClient, k.address); err != nil || got < 1 || got > uint(common.CurShard) {
+		t.Fatalf("%s key --shard 0 makes %s of shard %d, err: %v", name, k.address, got, err)
+	}
+}
+
+// a keyfile does not decrypt with a wrong password, a changed ciphertext or
+// mac, when truncated or of an unknown version, and never claims an address
+// it does not hold.
+func Test_Keystore_Corrupted(t *testing.T) {
+	name := "Test_Keystore_Corrupted"
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	k := newKey(t, name, 1)
+	file, password := filepath.Join(dir, "key"), "corrupt-123"
+	saveKey(t, name, k.privateKey, file, password)
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("%s read keyfile err: %s", name, err)
+	}
+
+	if decrypted, output, err := decKeyFile(file, password+"x"); err == nil && decrypted != nil {
+		t.Fatalf("%s keyfile decrypts with a wrong password: %s", name, output)
+	}
+
+	if decrypted, output, err := decKeyFile(file, ""); err == nil && decrypted != nil {
+		t.Fatalf("%s keyfile decrypts with an empty password: %s", name, output)
+	}
+
+	modify := func(change func(keyfile map[string]interface{}, crypto map[string]interface{})) []byte {
+		var keyfile map[string]interface{}
+		if err := json.Unmarshal(data, &keyfile); err != nil {
+			t.Fatalf("%s invalid keyfile %s: %s", name, data, err)
+		}
+
+		crypto, ok := keyfile["crypto"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("%s keyfile has no crypto: %s", name, data)
+		}
+
+		change(keyfile, crypto)
+		modified, err := json.Marshal(keyfile)
+		if err != nil {
+			t.Fatalf("%s marshal keyfile err: %s", name, err)
+		}
+
+		return modified
+	}
+
+	flip := func(value interface{}) string {
+		s, _ := value.(string)
+		if len(s) < 4 {
+			return "00"
+		}
+
+		mid, replaced := len(s)/2, byte('0')
+		if s[mid] == '0' {
+			replaced = '1'
+		}
+		return s[:mid] + string(replaced) + s[mid+1:]
+	}
+
+	other := newKey(t, name, 1)
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"ciphertext", modify(func(_, c map[string]interface{}) { c["ciphertext"] = flip(c["ciphertext"]) })},
+		{"mac", modify(func(_, c map[string]interface{}) { c["mac"] = flip(c["mac"]) })},
+		{"salt", modify(func(_, c map[string]interface{}) { c["salt"] = flip(c["salt"]) })},
+		{"version 0", modify(func(f, _ map[string]interface{}) { f["version"] = 0 })},
+		{"version 2", modify(func(f, _ map[string]interface{}) { f["version"] = 2 })},
+		{"truncated", data[:len(data)/2]},
+		{"empty", []byte{}},
+	}
+
+	for _, c := range cases {
+		corrupted := filepath.Join(dir, strings.Replace(c.name, " ", "-", -1))
+		if err := ioutil.WriteFile(corrupted, c.data, 0600); err != nil {
+			t.Fatalf("%s write %s err: %s", name, corrupted, err)
+		}
+
+		if decrypted, output, err := decKeyFile(corrupted, password); err == nil && decrypted != nil {
+			t.Fatalf("%s keyfile with a changed %s decrypts: %s", name, c.name, output)
+		}
+	}
+
+	// the address of a keyfile is not authenticated, it must not be trusted
+	forged := filepath.Join(dir, "forged")
+	if err := ioutil.WriteFile(forged, modify(func(f, _ map[string]interface{}) { f["address"] = other.address }), 0600); err != nil {
+		t.Fatalf("%s write %s err: %s", name, forged, err)
+	}
+
+	if decrypted, output, err := decKeyFile(forged, password); err == nil && decrypted != nil && decrypted.address == other.address {
+		t.Fatalf("%s keyfile with a forged address claims %s: %s", name, other.address, output)
+	}
+}
@@ -0,0 +1,105 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package testcase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/differ"
+	"github.com/seeleteam/e2e-blackbox/node"
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+const (
+	// differentialBlocks is the number of latest blocks compared
+	differentialBlocks = 100
+	// confirmations keeps the compared blocks away from the heads, which
+	// may still be reorganized
+	confirmations = 6
+)
+
+// differentialEndpoints returns the full and light node of shard 1, false
+// without a topology holding a light node of shard 1.
+func differentialEndpoints(topology *node.Topology) (differ.Endpoint, differ.Endpoint, bool) {
+	full := differ.Endpoint{Command: common.CmdClient, Addr: common.ServerAddr}
+	light := differ.Endpoint{Command: common.CmdLight}
+	if topology == nil {
+		return full, light, false
+	}
+
+	fullFound, lightFound := false, false
+	for _, n := range topology.Shard(1) {
+		if n.Light && !lightFound {
+			light.Addr, lightFound = n.Addr(), true
+		} else if !n.Light && !fullFound {
+			full.Addr, fullFound = n.Addr(), true
+		}
+	}
+
+	return full, light, lightFound
+}
+
+func Test_Light_Differential(t *testing.T) {
+	topology, network, err := node.Setup(common.TopologyFile)
+	if err != nil {
+		t.Fatalf("Test_Light_Differential setup topology err: %s", err)
+	}
+	defer node.Teardown(topology, network)
+
+	full, light, ok := differentialEndpoints(topology)
+	if !ok {
+		t.Skip("Test_Light_Differential needs a light node of shard 1 in the topology")
+	}
+
+	fullHead, err := common.GetBlock(t, full.Command, -1, full.Addr)
+	if err != nil {
+		t.Fatalf("Test_Light_Differential get full node head err: %s", err)
+	}
+
+	lightHead, err := common.GetBlock(t, light.Command, -1, light.Addr)
+	if err != nil {
+		t.Fatalf("Test_Light_Differential get light node head err: %s", err)
+	}
+
+	to := fullHead.Header.Height
+	if lightHead.Header.Height < to {
+		to = lightHead.Header.Height
+	}
+
+	if to <= confirmations {
+		t.Skipf("Test_Light_Differential the chain is too short, height %d", to)
+	}
+	to -= confirmations
+
+	from := uint64(1)
+	if to > differentialBlocks {
+		from = to - differentialBlocks + 1
+	}
+
+	checker := differ.Checker{
+		Full:  full,
+		Light: light,
+		From:  from,
+		To:    to,
+		Accounts: []string{common.AccountShard1_1, common.AccountShard1_2, common.AccountShard1_3,
+			common.AccountShard1_4, common.AccountShard1_5, common.Account1_Aux, common.Account1_Aux2},
+		MaxReceipts: 20,
+		Retries:     5,
+		RetryDelay:  2 * time.Second,
+	}
+
+	report, err := checker.Run()
+	if err != nil {
+		t.Fatalf("Test_Light_Differential err: %s", err)
+	}
+
+	if !report.Consistent() {
+		t.Fatalf("Test_Light_Differential light node diverges from the full node\n%s", report)
+	}
+
+	t.Log(report)
+}
@@ -11,10 +11,12 @@ import (
 	"fmt"
 	"io"
 	"os/exec"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/seeleteam/e2e-blackbox/audit"
 	"github.com/seeleteam/e2e-blackbox/testcase/common"
 )
 
@@ -100,16 +102,31 @@ func Test_Light_GetBlock_ByHeight(t *testing.T) {
 	}
 }
 
-// func Test_Light_GetBlock_ByHash(t *testing.T) {
-// 	cmd := exec.Command(common.CmdLight, "getblock", "--hash", common.BlockHash, "--address", common.ServerAddr)
-// 	if _, err := cmd.CombinedOutput(); err != nil {
-// 		t.Fatalf("getblock error, %s", err)
-// 	}
-// }
+func Test_Light_GetBlock_ByHash(t *testing.T) {
+	// the hash is only known for a chain started from a recorded fixture
+	if common.Chain == nil {
+		t.Skip("no chain fixture recorded")
+	}
+
+	cmd := exec.Command(common.CmdLight, "getblock", "--hash", common.BlockHash, "--address", common.ServerAddr)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("getblock error, %s", err)
+	}
+
+	var blockInfo common.BlockInfo
+	if err = json.Unmarshal(output, &blockInfo); err != nil {
+		t.Fatalf("Test_Light_GetBlock_ByHash: %s", err)
+	}
+
+	if blockInfo.Hash != common.BlockHash || blockInfo.Header.Height != common.KnownHeight {
+		t.Fatalf("Test_Light_GetBlock_ByHash returns block %s at height %d, want the fixture block", blockInfo.Hash, blockInfo.Header.Height)
+	}
+}
 
 func Test_Light_GetBlock_Fulltx(t *testing.T) {
 	// getblock fulltx support.
-	cmd := exec.Command(common.CmdLight, "getblock", "--height", "1", "--fulltx", "--address", common.ServerAddr)
+	cmd := exec.Command(common.CmdLight, "getblock", "--height", strconv.FormatUint(common.KnownHeight, 10), "--fulltx", "--address", common.ServerAddr)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		t.Fatalf("getblock error, %s", err)
 	} else {
@@ -651,73 +668,29 @@ func Test_Light_GetTxInBlock_ByHash0x(t *testing.T) {
 // 	}
 // }
 
+// Test_CheckChain_Consistent audits the whole chain from genesis, `run audit`
+// does the same incrementally.
 func Test_CheckChain_Consistent(t *testing.T) {
-	block, err := common.GetBlock(t, common.CmdClient, -1, common.ServerAddr)
-	if err != nil {
-		t.Fatalf("Test_CheckChain_Consistent getBlock err. %s", err)
-	}
-
-	toHeight := block.Header.Height
-	if toHeight > 10 {
-		toHeight = toHeight - 6
+	auditor := &audit.Auditor{
+		Client:        common.CmdClient,
+		Addr:          common.ServerAddr,
+		Confirmations: 6,
+		MaxDrift:      15 * time.Second,
+		Difficulty:    audit.SeeleDifficulty,
+		Reward:        audit.SeeleReward,
 	}
 
-	block, err = common.GetBlock(t, common.CmdClient, 0, common.ServerAddr)
+	result, err := auditor.Run(nil)
 	if err != nil {
-		t.Fatalf("Test_CheckChain_Consistent getBlock err. %s", err)
+		t.Fatalf("Test_CheckChain_Consistent audit err. %s", err)
 	}
 
-	//	toHeight = 2001
-	preHash, preTimestamp, preHeight := block.Hash, block.Header.CreateTimestamp, block.Header.Height
-
-	allTime := uint32(0)
-	maxTime := uint32(0)
-	intL := make([]int, 10)
-	allTXs := 0
-	blockCnt := 0
-	for cur := uint64(1); cur <= toHeight; cur++ {
-		block, err = common.GetBlock(t, common.CmdClient, int64(cur), common.ServerAddr)
-		if err != nil {
-			t.Fatalf("Test_CheckChain_Consistent getBlock err. %s", err)
-		}
-
-		if block.Header.PreviousBlockHash != preHash || block.Header.Height != cur {
-			t.Fatalf("Test_CheckChain_Consistent preHash not match: preHash=%s curHash=%s curHeight=%d cur=%d", preHash, block.Header.PreviousBlockHash, block.Header.Height, cur)
-		}
-
-		if block.Header.CreateTimestamp < preTimestamp {
-			t.Fatalf("Test_CheckChain_Consistent timestamp not match. curHeight=%d cur=%d", block.Header.Height, cur)
-		}
-
-		if block.Header.Height != preHeight+1 {
-			t.Fatalf("Test_CheckChain_Consistent height not match. curHeight=%d cur=%d", block.Header.Height, cur)
-		}
-
-		diffTime := block.Header.CreateTimestamp - preTimestamp
-		blockCnt = blockCnt + 1
-		if diffTime < 10000 {
-			allTime = allTime + diffTime
-			idx := diffTime / 10
-			if idx > 9 {
-				idx = 9
-			}
-
-			intL[idx] = intL[idx] + 1
-			if maxTime < diffTime {
-				maxTime = diffTime
-			}
-		}
-		preHash, preTimestamp, preHeight = block.Hash, block.Header.CreateTimestamp, block.Header.Height
-		allTXs = allTXs + len(block.Transactions)
-		if cur%1000 == 0 {
-			fmt.Println("Test_CheckChain_Consistent checked. cur=", cur, " txs=", allTXs)
-		}
+	for _, f := range result.Findings {
+		t.Errorf("Test_CheckChain_Consistent [%s] block %d %s: %s", f.Check, f.Height, f.Hash, f.Message)
 	}
 
-	// fmt.Println("Test_CheckChain_Consistent average block-creation time=", allTime/uint32(toHeight), " maxTime =", maxTime)
-	// fmt.Println("Test_CheckChain_Consistent txs=", allTXs, ", blockCnt=", blockCnt, " avg_txs/block =", allTXs/blockCnt)
-	// fmt.Println("Test_CheckChain_Consistent txs=", allTXs)
-	// //for _, cnt := range intL {
-	// fmt.Println("Test_CheckChain_Consistent:", intL)
-	//}
+	var stats strings.Builder
+	result.Stats.Write(&stats)
+	result.Stats.WriteHistogram(&stats)
+	t.Log(stats.String())
 }
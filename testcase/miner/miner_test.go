@@ -0,0 +1,366 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package miner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/node"
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+const (
+	// minedBlocks is the number of blocks the fresh coinbase must mine
+	minedBlocks = 5
+	// feeTxs are sent while mining so the blocks carry fees
+	feeTxs = 3
+	// mineTimeout bounds the wait for the blocks of the fresh coinbase
+	mineTimeout = 5 * time.Minute
+	// hashrateWarmup lets the miner restart with the new thread count
+	hashrateWarmup = 15 * time.Second
+	// hashrateScale is the least gain from one to many threads
+	hashrateScale = 1.3
+	// feeFunds pays the fee txs of the fresh sender
+	feeFunds = 10000000
+)
+
+var (
+	addressRe = regexp.MustCompile(`public key:\s*(0x[0-9a-fA-F]+)`)
+	feeTo     = common.Account1_Aux
+)
+
+// miner runs a miner subcommand on the shard 1 node.
+func miner(args ...string) (string, error) {
+	args = append(append([]string{"miner"}, args...), "--address", common.ServerAddr)
+	output, err := exec.Command(common.CmdClient, args...).CombinedOutput()
+	if err != nil {
+		return "", errors.New(string(bytes.TrimSpace(output)))
+	}
+
+	return string(bytes.TrimSpace(output)), nil
+}
+
+func mustMiner(t *testing.T, name string, args ...string) string {
+	output, err := miner(args...)
+	if err != nil {
+		t.Fatalf("%s miner %s err: %s", name, strings.Join(args, " "), err)
+	}
+
+	return output
+}
+
+func height(t *testing.T, name string) int64 {
+	output, err := exec.Command(common.CmdClient, "getinfo", "--address", common.ServerAddr).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s getinfo err: %s %s", name, err, output)
+	}
+
+	var info common.ResGetInfo
+	if err = json.Unmarshal(output, &info); err != nil {
+		t.Fatalf("%s getinfo prints %s: %s", name, output, err)
+	}
+
+	return info.CurrentBlockHeight
+}
+
+// block is a mined block, the first tx pays the reward to the miner.
+type block struct {
+	height       int64
+	coinbase     string
+	reward       int64
+	transactions []common.TxInfoInBlock
+	debts        int
+}
+
+func getBlock(t *testing.T, name string, h int64) *block {
+	output, err := exec.Command(common.CmdClient, "getblock", "--height", strconv.FormatInt(h, 10), "--fulltx", "--address", common.ServerAddr).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s getblock %d err: %s %s", name, h, err, output)
+	}
+
+	var info struct {
+		Transactions []common.TxInfoInBlock `json:"transactions"`
+		Debts        []interface{}          `json:"debts"`
+	}
+	if err = json.Unmarshal(output, &info); err != nil {
+		t.Fatalf("%s getblock %d prints %s: %s", name, h, output, err)
+	}
+
+	if len(info.Transactions) == 0 {
+		t.Fatalf("%s block %d has no reward tx", name, h)
+	}
+
+	reward := info.Transactions[0]
+	return &block{h, strings.ToLower(reward.To), reward.Amount, info.Transactions[1:], len(info.Debts)}
+}
+
+// minedBy returns the blocks after from up to the head paid to the coinbase.
+func minedBy(t *testing.T, name, coinbase string, from int64) []*block {
+	var blocks []*block
+	for h, head := from+1, height(t, name); h <= head; h++ {
+		if b := getBlock(t, name, h); b.coinbase == coinbase {
+			blocks = append(blocks, b)
+		}
+	}
+
+	return blocks
+}
+
+func freshCoinbase(t *testing.T, name string) string {
+	output, err := exec.Command(common.CmdClient, "key", "--shard", "1").CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s key err: %s %s", name, err, output)
+	}
+
+	match := addressRe.FindSubmatch(output)
+	if match == nil {
+		t.Fatalf("%s key prints no address: %s", name, output)
+	}
+
+	return strings.ToLower(string(match[1]))
+}
+
+// exclusive skips the test unless it owns the nodes, stopping the miner or
+// changing its coinbase and threads stalls the receipts of other packages.
+// It starts the topology and returns its teardown.
+func exclusive(t *testing.T, name string) func() {
+	if !node.Exclusive() {
+		t.Skipf("%s reconfigures the miner other packages use, set %s=1 to run it alone", name, node.ExclusiveEnv)
+	}
+
+	topology, network, err := node.Setup(common.TopologyFile)
+	if err != nil {
+		t.Fatalf("%s setup topology err: %s", name, err)
+	}
+
+	return func() { node.Teardown(topology, network) }
+}
+
+// restore puts the coinbase, threads and status of the miner back after a test.
+func restore(t *testing.T, name string) func() {
+	coinbase := mustMiner(t, name, "getcoinbase")
+	threads := mustMiner(t, name, "threads")
+	status := mustMiner(t, name, "status")
+
+	return func() {
+		if _, err := miner("setcoinbase", "--coinbase", coinbase); err != nil {
+			t.Errorf("%s restore coinbase %s err: %s", name, coinbase, err)
+		}
+
+		if _, err := miner("setthreads", "--threads", threads); err != nil {
+			t.Errorf("%s restore threads %s err: %s", name, threads, err)
+		}
+
+		current, err := miner("status")
+		if err == nil && current != status {
+			if status == "Running" {
+				_, err = miner("start", "--threads", threads)
+			} else {
+				_, err = miner("stop")
+			}
+		}
+
+		if err != nil {
+			t.Errorf("%s restore status %s err: %s", name, status, err)
+		}
+	}
+}
+
+func ensureRunning(t *testing.T, name string) {
+	if mustMiner(t, name, "status") != "Running" {
+		mustMiner(t, name, "start")
+	}
+}
+
+// blocks mined after setcoinbase pay their reward and the fees of their txs
+// to the new coinbase, and none are mined once the miner stops.
+func Test_Miner_RewardsAndStop(t *testing.T) {
+	name := "Test_Miner_RewardsAndStop"
+	defer exclusive(t, name)()
+	defer restore(t, name)()
+
+	// the fee txs are sent from a fresh account, a committed keyfile is sent
+	// from by other packages and its nonce would race
+	dir, err := ioutil.TempDir("", "miner")
+	if err != nil {
+		t.Fatalf("%s temp dir err: %s", name, err)
+	}
+	defer os.RemoveAll(dir)
+
+	feeFrom, from, err := common.NewKeyFile(t, common.CmdClient, 1, dir)
+	if err != nil {
+		t.Fatalf("%s new fee sender err: %s", name, err)
+	}
+
+	if err = common.FundAccounts(t, common.CmdClient, common.KeyFileShard1_2, common.AccountShard1_2, feeFunds, common.ServerAddr, from); err != nil {
+		t.Fatalf("%s fund fee sender err: %s", name, err)
+	}
+
+	coinbase := freshCoinbase(t, name)
+	start := height(t, name)
+	mustMiner(t, name, "setcoinbase", "--coinbase", coinbase)
+	if got := mustMiner(t, name, "getcoinbase"); strings.ToLower(got) != coinbase {
+		t.Fatalf("%s getcoinbase returns %s after setting %s", name, got, coinbase)
+	}
+	ensureRunning(t, name)
+
+	nonce, err := common.GetNonce(t, common.CmdClient, from, common.ServerAddr)
+	if err != nil {
+		t.Fatalf("%s get nonce of %s err: %s", name, from, err)
+	}
+
+	// fees holds the fee txs not yet seen in a block of the coinbase
+	fees := make(map[string]bool)
+	for i := 0; i < feeTxs; i++ {
+		txHash, _, err := common.SendTxWithPrice(t, common.CmdClient, 1, 1+i, nonce+i, 0, feeFrom, feeTo, "", common.ServerAddr)
+		if err != nil {
+			t.Fatalf("%s sendtx err: %s", name, err)
+		}
+		fees[txHash] = true
+	}
+
+	began := time.Now()
+	deadline := began.Add(mineTimeout)
+	for len(minedBy(t, name, coinbase, start)) < minedBlocks {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s %s mines less than %d blocks in %s", name, coinbase, minedBlocks, mineTimeout)
+		}
+		time.Sleep(2 * time.Second)
+	}
+
+	mustMiner(t, name, "stop")
+	if status := mustMiner(t, name, "status"); status != "Stopped" {
+		t.Fatalf("%s miner status is %s after stop", name, status)
+	}
+
+	// a block being sealed when the miner stops may still land
+	time.Sleep(3 * time.Second)
+	stopped := height(t, name)
+	blocks := minedBy(t, name, coinbase, start)
+
+	// waiting three block intervals of the coinbase makes a missed halt obvious
+	interval := time.Since(began) / time.Duration(len(blocks))
+	if interval < 10*time.Second {
+		interval = 10 * time.Second
+	}
+	time.Sleep(3 * interval)
+
+	if after := minedBy(t, name, coinbase, stopped); len(after) > 0 {
+		t.Fatalf("%s block %d is mined by %s after the miner stopped", name, after[0].height, coinbase)
+	}
+
+	var expected int64
+	debts := false
+	for _, b := range blocks {
+		if b.reward <= 0 {
+			t.Fatalf("%s block %d pays no reward to %s", name, b.height, coinbase)
+		}
+		expected += b.reward
+		debts = debts || b.debts > 0
+
+		for _, tx := range b.transactions {
+			receipt, err := common.GetReceipt(t, common.CmdClient, tx.Hash, common.ServerAddr)
+			if err != nil {
+				t.Fatalf("%s getreceipt %s err: %s", name, tx.Hash, err)
+			}
+			expected += receipt.TotalFee
+			delete(fees, tx.Hash)
+		}
+	}
+
+	balance, err := common.GetBalance(t, common.CmdClient, coinbase, common.ServerAddr)
+	if err != nil {
+		t.Fatalf("%s getbalance of %s err: %s", name, coinbase, err)
+	}
+
+	// the fees of debts are paid to the coinbase too but are not reported per block
+	if balance < expected || (!debts && balance != expected) {
+		t.Fatalf("%s %s has %d after %d blocks, rewards and fees are %d", name, coinbase, balance, len(blocks), expected)
+	}
+
+	if len(fees) == feeTxs {
+		t.Logf("%s no fee tx is mined by %s, only rewards are checked", name, coinbase)
+	}
+}
+
+func hashrate(t *testing.T, name string) float64 {
+	var sum float64
+	const samples = 5
+	for i := 0; i < samples; i++ {
+		rate, err := strconv.ParseFloat(mustMiner(t, name, "hashrate"), 64)
+		if err != nil {
+			t.Fatalf("%s hashrate err: %s", name, err)
+		}
+		sum += rate
+		time.Sleep(2 * time.Second)
+	}
+
+	return sum / samples
+}
+
+// the hashrate grows with the miner threads.
+func Test_Miner_HashrateScales(t *testing.T) {
+	name := "Test_Miner_HashrateScales"
+	defer exclusive(t, name)()
+	threads := runtime.NumCPU()
+	if threads > 4 {
+		threads = 4
+	}
+	if threads < 2 {
+		t.Skipf("%s needs 2 cpus, has %d", name, threads)
+	}
+
+	defer restore(t, name)()
+	ensureRunning(t, name)
+
+	rates := make(map[int]float64)
+	for _, n := range []int{1, threads} {
+		mustMiner(t, name, "setthreads", "--threads", strconv.Itoa(n))
+		if got := mustMiner(t, name, "threads"); got != strconv.Itoa(n) {
+			t.Fatalf("%s threads returns %s after setting %d", name, got, n)
+		}
+
+		time.Sleep(hashrateWarmup)
+		rates[n] = hashrate(t, name)
+	}
+
+	if rates[1] <= 0 {
+		t.Fatalf("%s hashrate with 1 thread is %f", name, rates[1])
+	}
+
+	if rates[threads] < rates[1]*hashrateScale {
+		t.Fatalf("%s hashrate is %f with 1 thread and %f with %d", name, rates[1], rates[threads], threads)
+	}
+}
+
+// a coinbase of another shard is refused and the coinbase stays.
+func Test_Miner_CoinbaseOtherShard(t *testing.T) {
+	name := "Test_Miner_CoinbaseOtherShard"
+	defer exclusive(t, name)()
+	defer restore(t, name)()
+
+	coinbase := freshCoinbase(t, name)
+	mustMiner(t, name, "setcoinbase", "--coinbase", coinbase)
+
+	if _, err := miner("setcoinbase", "--coinbase", common.AccountShard2_1); err == nil {
+		t.Fatalf("%s setcoinbase to shard 2 account %s returns ok", name, common.AccountShard2_1)
+	}
+
+	if got := mustMiner(t, name, "getcoinbase"); strings.ToLower(got) != coinbase {
+		t.Fatalf("%s coinbase is %s after a refused setcoinbase, want %s", name, got, coinbase)
+	}
+}
@@ -0,0 +1,164 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package testcase
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/proxy"
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+// rpcTimeout bounds a cli call through a faulty link.
+const rpcTimeout = 30 * time.Second
+
+func startRPCProxy(t *testing.T) *proxy.Proxy {
+	p := proxy.New("cli->node", common.ServerAddr)
+	if err := p.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("start rpc proxy err: %s", err)
+	}
+
+	return p
+}
+
+func getInfoThrough(addr string) (*common.ResGetInfo, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+
+	start := time.Now()
+	output, err := exec.CommandContext(ctx, common.CmdClient, "getinfo", "--address", addr).CombinedOutput()
+	elapsed := time.Since(start)
+	if err != nil {
+		return nil, elapsed, err
+	}
+
+	var info common.ResGetInfo
+	if err = json.Unmarshal(output, &info); err != nil {
+		return nil, elapsed, err
+	}
+
+	return &info, elapsed, nil
+}
+
+func Test_Fault_RPC_Through_Proxy(t *testing.T) {
+	p := startRPCProxy(t)
+	defer p.Stop()
+
+	info, _, err := getInfoThrough(p.Addr())
+	if err != nil {
+		t.Fatalf("Test_Fault_RPC_Through_Proxy getinfo err: %s", err)
+	}
+
+	direct, _, err := getInfoThrough(common.ServerAddr)
+	if err != nil {
+		t.Fatalf("Test_Fault_RPC_Through_Proxy direct getinfo err: %s", err)
+	}
+
+	if info.Shard != direct.Shard || info.Coinbase != direct.Coinbase {
+		t.Fatalf("Test_Fault_RPC_Through_Proxy proxied node differs: %+v != %+v", info, direct)
+	}
+}
+
+func Test_Fault_RPC_Latency(t *testing.T) {
+	p := startRPCProxy(t)
+	defer p.Stop()
+
+	latency := 500 * time.Millisecond
+	p.SetLatency(latency, 100*time.Millisecond)
+	_, elapsed, err := getInfoThrough(p.Addr())
+	if err != nil {
+		t.Fatalf("Test_Fault_RPC_Latency getinfo err: %s", err)
+	}
+
+	// request and response are both delayed
+	if elapsed < 2*latency {
+		t.Fatalf("Test_Fault_RPC_Latency round trip %s is shorter than the injected latency", elapsed)
+	}
+}
+
+// the response can't come back faster than the bandwidth allows, the bytes
+// are counted by the proxy so that a small block still gives a lower bound.
+func Test_Fault_RPC_Throttle(t *testing.T) {
+	p := startRPCProxy(t)
+	defer p.Stop()
+
+	bandwidth := int64(256)
+	p.SetBandwidth(bandwidth)
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, common.CmdClient, "getblock", "--height", strconv.FormatUint(common.KnownHeight, 10), "--fulltx", "--address", p.Addr())
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("Test_Fault_RPC_Throttle getblock err: %s %s", err, output)
+	}
+
+	_, received := p.Transferred()
+	if received < bandwidth {
+		t.Fatalf("Test_Fault_RPC_Throttle only %d bytes came back through the proxy", received)
+	}
+
+	if minimum := time.Duration(received) * time.Second / time.Duration(bandwidth); elapsed < minimum {
+		t.Fatalf("Test_Fault_RPC_Throttle %d bytes in %s, exceeds the bandwidth limit of %d bytes/s", received, elapsed, bandwidth)
+	}
+}
+
+func Test_Fault_RPC_Blackhole_Timeout(t *testing.T) {
+	p := startRPCProxy(t)
+	defer p.Stop()
+
+	p.SetBlackhole(true)
+	if _, _, err := getInfoThrough(p.Addr()); err == nil {
+		t.Fatal("Test_Fault_RPC_Blackhole_Timeout getinfo succeeded through a blackholed link")
+	}
+
+	p.SetBlackhole(false)
+	if _, _, err := getInfoThrough(p.Addr()); err != nil {
+		t.Fatalf("Test_Fault_RPC_Blackhole_Timeout getinfo after heal err: %s", err)
+	}
+}
+
+func Test_Fault_RPC_Dropped_Connection(t *testing.T) {
+	p := startRPCProxy(t)
+	defer p.Stop()
+
+	p.SetDropRate(1)
+	if _, _, err := getInfoThrough(p.Addr()); err == nil {
+		t.Fatal("Test_Fault_RPC_Dropped_Connection getinfo succeeded while all connections are dropped")
+	}
+
+	p.Reset()
+	if _, _, err := getInfoThrough(p.Addr()); err != nil {
+		t.Fatalf("Test_Fault_RPC_Dropped_Connection getinfo after reset err: %s", err)
+	}
+}
+
+func Test_Fault_Network_Partition(t *testing.T) {
+	network := proxy.NewNetwork()
+	defer network.Close()
+
+	link, err := network.AddLink("cli", "node", common.ServerAddr)
+	if err != nil {
+		t.Fatalf("Test_Fault_Network_Partition add link err: %s", err)
+	}
+
+	network.Partition([]string{"cli"}, []string{"node"})
+	if _, _, err := getInfoThrough(link.Addr()); err == nil {
+		t.Fatal("Test_Fault_Network_Partition getinfo succeeded across the partition")
+	}
+
+	network.Heal()
+	if _, _, err := getInfoThrough(link.Addr()); err != nil {
+		t.Fatalf("Test_Fault_Network_Partition getinfo after heal err: %s", err)
+	}
+}
@@ -0,0 +1,121 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package testcase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/forkwatch"
+	"github.com/seeleteam/e2e-blackbox/node"
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+const (
+	// forkPartition is how long the minority mines on its own branch
+	forkPartition = 2 * time.Minute
+	// forkConvergence bounds the time the shard takes to agree again after healing
+	forkConvergence = 5 * time.Minute
+	forkWindow      = 30
+)
+
+func names(nodes []*node.Node) []string {
+	var result []string
+	for _, n := range nodes {
+		result = append(result, n.Name)
+	}
+
+	return result
+}
+
+// a partitioned node of shard 1 mines its own branch, once healed it must
+// reorganize onto the majority chain, and the txs orphaned by the reorg must
+// be included again.
+func Test_Fork_Partition_Converge(t *testing.T) {
+	if !node.Exclusive() {
+		t.Skipf("Test_Fork_Partition_Converge partitions nodes other packages use, set %s=1 to run it alone", node.ExclusiveEnv)
+	}
+
+	topology, network, err := node.Setup(common.TopologyFile)
+	if err != nil {
+		t.Fatalf("Test_Fork_Partition_Converge setup topology err: %s", err)
+	}
+	defer node.Teardown(topology, network)
+
+	if topology == nil {
+		t.Skip("no topology, there is a single node per shard")
+	}
+
+	nodes := topology.Shard(1)
+	if len(nodes) < 2 {
+		t.Skip("shard 1 has less than two nodes")
+	}
+
+	monitor := &forkwatch.Monitor{Client: common.CmdClient, Window: forkWindow, Period: 2 * time.Second, Events: t.Logf}
+	for _, n := range nodes {
+		monitor.Targets = append(monitor.Targets, forkwatch.Target{Name: n.Name, Addr: n.Addr(), Shard: 1})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		monitor.Watch(ctx)
+		close(done)
+	}()
+
+	minority, majority := nodes[:1], nodes[1:]
+	network.Partition(names(minority), names(majority))
+
+	// a tx sent to the minority is only mined on its branch
+	nonce, err := common.GetNonce(t, common.CmdClient, common.AccountShard1_3, minority[0].Addr())
+	if err != nil {
+		t.Fatalf("Test_Fork_Partition_Converge get nonce err: %s", err)
+	}
+
+	minorityTx, _, err := common.SendTx(t, common.CmdClient, 1, nonce, 0, common.KeyFileShard1_3, common.Account1_Aux2, "", minority[0].Addr())
+	if err != nil {
+		t.Fatalf("Test_Fork_Partition_Converge sendtx err: %s", err)
+	}
+
+	time.Sleep(forkPartition)
+	network.Heal()
+	cancel()
+	<-done
+
+	if err = monitor.WaitConverged(forkConvergence); err != nil {
+		t.Fatalf("Test_Fork_Partition_Converge %s\n%s", err, monitor.Report())
+	}
+
+	report := monitor.Report()
+	t.Logf("Test_Fork_Partition_Converge\n%s", report)
+
+	// whether mined on the minority branch or still pending there, the tx
+	// must end up on the majority chain
+	receipt, err := common.WaitReceipt(t, common.CmdClient, minorityTx, majority[0].Addr())
+	if err != nil {
+		t.Fatalf("Test_Fork_Partition_Converge minority tx %s is lost: %s", minorityTx, err)
+	}
+
+	if receipt.Failed {
+		t.Fatalf("Test_Fork_Partition_Converge minority tx %s failed on the majority chain: %s", minorityTx, receipt.Result)
+	}
+
+	if len(report.Forks) == 0 {
+		t.Skip("Test_Fork_Partition_Converge no fork observed, the minority did not mine during the partition")
+	}
+
+	for _, tx := range report.Orphaned() {
+		receipt, err := common.WaitReceipt(t, common.CmdClient, tx, majority[0].Addr())
+		if err != nil {
+			t.Fatalf("Test_Fork_Partition_Converge orphaned tx %s is lost: %s", tx, err)
+		}
+
+		if receipt.Failed {
+			t.Fatalf("Test_Fork_Partition_Converge orphaned tx %s failed when included again: %s", tx, receipt.Result)
+		}
+	}
+}
@@ -0,0 +1,434 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package sign
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+const (
+	signTo     = common.AccountShard2_1
+	signGas    = 100000
+	goldenFile = "golden.json"
+	// signFunds pays the txs the suite sends, Account2 is topped up to it
+	signFunds = 100000000
+)
+
+var (
+	// the suite signs with the private key of Account2 on shard 2, no other
+	// suite sends from it as there is no keyfile of it
+	signKey  = common.AccountPrivateKey2
+	signFrom = common.Account2
+
+	update = flag.Bool("sign.update", false, "record the golden signatures instead of comparing them")
+
+	hashRe = regexp.MustCompile(`^0x[0-9a-f]{64}$`)
+
+	fundOnce sync.Once
+	fundErr  error
+)
+
+// funded tops Account2 up from a shard 2 keyfile before the first tx is sent,
+// the genesis of the nodes does not know it.
+func funded(t *testing.T, name string) {
+	fundOnce.Do(func() {
+		balance, err := common.GetBalance(t, common.CmdClient, signFrom, common.ServertwoAddr)
+		if err == nil && balance < signFunds {
+			err = common.FundAccounts(t, common.CmdClient, common.KeyFileShard2_3, common.AccountShard2_3, signFunds, common.ServertwoAddr, signFrom)
+		}
+		fundErr = err
+	})
+
+	if fundErr != nil {
+		t.Fatalf("%s fund %s err: %s", name, signFrom, fundErr)
+	}
+}
+
+// signCase is a tx signed by the suite.
+type signCase struct {
+	name    string
+	amount  int
+	price   int
+	nonce   int
+	payload string
+}
+
+// goldenCases have fixed nonces, they are only signed and never sent.
+var goldenCases = []signCase{
+	{"zero", 0, 1, 0, ""},
+	{"amount", 123456789, 1, 0, ""},
+	{"price", 1, 50, 0, ""},
+	{"nonce", 1, 1, 987654, ""},
+	{"payload", 1, 1, 0, "0xdeadbeef"},
+	{"all", 1000000000, 7, 42, "0x0102030405060708090a0b0c0d0e0f"},
+}
+
+func sign(t *testing.T, name string, c signCase) *common.SignedTx {
+	tx, err := common.SignTx(t, common.CmdClient, signKey, signTo, c.amount, c.price, signGas, c.nonce, c.payload)
+	if err != nil {
+		t.Fatalf("%s sign %s err: %s", name, c.name, err)
+	}
+
+	return tx
+}
+
+func compact(t *testing.T, raw json.RawMessage) string {
+	var b bytes.Buffer
+	if err := json.Compact(&b, raw); err != nil {
+		t.Fatalf("invalid signed tx %s: %s", raw, err)
+	}
+
+	return b.String()
+}
+
+func nonce(t *testing.T, name string) int {
+	n, err := common.GetNonce(t, common.CmdClient, signFrom, common.ServertwoAddr)
+	if err != nil {
+		t.Fatalf("%s get nonce err: %s", name, err)
+	}
+
+	return n
+}
+
+func mustReceipt(t *testing.T, name, txHash string) *common.ReceiptInfo {
+	receipt, err := common.WaitReceipt(t, common.CmdClient, txHash, common.ServertwoAddr)
+	if err != nil {
+		t.Fatalf("%s %s", name, err)
+	}
+
+	if receipt.Failed {
+		t.Fatalf("%s tx %s failed: %s", name, txHash, receipt.Result)
+	}
+
+	return receipt
+}
+
+// signedFields are the fields of a signed tx pinned by the cases themselves.
+type signedFields struct {
+	Hash string
+	Data struct {
+		From         string
+		To           string
+		Amount       int64
+		AccountNonce int
+		GasPrice     int
+		GasLimit     int
+		Payload      string
+	}
+	Signature struct {
+		Sig string
+	}
+}
+
+// payloadBytes decodes the payload of a signed tx, printed as hex or base64.
+func payloadBytes(payload string) ([]byte, error) {
+	if strings.HasPrefix(payload, "0x") {
+		return hex.DecodeString(payload[2:])
+	}
+
+	return base64.StdEncoding.DecodeString(payload)
+}
+
+// checkFields compares the signed tx with the case it was signed from, the
+// sender must be the account of the private key.
+func checkFields(raw json.RawMessage, c signCase) error {
+	var tx signedFields
+	if err := json.Unmarshal(raw, &tx); err != nil {
+		return err
+	}
+
+	payload, err := payloadBytes(tx.Data.Payload)
+	if err != nil {
+		return fmt.Errorf("payload %s is neither hex nor base64", tx.Data.Payload)
+	}
+
+	expected, _ := payloadBytes(c.payload)
+	data := tx.Data
+	switch {
+	case !hashRe.MatchString(strings.ToLower(tx.Hash)):
+		return fmt.Errorf("hash %q is not 32 bytes of hex", tx.Hash)
+	case tx.Signature.Sig == "":
+		return fmt.Errorf("signature is empty")
+	case !strings.EqualFold(data.From, signFrom):
+		return fmt.Errorf("from is %s, the key is of %s", data.From, signFrom)
+	case !strings.EqualFold(data.To, signTo):
+		return fmt.Errorf("to is %s, signed for %s", data.To, signTo)
+	case data.Amount != int64(c.amount), data.AccountNonce != c.nonce, data.GasPrice != c.price, data.GasLimit != signGas:
+		return fmt.Errorf("amount %d nonce %d price %d gas %d, signed %d %d %d %d", data.Amount, data.AccountNonce, data.GasPrice, data.GasLimit, c.amount, c.nonce, c.price, signGas)
+	case !bytes.Equal(payload, expected):
+		return fmt.Errorf("payload is %x, signed %s", payload, c.payload)
+	}
+
+	return nil
+}
+
+// the same key and fields always give the same signed tx with exactly these
+// fields, and different fields give different hashes. golden.json pins the
+// bytes as well, it is recorded with -sign.update by a cli build that is known
+// to be good.
+func Test_Sign_Golden(t *testing.T) {
+	signed := make(map[string]string)
+	hashes := make(map[string]string)
+	for _, c := range goldenCases {
+		tx := sign(t, "Test_Sign_Golden", c)
+		first, second := compact(t, tx.Raw), compact(t, sign(t, "Test_Sign_Golden", c).Raw)
+		if first != second {
+			t.Fatalf("Test_Sign_Golden %s signs differently twice:\n%s\n%s", c.name, first, second)
+		}
+
+		if err := checkFields(tx.Raw, c); err != nil {
+			t.Fatalf("Test_Sign_Golden %s %s: %s", c.name, err, first)
+		}
+
+		if other, ok := hashes[tx.Hash]; ok {
+			t.Fatalf("Test_Sign_Golden %s and %s have the same hash %s", c.name, other, tx.Hash)
+		}
+		hashes[tx.Hash] = c.name
+		signed[c.name] = first
+	}
+
+	if *update {
+		data, err := json.MarshalIndent(signed, "", "\t")
+		if err != nil {
+			t.Fatalf("Test_Sign_Golden marshal err: %s", err)
+		}
+
+		if err = ioutil.WriteFile(goldenFile, append(data, '\n'), 0644); err != nil {
+			t.Fatalf("Test_Sign_Golden write %s err: %s", goldenFile, err)
+		}
+		return
+	}
+
+	data, err := ioutil.ReadFile(goldenFile)
+	if os.IsNotExist(err) {
+		// CI must compare the bytes, like the contract suites refuse unchecked artifacts there
+		if os.Getenv("CI") != "" {
+			t.Fatalf("Test_Sign_Golden no golden signatures in %s, record them with -sign.update by a known good cli build", goldenFile)
+		}
+
+		t.Logf("Test_Sign_Golden no golden signatures in %s, only the fields are pinned, record them with -sign.update", goldenFile)
+		return
+	}
+
+	if err != nil {
+		t.Fatalf("Test_Sign_Golden read %s err: %s", goldenFile, err)
+	}
+
+	var golden map[string]string
+	if err = json.Unmarshal(data, &golden); err != nil {
+		t.Fatalf("Test_Sign_Golden invalid %s: %s", goldenFile, err)
+	}
+
+	for _, c := range goldenCases {
+		if golden[c.name] != signed[c.name] {
+			t.Errorf("Test_Sign_Golden %s signs\n%s\nrecorded\n%s", c.name, signed[c.name], golden[c.name])
+		}
+	}
+}
+
+// saveKey writes a keyfile of the signing key, so sendtx can send from it.
+func saveKey(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "sign")
+	if err != nil {
+		t.Fatalf("temp dir err: %s", err)
+	}
+
+	file := filepath.Join(dir, "key")
+	cmd := exec.Command(common.CmdClient, "savekey", "--privatekey", signKey, "--file", file)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("savekey stdin err: %s", err)
+	}
+
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("savekey err: %s", err)
+	}
+
+	io.WriteString(stdin, "123\n123\n")
+	stdin.Close()
+	if err = cmd.Wait(); err != nil {
+		t.Fatalf("savekey err: %s %s", err, out.String())
+	}
+
+	return file
+}
+
+// sendtx from a keyfile sends the tx sign makes offline from its key.
+func Test_Sign_MatchesSendTx(t *testing.T) {
+	funded(t, "Test_Sign_MatchesSendTx")
+	keyfile := saveKey(t)
+	defer os.RemoveAll(filepath.Dir(keyfile))
+
+	cases := []signCase{{"transfer", 3, 1, 0, ""}, {"price", 5, 9, 0, ""}, {"payload", 7, 2, 0, "0xcafe"}}
+	for _, c := range cases {
+		c.nonce = nonce(t, "Test_Sign_MatchesSendTx")
+		signed := sign(t, "Test_Sign_MatchesSendTx", c)
+
+		txHash, _, err := common.SendTxWithPrice(t, common.CmdClient, c.amount, c.price, c.nonce, signGas, keyfile, signTo, c.payload, common.ServertwoAddr)
+		if err != nil {
+			t.Fatalf("Test_Sign_MatchesSendTx sendtx %s err: %s", c.name, err)
+		}
+
+		if txHash != signed.Hash {
+			t.Fatalf("Test_Sign_MatchesSendTx %s sendtx sends %s, sign makes %s", c.name, txHash, signed.Hash)
+		}
+
+		mustReceipt(t, "Test_Sign_MatchesSendTx", txHash)
+	}
+}
+
+// raw txs signed offline are accepted, reported under their hash and mined.
+func Test_Sign_SubmitRaw(t *testing.T) {
+	funded(t, "Test_Sign_SubmitRaw")
+	start := nonce(t, "Test_Sign_SubmitRaw")
+	var cases []signCase
+	for i, amount := range []int{1, 1000, 123456789} {
+		for j, price := range []int{1, 3, 10} {
+			payload := []string{"", "0x01", "0xdeadbeef"}[(i+j)%3]
+			cases = append(cases, signCase{"amount " + strconv.Itoa(amount) + " price " + strconv.Itoa(price), amount, price, start + len(cases), payload})
+		}
+	}
+
+	var signed []*common.SignedTx
+	for _, c := range cases {
+		tx := sign(t, "Test_Sign_SubmitRaw", c)
+		if err := common.AddTx(common.ServertwoAddr, tx.Raw); err != nil {
+			t.Fatalf("Test_Sign_SubmitRaw %s submit err: %s", c.name, err)
+		}
+		signed = append(signed, tx)
+	}
+
+	for i, tx := range signed {
+		receipt := mustReceipt(t, "Test_Sign_SubmitRaw", tx.Hash)
+		if receipt.Hash != tx.Hash {
+			t.Fatalf("Test_Sign_SubmitRaw %s receipt of %s reports tx %s", cases[i].name, tx.Hash, receipt.Hash)
+		}
+
+		if receipt.TotalFee != receipt.UsedGas*int64(cases[i].price) {
+			t.Fatalf("Test_Sign_SubmitRaw %s paid %d for %d gas at price %d", cases[i].name, receipt.TotalFee, receipt.UsedGas, cases[i].price)
+		}
+
+		info, err := common.GetTxByHash(t, common.CmdClient, tx.Hash, common.ServertwoAddr)
+		if err != nil {
+			t.Fatalf("Test_Sign_SubmitRaw gettxbyhash %s err: %s", tx.Hash, err)
+		}
+
+		if info.Transaction.Hash != tx.Hash || info.Transaction.Nonce != cases[i].nonce || info.Transaction.Amount != int64(cases[i].amount) {
+			t.Fatalf("Test_Sign_SubmitRaw %s node reports %+v for %s", cases[i].name, info.Transaction, tx.Hash)
+		}
+	}
+}
+
+// tamper changes one field of a signed tx, paths go through the nested objects.
+func tamper(t *testing.T, raw json.RawMessage, path ...string) json.RawMessage {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var tx map[string]interface{}
+	if err := decoder.Decode(&tx); err != nil {
+		t.Fatalf("invalid signed tx %s: %s", raw, err)
+	}
+
+	parent := tx
+	for _, key := range path[:len(path)-1] {
+		child, ok := parent[key].(map[string]interface{})
+		if !ok {
+			t.Fatalf("signed tx has no %v: %s", path, raw)
+		}
+		parent = child
+	}
+
+	key := path[len(path)-1]
+	switch value := parent[key].(type) {
+	case json.Number:
+		n, err := strconv.ParseInt(value.String(), 10, 64)
+		if err != nil {
+			t.Fatalf("signed tx %v is not an integer: %s", path, value)
+		}
+		parent[key] = n + 1
+	case string:
+		// a character of the same alphabet keeps hex and base64 values decodable
+		mid := len(value) / 2
+		replaced := byte('a')
+		if len(value) > 0 && value[mid] == 'a' {
+			replaced = 'b'
+		}
+		if len(value) < 4 {
+			parent[key] = "0x01"
+		} else {
+			parent[key] = value[:mid] + string(replaced) + value[mid+1:]
+		}
+	default:
+		t.Fatalf("signed tx has no %v: %s", path, raw)
+	}
+
+	data, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatalf("marshal tampered tx err: %s", err)
+	}
+
+	return data
+}
+
+// a signed tx with any field changed afterwards is rejected, the untouched
+// one is accepted.
+func Test_Sign_Tampered(t *testing.T) {
+	funded(t, "Test_Sign_Tampered")
+	c := signCase{"tampered", 11, 2, nonce(t, "Test_Sign_Tampered"), "0x0102"}
+	signed := sign(t, "Test_Sign_Tampered", c)
+
+	fields := [][]string{
+		{"Hash"},
+		{"Data", "From"},
+		{"Data", "To"},
+		{"Data", "Amount"},
+		{"Data", "AccountNonce"},
+		{"Data", "GasPrice"},
+		{"Data", "GasLimit"},
+		{"Data", "Payload"},
+		{"Signature", "Sig"},
+	}
+
+	for _, field := range fields {
+		tampered := tamper(t, signed.Raw, field...)
+		if err := common.AddTx(common.ServertwoAddr, tampered); err == nil {
+			t.Fatalf("Test_Sign_Tampered tx with a changed %v is accepted: %s", field, tampered)
+		}
+	}
+
+	content, err := common.GetPoolContentTxs(t, common.CmdClient, common.ServertwoAddr)
+	if err != nil {
+		t.Fatalf("Test_Sign_Tampered gettxpoolcontent err: %s", err)
+	}
+
+	if _, inPool := common.FindTxHashFromPool(signed.Hash, nil, &content); inPool {
+		t.Fatalf("Test_Sign_Tampered a tampered tx entered the pool as %s", signed.Hash)
+	}
+
+	if err = common.AddTx(common.ServertwoAddr, signed.Raw); err != nil {
+		t.Fatalf("Test_Sign_Tampered untouched tx is rejected: %s", err)
+	}
+
+	mustReceipt(t, "Test_Sign_Tampered", signed.Hash)
+}
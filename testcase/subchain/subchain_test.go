@@ -5,660 +5,435 @@
 
 package testcase
 
-// import (
-// 	"bytes"
-// 	"encoding/json"
-// 	"fmt"
-// 	"io"
-// 	"os"
-// 	"os/exec"
-// 	"strings"
-// 	"sync"
-// 	"testing"
-// 	"time"
-
-// 	"github.com/seeleteam/e2e-blackbox/testcase/common"
-// )
-
-// // ==============================begin template command===============================================
-// func Test_Client_SubChain_template_Invalid_Name(t *testing.T) {
-// 	cmd := exec.Command(common.CmdClient, "subchain", "template", "--file", "subchain", "--name", "seele.123_we")
-
-// 	var outErr bytes.Buffer
-// 	cmd.Stderr = &outErr
-
-// 	if err := cmd.Start(); err != nil {
-// 		t.Fatalf("%s: An error occured: %s", "Test_Client_SubChain_template_Invalid_Name", err)
-// 	}
-
-// 	cmd.Wait()
-
-// 	errStr := outErr.String()
-// 	if !strings.Contains(errStr, "invalid name, only numbers, letters, and dash lines are allowed") {
-// 		t.Fatalf("%s Err:%s", "Test_Client_SubChain_template_Invalid_Name", errStr)
-// 	}
-// }
-
-// func Test_Client_SubChain_template_Invalid_Name_Empty(t *testing.T) {
-// 	cmd := exec.Command(common.CmdClient, "subchain", "template", "--file", "subchain", "--name", "")
-
-// 	var outErr bytes.Buffer
-// 	cmd.Stderr = &outErr
-
-// 	if err := cmd.Start(); err != nil {
-// 		t.Fatalf("%s: An error occured: %s", "Test_Client_SubChain_template_Invalid_Name_Empty", err)
-// 	}
-
-// 	cmd.Wait()
-
-// 	errStr := outErr.String()
-// 	if !strings.Contains(errStr, "name is empty") {
-// 		t.Fatalf("%s Err:%s", "Test_Client_SubChain_template_Invalid_Name_Empty", errStr)
-// 	}
-// }
-
-// func Test_Client_SubChain_template_Invalid_Name_Exceed_Max_Length(t *testing.T) {
-// 	domainName := ""
-// 	for i := 0; i < 33; i++ {
-// 		domainName += "s"
-// 	}
-
-// 	cmd := exec.Command(common.CmdClient, "subchain", "template", "--file", "subchain", "--name", domainName)
-
-// 	var outErr bytes.Buffer
-// 	cmd.Stderr = &outErr
-
-// 	if err := cmd.Start(); err != nil {
-// 		t.Fatalf("%s: An error occured: %s", "Test_Client_SubChain_template_Invalid_Name_Exceed_Max_Length", err)
-// 	}
-
-// 	cmd.Wait()
-
-// 	errStr := outErr.String()
-// 	if !strings.Contains(errStr, "name too long") {
-// 		t.Fatalf("%s Err:%s", "Test_Client_SubChain_template_Invalid_Name_Exceed_Max_Length", errStr)
-// 	}
-// }
-
-// func Test_Client_SubChain_template_Invalid_FilePath(t *testing.T) {
-// 	cmd := exec.Command(common.CmdClient, "subchain", "template", "--file", "subc<<<<^%hain", "--name", "testinvalidfilepath")
-
-// 	var outErr bytes.Buffer
-// 	cmd.Stderr = &outErr
-
-// 	if err := cmd.Start(); err != nil {
-// 		t.Fatalf("%s: An error occured: %s", "Test_Client_SubChain_template_Invalid_FilePath", err)
-// 	}
-
-// 	cmd.Wait()
-
-// 	errStr := outErr.String()
-// 	if !strings.Contains(errStr, "The filename, directory name, or volume label syntax is incorrect") {
-// 		t.Fatalf("%s Err:%s", "Test_Client_SubChain_template_Invalid_FilePath", errStr)
-// 	}
-// }
-
-// func Test_Client_SubChain_template(t *testing.T) {
-// 	subChainTemplate(t, "Test_Client_SubChain_template", "Test_Client_SubChain_template", "subchaintest")
-// 	os.RemoveAll("Test_Client_SubChain_template")
-// }
-
-// func subChainTemplate(t *testing.T, funcName, subChainFile, domainName string) {
-// 	cmd := exec.Command(common.CmdClient, "subchain", "template", "--file", subChainFile, "--name", domainName)
-
-// 	var out bytes.Buffer
-// 	var outErr bytes.Buffer
-// 	cmd.Stdout, cmd.Stderr = &out, &outErr
-
-// 	if err := cmd.Start(); err != nil {
-// 		t.Fatalf("%s: An error occured: %s", funcName, err)
-// 	}
-
-// 	cmd.Wait()
-
-// 	output, errStr := out.String(), outErr.String()
-// 	if errStr != "" {
-// 		t.Fatalf("%s cmd err: %s", funcName, errStr)
-// 	}
-
-// 	if !strings.Contains(output, "generate template json file for sub chain register successfully") {
-// 		t.Fatalf("%s Err:%s", funcName, errStr)
-// 	}
-// }
-
-// // ==============================end template command===============================================
-
-// // ==============================begin register command===============================================
-// func Test_Client_SubChain_register_Invalid_KeyFile(t *testing.T) {
-// 	validateInfo := "invalid name"
-// 	subChainInvalidRegister(t, "Test_Client_SubChain_register_Invalid_KeyFile", "common.KeyFileShard1_1",
-// 		"123", "15", "200000", "", "subChainTemplate.json", validateInfo)
-// }
-
-// func Test_Client_SubChain_register_Unmatched_keyfile_And_Pass(t *testing.T) {
-// 	validateInfo := "invalid name"
-// 	subChainInvalidRegister(t, "Test_Client_SubChain_register_Unmatched_keyfile_And_Pass", common.KeyFileShard1_1,
-// 		"12345", "15", "200000", "", "subChainTemplate.json", validateInfo)
-// }
-
-// func Test_Client_SubChain_register_Invalid_PriceValue(t *testing.T) {
-// 	validateInfo := "invalid name"
-// 	subChainInvalidRegister(t, "Test_Client_SubChain_register_Invalid_PriceValue", common.KeyFileShard1_1,
-// 		"123", "q3", "200000", "", "subChainTemplate.json", validateInfo)
-// }
-
-// func Test_Client_SubChain_register_Invalid_Gas(t *testing.T) {
-// 	validateInfo := "invalid value"
-// 	subChainInvalidRegister(t, "Test_Client_SubChain_register_Invalid_Gas", common.KeyFileShard1_1,
-// 		"123", "15", "qw", "", "subChainTemplate.json", validateInfo)
-// }
-
-// func Test_Client_SubChain_register_Invalid_Nonce(t *testing.T) {
-// 	validateInfo := "invalid value"
-// 	subChainInvalidRegister(t, "Test_Client_SubChain_register_Invalid_Nonce", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "er", "subChainTemplate.json", validateInfo)
-// }
-
-// // Name cannot contain special characters, such as /, \, `
-// // it may be a string consisting of numbers, letters, and middle lines, etc.
-// func Test_Client_SubChain_register_Invalid_Name(t *testing.T) {
-// 	validateInfo := "invalid name, only numbers, letters, and dash lines are allowed"
-// 	subChainInvalidRegister(t, "Test_Client_SubChain_register_Invalid_Name", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "", "subChainRegisterInvalidName.json", validateInfo)
-// }
-
-// func Test_Client_SubChain_register_Invalid_Name_Empty(t *testing.T) {
-// 	validateInfo := "name is empty"
-// 	subChainInvalidRegister(t, "Test_Client_SubChain_register_Invalid_Name_Empty", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "", "subChainRegisterNameEmpty.json", validateInfo)
-// }
-
-// func Test_Client_SubChain_register_Invalid_Name_Exceed_Max_Length(t *testing.T) {
-// 	validateInfo := "name too long"
-// 	subChainInvalidRegister(t, "Test_Client_SubChain_register_Invalid_Name_Exceed_Max_Length", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "", "subChainRegisterNameTooLong.json", validateInfo)
-// }
-
-// // func Test_Client_SubChain_register_Invalid_Name_Existed(t *testing.T) {
-// // 	receipt := subChainRegister(t, "Test_Client_SubChain_register_Invalid_Name_Existed", "subChainTemplate.json")
-// // 	if receipt.Failed {
-// // 		t.Fatalf("Test_Client_SubChain_register_Invalid_Name_Existed recepit error, %s", receipt.Result)
-// // 	}
-
-// // 	receipt1 := subChainRegister(t, "Test_Client_SubChain_register_Invalid_Name_Existed", "subChainTemplate.json")
-// // 	if !receipt1.Failed {
-// // 		t.Fatalf("Test_Client_SubChain_register_Invalid_Name_Existed SubChain repeated registration successully")
-// // 	}
-// // 	if !strings.Contains(receipt1.Result, "already exists") {
-// // 		t.Fatalf("Test_Client_SubChain_register_Invalid_Name_Existed result does not contain already exists, Result:%s", receipt1.Result)
-// // 	}
-// // }
-
-// func Test_Client_SubChain_register_Version_Empty(t *testing.T) {
-// 	validateInfo := "invalid subchain version"
-// 	subChainInvalidRegister(t, "Test_Client_SubChain_register_Version_Empty", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "", "subChainRegisterVersionEmpty.json", validateInfo)
-// }
-
-// func Test_Client_SubChain_register_TokenFullName_Empty(t *testing.T) {
-// 	validateInfo := "invalid subchain token full name"
-// 	subChainInvalidRegister(t, "Test_Client_SubChain_register_TokenFullName_Empty", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "", "subChainRegisterTokenFullNameEmpty.json", validateInfo)
-// }
-
-// func Test_Client_SubChain_register_TokenFullName_Equal_defaultTokenFullName(t *testing.T) {
-// 	validateInfo := "invalid subchain token full name"
-// 	subChainInvalidRegister(t, "Test_Client_SubChain_register_TokenFullName_Equal_defaultTokenFullName", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "", "subChainRegisterDefaultTokenFullName.json", validateInfo)
-// }
-
-// func Test_Client_SubChain_register_TokenShortName_Empty(t *testing.T) {
-// 	validateInfo := "invalid subchain token short name"
-// 	subChainInvalidRegister(t, "Test_Client_SubChain_register_TokenShortName_Empty", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "", "subChainRegisterTokenShortNameEmpty.json", validateInfo)
-// }
-
-// func Test_Client_SubChain_register_TokenShortName_Equal_defaultTokenShortName(t *testing.T) {
-// 	validateInfo := "invalid subchain token short name"
-// 	subChainInvalidRegister(t, "Test_Client_SubChain_register_TokenShortName_Equal_defaultTokenShortName", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "", "subChainRegisterDefaultTokenShortName.json", validateInfo)
-// }
-
-// func Test_Client_SubChain_register_Invalid_TokenAmount(t *testing.T) {
-// 	validateInfo := "invalid subchain token amount"
-// 	subChainInvalidRegister(t, "Test_Client_SubChain_register_Invalid_TokenAmount", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "", "subChainRegisterTokenAmount.json", validateInfo)
-// }
-
-// func Test_Client_SubChain_register(t *testing.T) {
-// 	receipt := subChainRegister(t, "Test_Client_SubChain_register", "subChainTemplate1.json")
-// 	if receipt.Failed {
-// 		t.Fatalf("Test_Client_SubChain_register recepit error, %s", receipt.Result)
-// 	}
-// }
-
-// func subChainInvalidRegister(t *testing.T, funcName, keyFile, passWord, price, gas, nonce, subChainFile, validateInfo string) {
-// 	if len(nonce) == 0 {
-// 		accountNonce, err := common.GetNonce(t, common.CmdClient, common.AccountShard1_1, common.ServerAddr)
-// 		if err != nil {
-// 			t.Fatalf("%s, err:%s", funcName, err)
-// 		}
-
-// 		nonce = fmt.Sprintf("%d", accountNonce)
-// 	}
-
-// 	cmd := exec.Command(common.CmdClient, "subchain", "register", "--from", keyFile, "--price", price, "--gas", gas,
-// 		"--nonce", nonce, "--file", subChainFile)
-
-// 	stdin, err := cmd.StdinPipe()
-// 	if err != nil {
-// 		t.Fatalf("%s err: %s", funcName, err)
-// 	}
-
-// 	defer stdin.Close()
-
-// 	var outErr bytes.Buffer
-// 	cmd.Stderr = &outErr
-
-// 	if err = cmd.Start(); err != nil {
-// 		t.Fatalf("%s: An error occured: %s", funcName, err)
-// 	}
-
-// 	io.WriteString(stdin, passWord+"\n")
-// 	cmd.Wait()
-
-// 	errStr := outErr.String()
-// 	if !strings.Contains(errStr, validateInfo) {
-// 		t.Fatalf("%s err=:%s, should be %s", funcName, errStr, validateInfo)
-// 	}
-// }
-
-// func subChainRegister(t *testing.T, funcName, domainName string) *common.ReceiptInfo {
-// 	cmd := exec.Command(common.CmdClient, "subchain", "register", "--from", common.KeyFileShard1_1, "--file", domainName)
-
-// 	stdin, err := cmd.StdinPipe()
-// 	if err != nil {
-// 		t.Fatalf("%s err: %s", funcName, err)
-// 	}
-
-// 	defer stdin.Close()
-
-// 	var out bytes.Buffer
-// 	var outErr bytes.Buffer
-// 	cmd.Stdout, cmd.Stderr = &out, &outErr
-
-// 	if err = cmd.Start(); err != nil {
-// 		t.Fatalf("%s: An error occured: %s", funcName, err)
-// 	}
-
-// 	io.WriteString(stdin, "123\n")
-// 	cmd.Wait()
-
-// 	output, errStr := out.String(), outErr.String()
-// 	if errStr != "" {
-// 		t.Fatalf("%s cmd err:%s", funcName, errStr)
-// 	}
-
-// 	str := output[strings.Index(output, `"Tx":`)+5 : strings.LastIndex(output, "}")]
-
-// 	var tx common.TxInfo
-// 	if err := json.Unmarshal([]byte(str), &tx); err != nil {
-// 		t.Fatalf("%s unmarshal register domain tx err: %s", funcName, err)
-// 	}
-
-// 	for {
-// 		time.Sleep(10)
-// 		number, err := common.GetPoolCountTxs(t, common.CmdClient, common.ServerAddr)
-// 		if err != nil {
-// 			t.Fatalf("%s get pool count err: %s", funcName, err)
-// 		}
-
-// 		if number == 0 {
-// 			break
-// 		}
-// 	}
-
-// 	time.Sleep(20)
-
-// 	receipt, err := common.GetReceipt(t, common.CmdClient, tx.Hash, common.ServerAddr)
-// 	if err != nil {
-// 		t.Fatalf("%s get receipt err: %s", funcName, err)
-// 	}
-
-// 	return receipt
-// }
-
-// // ==============================end register command===============================================
-
-// // ==============================begin query command===============================================
-// func Test_Client_SubChain_query_Invalid_KeyFile(t *testing.T) {
-// 	validateInfo := "invalid sender key file"
-// 	subChainInvalidQuery(t, "Test_Client_SubChain_query_Invalid_KeyFile", "common.KeyFileShard1_1",
-// 		"123456", "15", "200000", "", "game", validateInfo)
-// }
-
-// func Test_Client_SubChain_query_Unmatched_keyfile_And_Pass(t *testing.T) {
-// 	validateInfo := "invalid sender key file"
-// 	subChainInvalidQuery(t, "Test_Client_SubChain_query_Unmatched_keyfile_And_Pass", common.KeyFileShard1_1,
-// 		"123456", "15", "200000", "", "game", validateInfo)
-// }
-
-// func Test_Client_SubChain_query_Invalid_PriceValue(t *testing.T) {
-// 	validateInfo := "invalid gas price value"
-// 	subChainInvalidQuery(t, "Test_Client_SubChain_query_Invalid_PriceValue", common.KeyFileShard1_1,
-// 		"123", "q2", "200000", "", "game", validateInfo)
-// }
-
-// func Test_Client_SubChain_query_Invalid_Gas(t *testing.T) {
-// 	validateInfo := "invalid value"
-// 	subChainInvalidQuery(t, "Test_Client_SubChain_query_Invalid_Gas", common.KeyFileShard1_1,
-// 		"123", "15", "qw", "", "game", validateInfo)
-// }
-
-// func Test_Client_SubChain_query_Invalid_Nonce(t *testing.T) {
-// 	validateInfo := "invalid value "
-// 	subChainInvalidQuery(t, "Test_Client_SubChain_query_Invalid_Nonce", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "er", "game", validateInfo)
-// }
-
-// // Name cannot contain special characters, such as /, \, `
-// // it may be a string consisting of numbers, letters, and middle lines, etc.
-// func Test_Client_SubChain_query_Invalid_Name(t *testing.T) {
-// 	validateInfo := "invalid name, only numbers, letters, and dash lines are allowed"
-// 	subChainInvalidQuery(t, "Test_Client_SubChain_query_Invalid_Name", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "", "seele.game_23", validateInfo)
-// }
-
-// func Test_Client_SubChain_query_Invalid_Name_Empty(t *testing.T) {
-// 	validateInfo := "name is empty"
-// 	subChainInvalidQuery(t, "Test_Client_SubChain_query_Invalid_Name_Empty", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "", "", validateInfo)
-// }
-
-// func Test_Client_SubChain_query_Invalid_Name_Exceed_Max_Length(t *testing.T) {
-// 	domainName := ""
-// 	for i := 0; i < 33; i++ {
-// 		domainName += "s"
-// 	}
-
-// 	validateInfo := "name too long"
-// 	subChainInvalidQuery(t, "Test_Client_SubChain_query_Invalid_Name_Exceed_Max_Length", common.KeyFileShard1_1,
-// 		"123", "15", "200000", "", domainName, validateInfo)
-// }
-
-// func Test_Client_SubChain_query(t *testing.T) {
-// 	receipt := subChainRegister(t, "Test_Client_SubChain_register", "subChainTemplate_query.json")
-// 	if receipt.Failed {
-// 		t.Fatalf("Test_Client_SubChain_register recepit error, %s", receipt.Result)
-// 	}
-
-// 	receipt1 := subChainQuery(t, "Test_Client_SubChain_register", "testsubchaintemplatequery")
-// 	if receipt1.Failed {
-// 		t.Fatalf("Test_Client_SubChain_register recepit error, %s", receipt.Result)
-// 	}
-// }
-
-// func subChainInvalidQuery(t *testing.T, funcName, keyFile, passWord, price, gas, nonce, domainName, validateInfo string) {
-// 	if len(nonce) == 0 {
-// 		accountNonce, err := common.GetNonce(t, common.CmdClient, common.AccountShard1_1, common.ServerAddr)
-// 		if err != nil {
-// 			t.Fatalf("%s, err:%s", funcName, err)
-// 		}
-
-// 		nonce = fmt.Sprintf("%d", accountNonce)
-// 	}
-
-// 	cmd := exec.Command(common.CmdClient, "subchain", "query", "--from", keyFile, "--price", price, "--gas", gas,
-// 		"--nonce", nonce, "--name", domainName)
-
-// 	stdin, err := cmd.StdinPipe()
-// 	if err != nil {
-// 		t.Fatalf("%s err: %s", funcName, err)
-// 	}
-
-// 	defer stdin.Close()
-
-// 	var outErr bytes.Buffer
-// 	cmd.Stderr = &outErr
-
-// 	if err = cmd.Start(); err != nil {
-// 		t.Fatalf("%s: An error occured: %s", funcName, err)
-// 	}
-
-// 	io.WriteString(stdin, passWord+"\n")
-// 	cmd.Wait()
-
-// 	errStr := outErr.String()
-// 	if !strings.Contains(errStr, validateInfo) {
-// 		t.Fatalf("%s Err:%s", funcName, errStr)
-// 	}
-// }
-
-// func subChainQuery(t *testing.T, funcName, domainName string) *common.ReceiptInfo {
-// 	cmd := exec.Command(common.CmdClient, "subchain", "query", "--from", common.KeyFileShard1_1, "--name", domainName)
-
-// 	stdin, err := cmd.StdinPipe()
-// 	if err != nil {
-// 		t.Fatalf("%s err: %s", funcName, err)
-// 	}
-
-// 	defer stdin.Close()
-
-// 	var out bytes.Buffer
-// 	var outErr bytes.Buffer
-// 	cmd.Stdout, cmd.Stderr = &out, &outErr
-
-// 	if err = cmd.Start(); err != nil {
-// 		t.Fatalf("%s: An error occured: %s", funcName, err)
-// 	}
-
-// 	io.WriteString(stdin, "123\n")
-// 	cmd.Wait()
-
-// 	output, errStr := out.String(), outErr.String()
-// 	if errStr != "" {
-// 		t.Fatalf("%s cmd err: %s", funcName, errStr)
-// 	}
-
-// 	str := output[strings.Index(output, "{") : strings.LastIndex(output, "}")+1]
-
-// 	var tx common.TxInfo
-// 	if err := json.Unmarshal([]byte(str), &tx); err != nil {
-// 		t.Fatalf("%s unmarshal register domain tx err: %s", funcName, err)
-// 	}
-
-// 	for {
-// 		time.Sleep(10)
-// 		number, err := common.GetPoolCountTxs(t, common.CmdClient, common.ServerAddr)
-// 		if err != nil {
-// 			t.Fatalf("%s get pool count err: %s", funcName, err)
-// 		}
-
-// 		if number == 0 {
-// 			break
-// 		}
-// 	}
-
-// 	time.Sleep(20)
-
-// 	receipt, err := common.GetReceipt(t, common.CmdClient, tx.Hash, common.ServerAddr)
-// 	if err != nil {
-// 		t.Fatalf("%s get receipt err: %s", funcName, err)
-// 	}
-
-// 	return receipt
-// }
-
-// // ==============================end query command===============================================
-
-// // ==============================begin config command===============================================
-// var (
-// 	isExecuteQuerySubchain = 0
-
-// 	lock sync.Mutex
-// )
-
-// func querySubChain(t *testing.T, funcName string) {
-// 	if isExecuteQuerySubchain == 0 {
-// 		lock.Lock()
-// 		if isExecuteQuerySubchain == 0 {
-// 			receipt := subChainQuery(t, funcName, "testsubchaintemplateconfig")
-// 			if receipt.Failed {
-// 				t.Fatalf("%s recepit error, %s", funcName, receipt.Result)
-// 			}
-
-// 			if receipt.Result == "0x" {
-// 				receipt1 := subChainRegister(t, funcName, "subChainTemplate_config.json")
-// 				if receipt1.Failed {
-// 					t.Fatalf("%s recepit error, %s", funcName, receipt.Result)
-// 				}
-// 			}
-// 			isExecuteQuerySubchain = 1
-// 		}
-// 		lock.Unlock()
-// 	}
-// }
-
-// func subChainInvalidConfig(t *testing.T, funcName, coinbase, algorithm, privatekey, shard, node, name, output, validateInfo string) {
-// 	cmd := exec.Command(common.CmdClient, "subchain", "config", "--coinbase", coinbase, "--algorithm", algorithm,
-// 		"--privatekey", privatekey, "--shard", shard, "--node", node, "--output", output, "--name", name)
-
-// 	var out bytes.Buffer
-// 	var outErr bytes.Buffer
-// 	cmd.Stdout, cmd.Stderr = &out, &outErr
-
-// 	if err := cmd.Start(); err != nil {
-// 		t.Fatalf("%s: An error occured: %s", funcName, err)
-// 	}
-
-// 	cmd.Wait()
-
-// 	outStr, errStr := out.String(), outErr.String()
-// 	fmt.Println(outStr)
-// 	if !strings.Contains(errStr, validateInfo) {
-// 		t.Fatalf("%s Err:%s", funcName, errStr)
-// 	}
-// }
-
-// func Test_Client_SubChain_config_Invalid_coinbase(t *testing.T) {
-// 	querySubChain(t, "Test_Client_SubChain_config_Invalid_coinbase")
-// 	subChainInvalidConfig(t, "Test_Client_SubChain_config_Invalid_coinbase", "2323", "sha256",
-// 		"0xf65e40c6809643b25ce4df33153da2f3338876f181f83d2281c6ac4a987b1479", "1", "",
-// 		"testsubchaintemplateconfig", "config", "invalid coinbase, err:hex string without 0x prefix")
-// 	subChainInvalidConfig(t, "Test_Client_SubChain_config_Invalid_coinbase", "0x2323", "sha256",
-// 		"0xf65e40c6809643b25ce4df33153da2f3338876f181f83d2281c6ac4a987b1479", "1", "",
-// 		"testsubchaintemplateconfig", "config", "invalid coinbase, err:invalid address length 2, expected length is 20")
-// 	subChainInvalidConfig(t, "Test_Client_SubChain_config_Invalid_coinbase", "0x4c10f2cd2159b", "sha256",
-// 		"0xf65e40c6809643b25ce4df33153da2f3338876f181f83d2281c6ac4a987b1479", "1", "",
-// 		"testsubchaintemplateconfig", "config", "hex string of odd length")
-// }
-
-// func Test_Client_SubChain_config_ShardofCoinbase_NotEqual_ShardValue(t *testing.T) {
-// 	querySubChain(t, "Test_Client_SubChain_config_ShardofCoinbase_NotEqual_ShardValue")
-// 	subChainInvalidConfig(t, "Test_Client_SubChain_config_ShardofCoinbase_NotEqual_ShardValue", "0x4c10f2cd2159bb432094e3be7e17904c2b4aeb21", "sha256",
-// 		"0xf65e40c6809643b25ce4df33153da2f3338876f181f83d2281c6ac4a987b1479", "2", "",
-// 		"testsubchaintemplateconfig", "config", "input shard(2) is not equal to shard nubmer(1) obtained from the input coinbase:")
-// }
-
-// func Test_Client_SubChain_config_Invalid_PrivateKey(t *testing.T) {
-// 	querySubChain(t, "Test_Client_SubChain_config_Invalid_PrivateKey")
-// 	subChainInvalidConfig(t, "Test_Client_SubChain_config_Invalid_PrivateKey", "0x4c10f2cd2159bb432094e3be7e17904c2b4aeb21", "sha256",
-// 		"sdsd", "1", "",
-// 		"testsubchaintemplateconfig", "config", "Input string not a valid ecdsa string")
-// 	subChainInvalidConfig(t, "Test_Client_SubChain_config_Invalid_PrivateKey", "0x4c10f2cd2159bb432094e3be7e17904c2b4aeb21", "sha256",
-// 		"0x4c10f2cd2159bb4", "1", "",
-// 		"testsubchaintemplateconfig", "config", "invalid key: encoding/hex: odd length hex string")
-// 	subChainInvalidConfig(t, "Test_Client_SubChain_config_Invalid_PrivateKey", "0x4c10f2cd2159bb432094e3be7e17904c2b4aeb21", "sha256",
-// 		"0x2323", "1", "",
-// 		"testsubchaintemplateconfig", "config", "invalid key: invalid length, need 256 bits")
-// }
-
-// // static node can be empty
-// func Test_Client_SubChain_config_Invalid_StaticNode(t *testing.T) {
-// 	querySubChain(t, "Test_Client_SubChain_config_Invalid_StaticNode")
-// 	subChainInvalidConfig(t, "Test_Client_SubChain_config_Invalid_StaticNode", "0x4c10f2cd2159bb432094e3be7e17904c2b4aeb21", "sha256",
-// 		"0xf65e40c6809643b25ce4df33153da2f3338876f181f83d2281c6ac4a987b1479", "1", "we23",
-// 		"testsubchaintemplateconfig", "config", "address we23: missing port in address")
-
-// 	subChainInvalidConfig(t, "Test_Client_SubChain_config_Invalid_StaticNode", "0x4c10f2cd2159bb432094e3be7e17904c2b4aeb21", "sha256",
-// 		"0xf65e40c6809643b25ce4df33153da2f3338876f181f83d2281c6ac4a987b1479", "1", "we23:we",
-// 		"testsubchaintemplateconfig", "config", "lookup udp/we: getaddrinfow: The specified class was not found.")
-
-// 	subChainInvalidConfig(t, "Test_Client_SubChain_config_Invalid_StaticNode", "0x4c10f2cd2159bb432094e3be7e17904c2b4aeb21", "sha256",
-// 		"0xf65e40c6809643b25ce4df33153da2f3338876f181f83d2281c6ac4a987b1479", "1", "we23:2323",
-// 		"testsubchaintemplateconfig", "config", "lookup we23: no such host")
-// }
-
-// // Name cannot contain special characters, such as /, \, `
-// // it may be a string consisting of numbers, letters, and middle lines, etc.
-// func Test_Client_SubChain_config_Invalid_Name(t *testing.T) {
-// 	subChainInvalidConfig(t, "Test_Client_SubChain_config_Invalid_Name", "0x4c10f2cd2159bb432094e3be7e17904c2b4aeb21", "sha256",
-// 		"0xf65e40c6809643b25ce4df33153da2f3338876f181f83d2281c6ac4a987b1479", "1", "",
-// 		"testsubchaintemplate.config", "config", "invalid name, only numbers, letters, and dash lines are allowed")
-// }
-
-// //
-// func Test_Client_SubChain_config_Invalid_Name_Empty(t *testing.T) {
-// 	querySubChain(t, "Test_Client_SubChain_config_Invalid_Name_Empty")
-// 	subChainInvalidConfig(t, "Test_Client_SubChain_config_Invalid_Name_Empty", "0x4c10f2cd2159bb432094e3be7e17904c2b4aeb21", "sha256",
-// 		"0xf65e40c6809643b25ce4df33153da2f3338876f181f83d2281c6ac4a987b1479", "1", "",
-// 		"", "config", "name is empty")
-// }
-
-// func Test_Client_SubChain_config_Invalid_Name_Exceed_Max_Length(t *testing.T) {
-// 	querySubChain(t, "Test_Client_SubChain_config_Invalid_Name_Exceed_Max_Length")
-// 	domainName := ""
-// 	for i := 0; i < 33; i++ {
-// 		domainName += "s"
-// 	}
-// 	subChainInvalidConfig(t, "Test_Client_SubChain_config_Invalid_Name_Exceed_Max_Length", "0x4c10f2cd2159bb432094e3be7e17904c2b4aeb21", "sha256",
-// 		"0xf65e40c6809643b25ce4df33153da2f3338876f181f83d2281c6ac4a987b1479", "1", "",
-// 		domainName, "config", "name too long")
-// }
-
-// func Test_Client_SubChain_config_Invalid_Name_NotFound(t *testing.T) {
-// 	subChainInvalidConfig(t, "Test_Client_SubChain_config_Invalid_Name_NotFound", "0x4c10f2cd2159bb432094e3be7e17904c2b4aeb21", "sha256",
-// 		"0xf65e40c6809643b25ce4df33153da2f3338876f181f83d2281c6ac4a987b1479", "1", "",
-// 		"testsubchaintempla-teconfig", "config", "sub-chain testsubchaintempla-teconfig does not exist")
-// }
-
-// func Test_Client_SubChain_config_Invalid_Shard(t *testing.T) {
-// 	querySubChain(t, "Test_Client_SubChain_config_Invalid_Shard")
-// 	subChainInvalidConfig(t, "Test_Client_SubChain_config_Invalid_Shard", "0x4c10f2cd2159bb432094e3be7e17904c2b4aeb21", "sha256",
-// 		"0xf65e40c6809643b25ce4df33153da2f3338876f181f83d2281c6ac4a987b1479", "123", "",
-// 		"testsubchaintemplateconfig", "config", "input shard(123) is not equal to shard nubmer(1) obtained from the input coinbase")
-// }
-
-// func Test_Client_SubChain_config_Invalid_OutPut(t *testing.T) {
-// 	querySubChain(t, "Test_Client_SubChain_config_Invalid_OutPut")
-// 	subChainInvalidConfig(t, "Test_Client_SubChain_config_Invalid_OutPut", "0x4c10f2cd2159bb432094e3be7e17904c2b4aeb21", "sha256",
-// 		"0xf65e40c6809643b25ce4df33153da2f3338876f181f83d2281c6ac4a987b1479", "1", "",
-// 		"testsubchaintemplateconfig", "conf<<<<^%ig", "mkdir conf<<<<^%ig: The filename, directory name, or volume label syntax is incorrect")
-// }
-
-// func Test_Client_SubChain_config(t *testing.T) {
-// 	funcName := "Test_Client_SubChain_config"
-// 	querySubChain(t, funcName)
-// 	cmd := exec.Command(common.CmdClient, "subchain", "config", "--coinbase", "0x4c10f2cd2159bb432094e3be7e17904c2b4aeb21",
-// 		"--name", "testsubchaintemplateconfig")
-
-// 	var out bytes.Buffer
-// 	var outErr bytes.Buffer
-// 	cmd.Stdout, cmd.Stderr = &out, &outErr
-
-// 	if err := cmd.Start(); err != nil {
-// 		t.Fatalf("%s: An error occured: %s", funcName, err)
-// 	}
-
-// 	cmd.Wait()
-
-// 	output, errStr := out.String(), outErr.String()
-// 	if errStr != "" {
-// 		t.Fatalf("%s cmd err: %s", funcName, errStr)
-// 	}
-// 	if !strings.Contains(output, "generate sub chain config files successfully") {
-// 		t.Fatalf("%s run err: %s", funcName, output)
-// 	}
-// }
-
-// // ==============================end config command===============================================
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/seeleteam/e2e-blackbox/node"
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+const (
+	coinbase   = "0x4c10f2cd2159bb432094e3be7e17904c2b4aeb21"
+	privateKey = "0xf65e40c6809643b25ce4df33153da2f3338876f181f83d2281c6ac4a987b1479"
+
+	// registered is replaced with the name of a sub chain registered for the run
+	registered = "{registered}"
+)
+
+// subChainCase is a row of the table. A case with err expects the command to
+// fail with it, otherwise the output must contain output and, for the
+// commands that send a tx, the receipt must match failed and contain result,
+// or equal it if exact is set.
+type subChainCase struct {
+	name    string
+	command string
+	// register only, the fixture is copied under a unique name if unique is set
+	file   string
+	unique bool
+	args   []string
+
+	keyFile  string
+	password string
+	price    string
+	gas      string
+	nonce    string
+
+	err    string
+	output string
+	failed bool
+	result string
+	exact  bool
+}
+
+// sends returns whether the command sends a tx.
+func (c *subChainCase) sends() bool {
+	return c.command == "register" || c.command == "query"
+}
+
+func uniqueName(prefix string) string {
+	random := make([]byte, 4)
+	rand.Read(random)
+	return prefix + "-" + hex.EncodeToString(random)
+}
+
+// uniqueFixture copies the fixture under a unique sub chain name, so reruns
+// against the same chain do not collide on "already exists".
+func uniqueFixture(t *testing.T, dir, file string) (string, string) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("read fixture %s err: %s", file, err)
+	}
+
+	var fixture map[string]interface{}
+	if err = json.Unmarshal(data, &fixture); err != nil {
+		t.Fatalf("invalid fixture %s: %s", file, err)
+	}
+
+	name := uniqueName("e2e")
+	fixture["name"] = name
+	path := filepath.Join(dir, name+".json")
+	if data, err = json.MarshalIndent(fixture, "", "    "); err == nil {
+		err = ioutil.WriteFile(path, data, 0644)
+	}
+
+	if err != nil {
+		t.Fatalf("write fixture %s err: %s", path, err)
+	}
+
+	return path, name
+}
+
+// run runs the case and returns the tx it sent, if any.
+func (c subChainCase) run(t *testing.T, dir, registeredName string) (stdout string, receipt *common.ReceiptInfo) {
+	args := []string{"subchain", c.command}
+	if c.sends() {
+		keyFile, price, gas, nonce := c.keyFile, c.price, c.gas, c.nonce
+		if keyFile == "" {
+			keyFile = common.KeyFileShard1_1
+		}
+		if price == "" {
+			price = "15"
+		}
+		if gas == "" {
+			gas = "200000"
+		}
+		if nonce == "" {
+			accountNonce, err := common.GetNonce(t, common.CmdClient, common.AccountShard1_1, common.ServerAddr)
+			if err != nil {
+				t.Fatalf("%s, err:%s", c.name, err)
+			}
+			nonce = fmt.Sprintf("%d", accountNonce)
+		}
+
+		args = append(args, "--from", keyFile, "--price", price, "--gas", gas, "--nonce", nonce, "--address", common.ServerAddr)
+	}
+
+	if c.file != "" {
+		file := c.file
+		if c.unique {
+			file, _ = uniqueFixture(t, dir, c.file)
+		}
+		args = append(args, "--file", file)
+	}
+
+	for _, arg := range c.args {
+		args = append(args, strings.Replace(arg, registered, registeredName, -1))
+	}
+
+	password := c.password
+	if password == "" {
+		password = "123"
+	}
+
+	cmd := exec.Command(common.CmdClient, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("%s err: %s", c.name, err)
+	}
+	defer stdin.Close()
+
+	var out bytes.Buffer
+	var outErr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &outErr
+
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("%s: An error occured: %s", c.name, err)
+	}
+
+	io.WriteString(stdin, password+"\n")
+	cmd.Wait()
+
+	output, errStr := out.String(), outErr.String()
+	if c.err != "" {
+		if !strings.Contains(errStr, c.err) {
+			t.Fatalf("%s err=:%s, should be %s", c.name, errStr, c.err)
+		}
+		return output, nil
+	}
+
+	if errStr != "" {
+		t.Fatalf("%s cmd err: %s", c.name, errStr)
+	}
+
+	if !strings.Contains(output, c.output) {
+		t.Fatalf("%s output: %s, should contain %s", c.name, output, c.output)
+	}
+
+	if !c.sends() {
+		return output, nil
+	}
+
+	// register prints the tx in a Tx field, query prints it directly
+	var info struct {
+		common.TxInfo
+		Tx common.TxInfo
+	}
+	start, end := strings.Index(output, "{"), strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		t.Fatalf("%s output is not json: %s", c.name, output)
+	}
+
+	if err = json.Unmarshal([]byte(output[start:end+1]), &info); err != nil {
+		t.Fatalf("%s unmarshal tx err: %s %s", c.name, err, output)
+	}
+
+	hash := info.Tx.Hash
+	if hash == "" {
+		hash = info.Hash
+	}
+
+	if receipt, err = common.WaitReceipt(t, common.CmdClient, hash, common.ServerAddr); err != nil {
+		t.Fatalf("%s get receipt err: %s", c.name, err)
+	}
+
+	matched := strings.Contains(receipt.Result, c.result)
+	if c.exact {
+		matched = receipt.Result == c.result
+	}
+
+	if receipt.Failed != c.failed || !matched {
+		t.Fatalf("%s receipt failed %t result %s, expected failed %t result %s", c.name, receipt.Failed, receipt.Result, c.failed, c.result)
+	}
+
+	return output, receipt
+}
+
+func configArgs(coinbaseValue, privateKeyValue, shard, staticNode, name, output string) []string {
+	return []string{"--coinbase", coinbaseValue, "--algorithm", "sha256", "--privatekey", privateKeyValue,
+		"--shard", shard, "--node", staticNode, "--output", output, "--name", name}
+}
+
+var longName = strings.Repeat("s", 33)
+
+var subChainCases = []subChainCase{
+	// template
+	{name: "template_Invalid_Name", command: "template", args: []string{"--file", "subchain", "--name", "seele.123_we"},
+		err: "invalid name, only numbers, letters, and dash lines are allowed"},
+	{name: "template_Invalid_Name_Empty", command: "template", args: []string{"--file", "subchain", "--name", ""}, err: "name is empty"},
+	{name: "template_Invalid_Name_Exceed_Max_Length", command: "template", args: []string{"--file", "subchain", "--name", longName}, err: "name too long"},
+
+	// register, the sender and price are checked after the name, so their
+	// rows use a fixture with a valid one
+	{name: "register_Invalid_KeyFile", command: "register", file: "subChainTemplate1.json", unique: true, keyFile: "common.KeyFileShard1_1", err: "invalid sender key file"},
+	{name: "register_Unmatched_keyfile_And_Pass", command: "register", file: "subChainTemplate1.json", unique: true, password: "12345", err: "invalid sender key file"},
+	{name: "register_Invalid_PriceValue", command: "register", file: "subChainTemplate1.json", unique: true, price: "q3", err: "invalid gas price value"},
+	{name: "register_Invalid_Gas", command: "register", file: "subChainTemplate.json", gas: "qw", err: "invalid value"},
+	{name: "register_Invalid_Nonce", command: "register", file: "subChainTemplate.json", nonce: "er", err: "invalid value"},
+	{name: "register_Invalid_Name", command: "register", file: "subChainRegisterInvalidName.json", err: "invalid name, only numbers, letters, and dash lines are allowed"},
+	{name: "register_Invalid_Name_Empty", command: "register", file: "subChainRegisterNameEmpty.json", err: "name is empty"},
+	{name: "register_Invalid_Name_Exceed_Max_Length", command: "register", file: "subChainRegisterNameTooLong.json", err: "name too long"},
+	{name: "register_Version_Empty", command: "register", file: "subChainRegisterVersionEmpty.json", err: "invalid subchain version"},
+	{name: "register_TokenFullName_Empty", command: "register", file: "subChainRegisterTokenFullNameEmpty.json", err: "invalid subchain token full name"},
+	{name: "register_TokenFullName_Equal_defaultTokenFullName", command: "register", file: "subChainRegisterDefaultTokenFullName.json", err: "invalid subchain token full name"},
+	{name: "register_TokenShortName_Empty", command: "register", file: "subChainRegisterTokenShortNameEmpty.json", err: "invalid subchain token short name"},
+	{name: "register_TokenShortName_Equal_defaultTokenShortName", command: "register", file: "subChainRegisterDefaultTokenShortName.json", err: "invalid subchain token short name"},
+	{name: "register_Invalid_TokenAmount", command: "register", file: "subChainRegisterTokenAmount.json", err: "invalid subchain token amount"},
+	{name: "register", command: "register", file: "subChainTemplate1.json", unique: true},
+	{name: "register_Query_Fixture", command: "register", file: "subChainTemplate_query.json", unique: true},
+
+	// query
+	{name: "query_Invalid_KeyFile", command: "query", keyFile: "common.KeyFileShard1_1", password: "123456", args: []string{"--name", "game"}, err: "invalid sender key file"},
+	{name: "query_Unmatched_keyfile_And_Pass", command: "query", password: "123456", args: []string{"--name", "game"}, err: "invalid sender key file"},
+	{name: "query_Invalid_PriceValue", command: "query", price: "q2", args: []string{"--name", "game"}, err: "invalid gas price value"},
+	{name: "query_Invalid_Gas", command: "query", gas: "qw", args: []string{"--name", "game"}, err: "invalid value"},
+	{name: "query_Invalid_Nonce", command: "query", nonce: "er", args: []string{"--name", "game"}, err: "invalid value "},
+	{name: "query_Invalid_Name", command: "query", args: []string{"--name", "seele.game_23"}, err: "invalid name, only numbers, letters, and dash lines are allowed"},
+	{name: "query_Invalid_Name_Empty", command: "query", args: []string{"--name", ""}, err: "name is empty"},
+	{name: "query_Invalid_Name_Exceed_Max_Length", command: "query", args: []string{"--name", longName}, err: "name too long"},
+	{name: "query_Not_Registered", command: "query", args: []string{"--name", uniqueName("e2e")}, result: "0x", exact: true},
+	{name: "query", command: "query", args: []string{"--name", registered}},
+
+	// config
+	{name: "config_Invalid_coinbase_Without_Prefix", command: "config", args: configArgs("2323", privateKey, "1", "", registered, "config"),
+		err: "invalid coinbase, err:hex string without 0x prefix"},
+	{name: "config_Invalid_coinbase_Length", command: "config", args: configArgs("0x2323", privateKey, "1", "", registered, "config"),
+		err: "invalid coinbase, err:invalid address length 2, expected length is 20"},
+	{name: "config_Invalid_coinbase_Odd_Length", command: "config", args: configArgs("0x4c10f2cd2159b", privateKey, "1", "", registered, "config"),
+		err: "hex string of odd length"},
+	{name: "config_ShardofCoinbase_NotEqual_ShardValue", command: "config", args: configArgs(coinbase, privateKey, "2", "", registered, "config"),
+		err: "input shard(2) is not equal to shard nubmer(1) obtained from the input coinbase:"},
+	{name: "config_Invalid_PrivateKey", command: "config", args: configArgs(coinbase, "sdsd", "1", "", registered, "config"),
+		err: "Input string not a valid ecdsa string"},
+	{name: "config_Invalid_PrivateKey_Odd_Length", command: "config", args: configArgs(coinbase, "0x4c10f2cd2159bb4", "1", "", registered, "config"),
+		err: "invalid key: encoding/hex: odd length hex string"},
+	{name: "config_Invalid_PrivateKey_Length", command: "config", args: configArgs(coinbase, "0x2323", "1", "", registered, "config"),
+		err: "invalid key: invalid length, need 256 bits"},
+	{name: "config_Invalid_StaticNode", command: "config", args: configArgs(coinbase, privateKey, "1", "we23", registered, "config"),
+		err: "address we23: missing port in address"},
+	{name: "config_Invalid_Name", command: "config", args: configArgs(coinbase, privateKey, "1", "", "testsubchaintemplate.config", "config"),
+		err: "invalid name, only numbers, letters, and dash lines are allowed"},
+	{name: "config_Invalid_Name_Empty", command: "config", args: configArgs(coinbase, privateKey, "1", "", "", "config"), err: "name is empty"},
+	{name: "config_Invalid_Name_Exceed_Max_Length", command: "config", args: configArgs(coinbase, privateKey, "1", "", longName, "config"), err: "name too long"},
+	{name: "config_Invalid_Name_NotFound", command: "config", args: configArgs(coinbase, privateKey, "1", "", "testsubchaintempla-teconfig", "config"),
+		err: "sub-chain testsubchaintempla-teconfig does not exist"},
+	{name: "config_Invalid_Shard", command: "config", args: configArgs(coinbase, privateKey, "123", "", registered, "config"),
+		err: "input shard(123) is not equal to shard nubmer(1) obtained from the input coinbase"},
+}
+
+// registerSubChain registers subChainTemplate_config.json under a unique name.
+func registerSubChain(t *testing.T, dir string) string {
+	file, name := uniqueFixture(t, dir, "subChainTemplate_config.json")
+	c := subChainCase{name: "register_" + name, command: "register", file: file}
+	c.run(t, dir, "")
+	return name
+}
+
+func Test_Client_SubChain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "subchain")
+	if err != nil {
+		t.Fatalf("Test_Client_SubChain create temp dir err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := registerSubChain(t, dir)
+	for _, c := range subChainCases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			c.run(t, dir, name)
+		})
+	}
+}
+
+// freeAddr returns a local address with a port nobody listens on.
+func freeAddr(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("find free port err: %s", err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+// findFile returns the first file with the name below dir.
+func findFile(dir, name string) string {
+	found := ""
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && found == "" && !info.IsDir() && info.Name() == name {
+			found = path
+		}
+		return nil
+	})
+
+	return found
+}
+
+// Test_Client_SubChain_Flow generates a template, registers and queries it,
+// generates the node config of the sub chain and boots a node from it.
+func Test_Client_SubChain_Flow(t *testing.T) {
+	funcName := "Test_Client_SubChain_Flow"
+	dir, err := ioutil.TempDir("", "subchain")
+	if err != nil {
+		t.Fatalf("%s create temp dir err: %s", funcName, err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := uniqueName("e2e")
+	template := filepath.Join(dir, "template.json")
+	(subChainCase{name: funcName + " template", command: "template", args: []string{"--file", template, "--name", name},
+		output: "generate template json file for sub chain register successfully"}).run(t, dir, "")
+
+	// the template leaves the token at defaults that register rejects, take them from the config fixture
+	var generated, tokens map[string]interface{}
+	data, err := ioutil.ReadFile(template)
+	if err == nil {
+		err = json.Unmarshal(data, &generated)
+	}
+	if err != nil {
+		t.Fatalf("%s read template err: %s", funcName, err)
+	}
+
+	if generated["name"] != name {
+		t.Fatalf("%s template name is %v, expected %s", funcName, generated["name"], name)
+	}
+
+	if data, err = ioutil.ReadFile("subChainTemplate_config.json"); err == nil {
+		err = json.Unmarshal(data, &tokens)
+	}
+	if err != nil {
+		t.Fatalf("%s read config fixture err: %s", funcName, err)
+	}
+
+	for _, key := range []string{"tokenFullName", "tokenShortName", "tokenAmount"} {
+		generated[key] = tokens[key]
+	}
+
+	if data, err = json.Marshal(generated); err == nil {
+		err = ioutil.WriteFile(template, data, 0644)
+	}
+	if err != nil {
+		t.Fatalf("%s write template err: %s", funcName, err)
+	}
+
+	(subChainCase{name: funcName + " register", command: "register", file: template}).run(t, dir, "")
+
+	_, receipt := (subChainCase{name: funcName + " query", command: "query", args: []string{"--name", name}}).run(t, dir, "")
+	if receipt.Result == "0x" {
+		t.Fatalf("%s registered sub chain %s is not found", funcName, name)
+	}
+
+	output := filepath.Join(dir, "config")
+	(subChainCase{name: funcName + " config", command: "config", args: configArgs(coinbase, privateKey, "1", "", name, output),
+		output: "generate sub chain config files successfully"}).run(t, dir, "")
+
+	configFile, accountsFile := findFile(output, "node.json"), findFile(output, "accounts.json")
+	if configFile == "" {
+		t.Fatalf("%s config generates no node.json in %s", funcName, output)
+	}
+
+	var config map[string]map[string]interface{}
+	if data, err = ioutil.ReadFile(configFile); err == nil {
+		err = json.Unmarshal(data, &config)
+	}
+	if err != nil {
+		t.Fatalf("%s read node config err: %s", funcName, err)
+	}
+
+	if config["basic"]["name"] != name {
+		t.Fatalf("%s node config is for %v, expected %s", funcName, config["basic"]["name"], name)
+	}
+
+	if _, err = os.Stat(common.CmdNode); err != nil {
+		t.Skipf("%s no node binary to boot the sub chain: %s", funcName, err)
+	}
+
+	// move the node off the ports of the nodes under test
+	rpcAddr := freeAddr(t)
+	config["basic"]["address"], config["basic"]["dataDir"] = rpcAddr, filepath.Join(dir, "data")
+	config["p2p"]["address"] = freeAddr(t)
+	for _, section := range []string{"httpServer", "wsserver", "metrics"} {
+		if config[section] != nil {
+			config[section]["address"] = freeAddr(t)
+		}
+	}
+
+	if data, err = json.MarshalIndent(config, "", "\t"); err == nil {
+		err = ioutil.WriteFile(configFile, data, 0644)
+	}
+	if err != nil {
+		t.Fatalf("%s write node config err: %s", funcName, err)
+	}
+
+	n := node.New(common.CmdNode, name, configFile, rpcAddr)
+	n.Accounts, n.LogFile = accountsFile, filepath.Join(dir, "node.log")
+	if err = n.Start(); err != nil {
+		t.Fatalf("%s boot sub chain node err: %s", funcName, err)
+	}
+	defer n.Stop()
+
+	if _, err = common.GetBlock(t, common.CmdClient, 0, rpcAddr); err != nil {
+		t.Fatalf("%s sub chain node has no genesis block: %s", funcName, err)
+	}
+}
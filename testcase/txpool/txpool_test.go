@@ -0,0 +1,502 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package testcase
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/metrics"
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+const (
+	poolTo  = common.AccountShard2_1
+	poolGas = 21000
+	// poolFunds pays the txs of a sender for the whole suite
+	poolFunds = 1000000000
+	// poolHoldBlocks is how many blocks a tx behind a nonce gap must stay unmined
+	poolHoldBlocks = 3
+	// poolDrainTxs fit in a block, the pool must be drained of them in poolDrainBlocks
+	poolDrainTxs    = 20
+	poolDrainBlocks = 3
+	// poolOrderingAttempts is how often the ordering txs are sent until both
+	// senders share a block
+	poolOrderingAttempts = 5
+)
+
+// poolKeyFileA and poolKeyFileB are fresh shard 2 accounts, the nonce gaps
+// the suite leaves behind block no other package
+var (
+	poolKeyFileA, poolAccountA string
+	poolKeyFileB, poolAccountB string
+
+	// keyDir holds the keyfiles of the fresh accounts, removed after the suite
+	keyDir      string
+	sendersErr  error
+	sendersOnce sync.Once
+)
+
+var poolCapacity = flag.Int("txpool.capacity", 0, "tx pool capacity of the shard 2 node, the size limit test is skipped if 0")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	dir, err := ioutil.TempDir("", "txpool")
+	if err != nil {
+		fmt.Println("create key dir err:", err)
+		os.Exit(1)
+	}
+
+	keyDir = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// senders creates and funds the two senders on first use.
+func senders(t *testing.T, name string) {
+	sendersOnce.Do(func() {
+		if poolKeyFileA, poolAccountA, sendersErr = common.NewKeyFile(t, common.CmdClient, 2, keyDir); sendersErr != nil {
+			return
+		}
+
+		if poolKeyFileB, poolAccountB, sendersErr = common.NewKeyFile(t, common.CmdClient, 2, keyDir); sendersErr != nil {
+			return
+		}
+
+		sendersErr = common.FundAccounts(t, common.CmdClient, common.KeyFileShard2_4, common.AccountShard2_4, poolFunds, common.ServertwoAddr, poolAccountA, poolAccountB)
+	})
+
+	if sendersErr != nil {
+		t.Fatalf("%s fresh senders err: %s", name, sendersErr)
+	}
+}
+
+func send(t *testing.T, keyfile string, price, nonce int) (string, error) {
+	txHash, _, err := common.SendTxWithPrice(t, common.CmdClient, 1, price, nonce, poolGas, keyfile, poolTo, "", common.ServertwoAddr)
+	return txHash, err
+}
+
+func mustSend(t *testing.T, name, keyfile string, price, nonce int) string {
+	txHash, err := send(t, keyfile, price, nonce)
+	if err != nil {
+		t.Fatalf("%s sendtx nonce %d price %d err: %s", name, nonce, price, err)
+	}
+
+	return txHash
+}
+
+func nonceOf(t *testing.T, name, account string) int {
+	nonce, err := common.GetNonce(t, common.CmdClient, account, common.ServertwoAddr)
+	if err != nil {
+		t.Fatalf("%s get nonce of %s err: %s", name, account, err)
+	}
+
+	return nonce
+}
+
+func mustReceipt(t *testing.T, name, txHash string) *common.ReceiptInfo {
+	receipt, err := common.WaitReceipt(t, common.CmdClient, txHash, common.ServertwoAddr)
+	if err != nil {
+		t.Fatalf("%s %s", name, err)
+	}
+
+	if receipt.Failed {
+		t.Fatalf("%s tx %s failed: %s", name, txHash, receipt.Result)
+	}
+
+	return receipt
+}
+
+func mined(t *testing.T, txHash string) bool {
+	_, err := common.GetReceipt(t, common.CmdClient, txHash, common.ServertwoAddr)
+	return err == nil
+}
+
+// poolContent returns the hashes of gettxpoolcontent.
+func poolContent(t *testing.T, name string) map[string]bool {
+	content, err := common.GetPoolContentTxs(t, common.CmdClient, common.ServertwoAddr)
+	if err != nil {
+		t.Fatalf("%s gettxpoolcontent err: %s", name, err)
+	}
+
+	hashes := make(map[string]bool)
+	for _, txs := range content {
+		for _, tx := range txs {
+			hashes[tx.Hash] = true
+		}
+	}
+
+	return hashes
+}
+
+func height(t *testing.T, name string) uint64 {
+	block, err := common.GetBlock(t, common.CmdClient, -1, common.ServertwoAddr)
+	if err != nil {
+		t.Fatalf("%s getblock err: %s", name, err)
+	}
+
+	return block.Header.Height
+}
+
+// waitBlocks waits until n blocks are mined.
+func waitBlocks(t *testing.T, name string, n uint64) {
+	start, deadline := height(t, name), time.Now().Add(common.ReceiptTimeout)
+	for height(t, name) < start+n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s %d blocks are not mined in %s", name, n, common.ReceiptTimeout)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// a tx with a future nonce waits in the pool until the gap is filled, then
+// both are mined in nonce order.
+func Test_TxPool_NonceGap(t *testing.T) {
+	name := "Test_TxPool_NonceGap"
+	senders(t, name)
+	nonce := nonceOf(t, name, poolAccountA)
+
+	future := mustSend(t, name, poolKeyFileA, 1, nonce+1)
+	if !poolContent(t, name)[future] {
+		t.Fatalf("%s tx %s with nonce %d is not in the pool", name, future, nonce+1)
+	}
+
+	waitBlocks(t, name, poolHoldBlocks)
+	if mined(t, future) {
+		t.Fatalf("%s tx %s with nonce %d is mined before nonce %d", name, future, nonce+1, nonce)
+	}
+
+	if !poolContent(t, name)[future] {
+		t.Fatalf("%s tx %s with nonce %d left the pool while waiting for the gap", name, future, nonce+1)
+	}
+
+	gap := mustSend(t, name, poolKeyFileA, 1, nonce)
+	mustReceipt(t, name, gap)
+	mustReceipt(t, name, future)
+
+	first, err := common.GetTxByHash(t, common.CmdClient, gap, common.ServertwoAddr)
+	if err != nil {
+		t.Fatalf("%s gettxbyhash err: %s", name, err)
+	}
+
+	second, err := common.GetTxByHash(t, common.CmdClient, future, common.ServertwoAddr)
+	if err != nil {
+		t.Fatalf("%s gettxbyhash err: %s", name, err)
+	}
+
+	if second.Height < first.Height || (second.Height == first.Height && second.TxIndex < first.TxIndex) {
+		t.Fatalf("%s nonce %d is mined at %d/%d before nonce %d at %d/%d", name, nonce+1, second.Height, second.TxIndex, nonce, first.Height, first.TxIndex)
+	}
+}
+
+// a tx with the nonce of a pooled tx replaces it when its price is higher,
+// and is refused when its price is not.
+func Test_TxPool_Replacement(t *testing.T) {
+	name := "Test_TxPool_Replacement"
+	senders(t, name)
+	nonce := nonceOf(t, name, poolAccountA)
+
+	// the gap holds the txs in the pool while they are replaced
+	original := mustSend(t, name, poolKeyFileA, 2, nonce+1)
+	replacement := mustSend(t, name, poolKeyFileA, 5, nonce+1)
+
+	content := poolContent(t, name)
+	if !content[replacement] {
+		t.Fatalf("%s replacement %s with a higher price is not in the pool", name, replacement)
+	}
+
+	if content[original] {
+		t.Fatalf("%s original %s is still in the pool after its replacement", name, original)
+	}
+
+	var refused []string
+	underpriced, err := send(t, poolKeyFileA, 3, nonce+1)
+	if err != nil {
+		t.Logf("%s underpriced replacement refused: %s", name, err)
+	} else {
+		refused = append(refused, underpriced)
+		content = poolContent(t, name)
+		if content[underpriced] {
+			t.Fatalf("%s replacement %s with price 3 replaced a tx with price 5", name, underpriced)
+		}
+
+		if !content[replacement] {
+			t.Fatalf("%s tx %s left the pool on a replacement with price 3", name, replacement)
+		}
+	}
+
+	mustReceipt(t, name, mustSend(t, name, poolKeyFileA, 1, nonce))
+	receipt := mustReceipt(t, name, replacement)
+	if receipt.TotalFee != receipt.UsedGas*5 {
+		t.Fatalf("%s replacement paid %d for %d gas, expected price 5", name, receipt.TotalFee, receipt.UsedGas)
+	}
+
+	for _, txHash := range append(refused, original) {
+		if mined(t, txHash) {
+			t.Fatalf("%s replaced or refused tx %s is mined", name, txHash)
+		}
+	}
+}
+
+// a full pool never grows beyond its capacity. A tx paying no more than the
+// pooled ones is refused without evicting any, a tx paying more evicts one of
+// the cheapest and is mined. Run with -txpool.capacity set to the small
+// capacity of the shard 2 node config, filling the default one takes hours.
+func Test_TxPool_Capacity(t *testing.T) {
+	name := "Test_TxPool_Capacity"
+	if *poolCapacity <= 0 {
+		t.Skip("the tx pool capacity is not set, use -txpool.capacity")
+	}
+	senders(t, name)
+
+	count := func() int64 {
+		n, err := common.GetPoolCountTxs(t, common.CmdClient, common.ServertwoAddr)
+		if err != nil {
+			t.Fatalf("%s gettxpoolcount err: %s", name, err)
+		}
+
+		return n
+	}
+
+	evicted := func(held []string) []string {
+		content := poolContent(t, name)
+		var gone []string
+		for _, txHash := range held {
+			if !content[txHash] {
+				gone = append(gone, txHash)
+			}
+		}
+
+		return gone
+	}
+
+	// txs behind a gap are never mined, they fill the pool for good
+	nonce := nonceOf(t, name, poolAccountA)
+	var held []string
+	for i := 1; i <= *poolCapacity; i++ {
+		if txHash, err := send(t, poolKeyFileA, 1, nonce+i); err == nil {
+			held = append(held, txHash)
+		} else {
+			t.Logf("%s nonce %d refused: %s", name, nonce+i, err)
+		}
+
+		if n := count(); n > int64(*poolCapacity) {
+			t.Fatalf("%s pool holds %d txs, its capacity is %d", name, n, *poolCapacity)
+		}
+	}
+
+	if n := count(); n != int64(*poolCapacity) {
+		t.Fatalf("%s pool holds %d txs after %d were sent, its capacity is not %d", name, n, *poolCapacity, *poolCapacity)
+	}
+
+	if gone := evicted(held); len(gone) > 0 {
+		t.Fatalf("%s txs of the same price evicted %d held txs: %v", name, len(gone), gone)
+	}
+
+	extra := nonce + *poolCapacity + 1
+	if txHash, err := send(t, poolKeyFileA, 1, extra); err == nil {
+		if poolContent(t, name)[txHash] {
+			t.Fatalf("%s full pool accepted tx %s paying the lowest price", name, txHash)
+		}
+	}
+
+	if gone := evicted(held); len(gone) > 0 {
+		t.Fatalf("%s tx paying the lowest price evicted %d held txs: %v", name, len(gone), gone)
+	}
+
+	rich := mustSend(t, name, poolKeyFileB, 10, nonceOf(t, name, poolAccountB))
+	if n := count(); n > int64(*poolCapacity) {
+		t.Fatalf("%s pool holds %d txs, its capacity is %d", name, n, *poolCapacity)
+	}
+
+	if gone := evicted(held); len(gone) == 0 {
+		t.Fatalf("%s tx %s paying more entered the full pool without evicting a held tx", name, rich)
+	}
+	mustReceipt(t, name, rich)
+
+	// the held and evicted nonces are sent again at a higher price, so the
+	// account is usable by the next tests
+	var last string
+	for i := 0; i <= *poolCapacity; i++ {
+		last = mustSend(t, name, poolKeyFileA, 2, nonce+i)
+	}
+	mustReceipt(t, name, last)
+}
+
+// orderedBlocks sends txs of both senders at different prices, holds them
+// behind a gap and releases them together. It checks the blocks mining them
+// order them by price and returns how many blocks mined txs of both senders.
+func orderedBlocks(t *testing.T, name string, count int) int {
+	nonceA, nonceB := nonceOf(t, name, poolAccountA), nonceOf(t, name, poolAccountB)
+
+	var txs []string
+	for i := 1; i < count; i++ {
+		txs = append(txs, mustSend(t, name, poolKeyFileA, 1, nonceA+i), mustSend(t, name, poolKeyFileB, 10, nonceB+i))
+	}
+	txs = append(txs, mustSend(t, name, poolKeyFileA, 1, nonceA), mustSend(t, name, poolKeyFileB, 10, nonceB))
+
+	heights := make(map[uint64]bool)
+	for _, txHash := range txs {
+		mustReceipt(t, name, txHash)
+		tx, err := common.GetTxByHash(t, common.CmdClient, txHash, common.ServertwoAddr)
+		if err != nil {
+			t.Fatalf("%s gettxbyhash err: %s", name, err)
+		}
+		heights[uint64(tx.Height)] = true
+	}
+
+	shared := 0
+	for h := range heights {
+		blockTxs, err := common.GetBlockTxs(t, common.CmdClient, int64(h), common.ServertwoAddr)
+		if err != nil {
+			t.Fatalf("%s getblock %d err: %s", name, h, err)
+		}
+
+		seenA := false
+		senders := make(map[string]bool)
+		for _, tx := range blockTxs {
+			switch strings.ToLower(tx.From) {
+			case poolAccountA:
+				seenA = true
+			case poolAccountB:
+				if seenA {
+					t.Fatalf("%s block %d mines a tx of price 1 before a tx of price 10: %+v", name, h, blockTxs)
+				}
+			default:
+				continue
+			}
+			senders[strings.ToLower(tx.From)] = true
+		}
+
+		if len(senders) == 2 {
+			shared++
+		}
+	}
+
+	return shared
+}
+
+// txs of different senders mined in the same block are ordered by price. The
+// txs are sent again until both senders share a block.
+func Test_TxPool_PriceOrdering(t *testing.T) {
+	name := "Test_TxPool_PriceOrdering"
+	senders(t, name)
+	for i := 1; i <= poolOrderingAttempts; i++ {
+		if shared := orderedBlocks(t, name, 3); shared > 0 {
+			return
+		}
+		t.Logf("%s attempt %d: the senders did not share a block", name, i)
+	}
+
+	t.Fatalf("%s the senders never shared a block in %d attempts, the ordering is not observed", name, poolOrderingAttempts)
+}
+
+// getpendingtxs, gettxpoolcontent and gettxpoolcount agree on the pool.
+func Test_TxPool_ContentConsistency(t *testing.T) {
+	name := "Test_TxPool_ContentConsistency"
+	senders(t, name)
+	nonce := nonceOf(t, name, poolAccountA)
+	held := mustSend(t, name, poolKeyFileA, 1, nonce+1)
+
+	// the pool changes as blocks are mined, read it until two contents around
+	// the other reads are equal
+	consistent := false
+	for i := 0; i < 5 && !consistent; i++ {
+		before := poolContent(t, name)
+		pending, err := common.GetPendingTxs(t, common.CmdClient, common.ServertwoAddr)
+		if err != nil {
+			t.Fatalf("%s getpendingtxs err: %s", name, err)
+		}
+
+		count, err := common.GetPoolCountTxs(t, common.CmdClient, common.ServertwoAddr)
+		if err != nil {
+			t.Fatalf("%s gettxpoolcount err: %s", name, err)
+		}
+
+		after := poolContent(t, name)
+		if len(before) != len(after) {
+			continue
+		}
+
+		consistent = true
+		for txHash := range before {
+			consistent = consistent && after[txHash]
+		}
+
+		if !consistent {
+			continue
+		}
+
+		for _, tx := range pending {
+			if !after[tx.Hash] {
+				t.Fatalf("%s pending tx %s is not in the pool content", name, tx.Hash)
+			}
+		}
+
+		if count != int64(len(after)) {
+			t.Fatalf("%s gettxpoolcount returns %d, the pool content holds %d txs", name, count, len(after))
+		}
+
+		if !after[held] {
+			t.Fatalf("%s held tx %s is not in the pool content", name, held)
+		}
+	}
+
+	if !consistent {
+		t.Fatalf("%s the pool content changed during every read", name)
+	}
+
+	mustReceipt(t, name, mustSend(t, name, poolKeyFileA, 1, nonce))
+	mustReceipt(t, name, held)
+
+	pending, err := common.GetPendingTxs(t, common.CmdClient, common.ServertwoAddr)
+	if err != nil {
+		t.Fatalf("%s getpendingtxs err: %s", name, err)
+	}
+
+	content := poolContent(t, name)
+	if inPending, _ := common.FindTxHashFromPool(held, &pending, nil); inPending || content[held] {
+		t.Fatalf("%s mined tx %s is still in the pool, pending %t, content %t", name, held, inPending, content[held])
+	}
+}
+
+// a burst of txs leaves the pool within a few blocks, as sampled by the
+// metrics scraper.
+func Test_TxPool_Drained(t *testing.T) {
+	name := "Test_TxPool_Drained"
+	senders(t, name)
+	scraper := &metrics.Scraper{Client: common.CmdClient, Targets: []metrics.Target{{Name: "shard2", Addr: common.ServertwoAddr}}, Interval: time.Second}
+	scraper.Start()
+	defer scraper.Stop()
+
+	// other suites may hold txs in the pool, it has to get back to that level
+	level, err := common.GetPoolCountTxs(t, common.CmdClient, common.ServertwoAddr)
+	if err != nil {
+		t.Fatalf("%s gettxpoolcount err: %s", name, err)
+	}
+
+	nonce := nonceOf(t, name, poolAccountA)
+	var last string
+	for i := 0; i < poolDrainTxs; i++ {
+		last = mustSend(t, name, poolKeyFileA, 1, nonce+i)
+	}
+	sent := time.Now()
+
+	mustReceipt(t, name, last)
+	waitBlocks(t, name, 1)
+	samples := scraper.Stop()
+
+	if err = metrics.PoolDrainedWithin(samples, "shard2", sent, level, poolDrainBlocks); err != nil {
+		t.Fatalf("%s %s", name, err)
+	}
+}
@@ -0,0 +1,413 @@
+/**
+*  @file
+*  @copyright defined in go-seele/LICENSE
+ */
+
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/seeleteam/e2e-blackbox/testcase/common"
+)
+
+const (
+	// wsTimeout bounds the wait for a notification, a few block intervals
+	wsTimeout     = 3 * time.Minute
+	wsHeads       = 5
+	wsLogTxs      = 3
+	wsPendingTxs  = 5
+	wsSubscribers = 32
+	// wsQuiet is how long a filter that matches nothing is watched
+	wsQuiet = 10 * time.Second
+	// senderFunds pays the fees of the txs a fresh sender sends
+	senderFunds = 100000000
+)
+
+var (
+	pendingTo = common.Account1_Aux2
+
+	// the senders are fresh shard 1 accounts, no other suite knows their nonces
+	pendingKeyFile, pendingFrom string
+	logKeyFile, logFrom         string
+
+	// keyDir holds the keyfiles of the fresh accounts, removed after the suite
+	keyDir      string
+	sendersErr  error
+	sendersOnce sync.Once
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	dir, err := ioutil.TempDir("", "ws")
+	if err != nil {
+		fmt.Println("create key dir err:", err)
+		os.Exit(1)
+	}
+
+	keyDir = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// senders creates and funds the senders once, from the pair 5 account
+func senders(t *testing.T, name string) {
+	sendersOnce.Do(func() {
+		if pendingKeyFile, pendingFrom, sendersErr = common.NewKeyFile(t, common.CmdClient, 1, keyDir); sendersErr != nil {
+			return
+		}
+
+		if logKeyFile, logFrom, sendersErr = common.NewKeyFile(t, common.CmdClient, 1, keyDir); sendersErr != nil {
+			return
+		}
+
+		sendersErr = common.FundAccounts(t, common.CmdClient, common.KeyFileShard1_5, common.AccountShard1_5, senderFunds, common.ServerAddr, pendingFrom, logFrom)
+	})
+
+	if sendersErr != nil {
+		t.Fatalf("%s fresh senders err: %s", name, sendersErr)
+	}
+}
+
+func dial(t *testing.T, name string) *common.WSClient {
+	client, err := common.DialWS(common.WSAddr)
+	if err != nil {
+		t.Fatalf("%s dial %s err: %s", name, common.WSAddr, err)
+	}
+
+	return client
+}
+
+func subscribe(t *testing.T, name string, client *common.WSClient, kind string, params ...interface{}) *common.Subscription {
+	sub, err := client.Subscribe(kind, params...)
+	if err != nil {
+		t.Fatalf("%s subscribe %s err: %s", name, kind, err)
+	}
+
+	return sub
+}
+
+func next(t *testing.T, name string, sub *common.Subscription) json.RawMessage {
+	select {
+	case result, ok := <-sub.C:
+		if !ok {
+			t.Fatalf("%s subscription %s is closed", name, sub.ID)
+		}
+		return result
+	case <-time.After(wsTimeout):
+		t.Fatalf("%s subscription %s gets nothing in %s", name, sub.ID, wsTimeout)
+	}
+
+	return nil
+}
+
+// head is a newHeads notification, the node sends the header alone or in a block
+type head struct {
+	Header *common.BlockHeader `json:"header"`
+	common.BlockHeader
+}
+
+func parseHead(t *testing.T, name string, raw json.RawMessage) common.BlockHeader {
+	var h head
+	if err := json.Unmarshal(raw, &h); err != nil {
+		t.Fatalf("%s invalid head %s: %s", name, raw, err)
+	}
+
+	if h.Header != nil {
+		return *h.Header
+	}
+
+	return h.BlockHeader
+}
+
+// checkLinked fails unless the head follows the previous one and extends the
+// block the node reports below it.
+func checkLinked(t *testing.T, name string, previous, current common.BlockHeader) {
+	if current.Height != previous.Height+1 {
+		t.Fatalf("%s head %d follows head %d", name, current.Height, previous.Height)
+	}
+
+	parent, err := common.GetBlock(t, common.CmdClient, int64(current.Height)-1, common.ServerAddr)
+	if err != nil {
+		t.Fatalf("%s getblock %d err: %s", name, current.Height-1, err)
+	}
+
+	if parent.Hash != current.PreviousBlockHash {
+		t.Fatalf("%s head %d extends %s, block %d is %s", name, current.Height, current.PreviousBlockHash, current.Height-1, parent.Hash)
+	}
+}
+
+func height(t *testing.T, name string) uint64 {
+	output, err := exec.Command(common.CmdClient, "getinfo", "--address", common.ServerAddr).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s getinfo err: %s %s", name, err, output)
+	}
+
+	var info common.ResGetInfo
+	if err = json.Unmarshal(output, &info); err != nil {
+		t.Fatalf("%s getinfo prints %s: %s", name, output, err)
+	}
+
+	return uint64(info.CurrentBlockHeight)
+}
+
+// new heads arrive one per block, in height order and linked to their parent.
+func Test_WS_NewHeads(t *testing.T) {
+	name := "Test_WS_NewHeads"
+	client := dial(t, name)
+	defer client.Close()
+
+	sub := subscribe(t, name, client, "newHeads")
+	previous := parseHead(t, name, next(t, name, sub))
+	for i := 1; i < wsHeads; i++ {
+		current := parseHead(t, name, next(t, name, sub))
+		checkLinked(t, name, previous, current)
+		previous = current
+	}
+}
+
+// sendTxs sends txs with consecutive nonces from the keyfile and returns
+// their hashes in nonce order.
+func sendTxs(t *testing.T, name, keyfile, account, to, payload string, count int) []string {
+	nonce, err := common.GetNonce(t, common.CmdClient, account, common.ServerAddr)
+	if err != nil {
+		t.Fatalf("%s get nonce of %s err: %s", name, account, err)
+	}
+
+	var hashes []string
+	for i := 0; i < count; i++ {
+		txHash, _, err := common.SendTx(t, common.CmdClient, 0, nonce+i, 0, keyfile, to, payload, common.ServerAddr)
+		if err != nil {
+			t.Fatalf("%s sendtx err: %s", name, err)
+		}
+		hashes = append(hashes, txHash)
+	}
+
+	return hashes
+}
+
+// logs of the contract and topic arrive once per call in block order, a
+// filter on another topic gets none.
+func Test_WS_Logs(t *testing.T) {
+	name := "Test_WS_Logs"
+	senders(t, name)
+	contract, _, topics, err := common.DeployContractAndSendTx(t)
+	if err != nil {
+		t.Fatalf("%s %s", name, err)
+	}
+
+	topic, _ := topics[0].(string)
+	output, err := exec.Command(common.CmdLight, "payload", "--abi", "../contract/simplestorage/SimpleEvent.abi", "--method", "get").CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s payload err: %s %s", name, err, output)
+	}
+	payload := string(bytes.TrimSpace(output[bytes.LastIndexByte(bytes.TrimSpace(output), ' ')+1:]))
+
+	client := dial(t, name)
+	defer client.Close()
+
+	sub := subscribe(t, name, client, "logs", map[string]interface{}{"address": contract, "topics": []string{topic}})
+	other := subscribe(t, name, client, "logs", map[string]interface{}{"address": contract, "topics": []string{common.BlockHashErr}})
+
+	sent := make(map[string]bool)
+	for _, txHash := range sendTxs(t, name, logKeyFile, logFrom, contract, payload, wsLogTxs) {
+		sent[txHash] = true
+	}
+
+	var previous *common.LogByTopic
+	for len(sent) > 0 {
+		var log common.LogByTopic
+		raw := next(t, name, sub)
+		if err = json.Unmarshal(raw, &log); err != nil {
+			t.Fatalf("%s invalid log %s: %s", name, raw, err)
+		}
+
+		if !strings.EqualFold(log.Log.Address, contract) || len(log.Log.Topics) == 0 || log.Log.Topics[0] != topic {
+			t.Fatalf("%s log %s does not match contract %s and topic %s", name, raw, contract, topic)
+		}
+
+		if !sent[log.Txhash] {
+			t.Fatalf("%s log of tx %s is not sent or arrives twice", name, log.Txhash)
+		}
+		delete(sent, log.Txhash)
+
+		if previous != nil && (log.Log.BklockNumber < previous.Log.BklockNumber ||
+			log.Log.BklockNumber == previous.Log.BklockNumber && log.Log.TransactionIndex <= previous.Log.TransactionIndex) {
+			t.Fatalf("%s log of block %d tx %d follows block %d tx %d", name, log.Log.BklockNumber, log.Log.TransactionIndex,
+				previous.Log.BklockNumber, previous.Log.TransactionIndex)
+		}
+		previous = &log
+	}
+
+	select {
+	case raw := <-other.C:
+		t.Fatalf("%s filter on topic %s gets %s", name, common.BlockHashErr, raw)
+	case <-time.After(wsQuiet):
+	}
+}
+
+// every tx accepted to the pool is notified once, in the order it was sent.
+func Test_WS_PendingTxs(t *testing.T) {
+	name := "Test_WS_PendingTxs"
+	senders(t, name)
+	client := dial(t, name)
+	defer client.Close()
+
+	sub := subscribe(t, name, client, "newPendingTransactions")
+	hashes := sendTxs(t, name, pendingKeyFile, pendingFrom, pendingTo, "", wsPendingTxs)
+
+	order := make(map[string]int)
+	for i, txHash := range hashes {
+		order[txHash] = i
+	}
+
+	// other suites send txs too, only ours are checked
+	expected := 0
+	for expected < len(hashes) {
+		var txHash string
+		raw := next(t, name, sub)
+		if err := json.Unmarshal(raw, &txHash); err != nil {
+			t.Fatalf("%s invalid pending tx %s: %s", name, raw, err)
+		}
+
+		i, ok := order[txHash]
+		if !ok {
+			continue
+		}
+
+		if i != expected {
+			t.Fatalf("%s pending tx %d %s arrives while waiting for tx %d %s", name, i, txHash, expected, hashes[expected])
+		}
+		expected++
+	}
+}
+
+// a closed connection ends its subscriptions, a new connection subscribes
+// again and continues from the current head.
+func Test_WS_Reconnect(t *testing.T) {
+	name := "Test_WS_Reconnect"
+	client := dial(t, name)
+	sub := subscribe(t, name, client, "newHeads")
+	first := parseHead(t, name, next(t, name, sub))
+	client.Close()
+
+	for closed := time.After(10 * time.Second); ; {
+		select {
+		case _, ok := <-sub.C:
+			if ok {
+				continue
+			}
+		case <-closed:
+			t.Fatalf("%s subscription %s stays open after close", name, sub.ID)
+		}
+		break
+	}
+
+	if err := client.Call(nil, "seele_unsubscribe", sub.ID); err != common.ErrWSClosed {
+		t.Fatalf("%s call on a closed connection returns %v", name, err)
+	}
+
+	// blocks are mined while disconnected, they are not replayed
+	deadline := time.Now().Add(wsTimeout)
+	for height(t, name) < first.Height+2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s no block above %d in %s", name, first.Height+1, wsTimeout)
+		}
+		time.Sleep(2 * time.Second)
+	}
+
+	client = dial(t, name)
+	defer client.Close()
+
+	again := subscribe(t, name, client, "newHeads")
+	if again.ID == sub.ID {
+		t.Fatalf("%s new subscription reuses id %s", name, sub.ID)
+	}
+
+	previous := parseHead(t, name, next(t, name, again))
+	if previous.Height < first.Height+2 {
+		t.Fatalf("%s head %d arrives after reconnecting at height %d", name, previous.Height, first.Height+2)
+	}
+	checkLinked(t, name, previous, parseHead(t, name, next(t, name, again)))
+
+	if err := client.Unsubscribe(again); err != nil {
+		t.Fatalf("%s unsubscribe err: %s", name, err)
+	}
+
+	if err := client.Unsubscribe(again); err == nil {
+		t.Fatalf("%s unsubscribe of a cancelled subscription returns ok", name)
+	}
+}
+
+// concurrent subscribers all get the same heads.
+func Test_WS_ManySubscribers(t *testing.T) {
+	name := "Test_WS_ManySubscribers"
+	subs := make([]*common.Subscription, wsSubscribers)
+	for i := range subs {
+		client := dial(t, name)
+		defer client.Close()
+		subs[i] = subscribe(t, name, client, "newHeads")
+	}
+
+	start := height(t, name)
+	heads := make([][]common.BlockHeader, len(subs))
+	errs := make(chan string, len(subs))
+	var wg sync.WaitGroup
+	for i, sub := range subs {
+		wg.Add(1)
+		go func(i int, sub *common.Subscription) {
+			defer wg.Done()
+			timeout := time.After(wsTimeout)
+			for len(heads[i]) < 2 {
+				select {
+				case raw, ok := <-sub.C:
+					if !ok {
+						errs <- "subscription " + sub.ID + " is closed"
+						return
+					}
+
+					var h head
+					if err := json.Unmarshal(raw, &h); err != nil {
+						errs <- "invalid head " + string(raw)
+						return
+					}
+
+					header := h.BlockHeader
+					if h.Header != nil {
+						header = *h.Header
+					}
+					if header.Height > start {
+						heads[i] = append(heads[i], header)
+					}
+				case <-timeout:
+					errs <- "subscription " + sub.ID + " gets no 2 heads in " + wsTimeout.String()
+					return
+				}
+			}
+		}(i, sub)
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("%s %s", name, err)
+	}
+
+	for i := range heads {
+		checkLinked(t, name, heads[i][0], heads[i][1])
+		if heads[i][0] != heads[0][0] || heads[i][1] != heads[0][1] {
+			t.Fatalf("%s subscriber %d gets %+v, subscriber 0 gets %+v", name, i, heads[i], heads[0])
+		}
+	}
+}